@@ -1,3 +1,10 @@
+export type Page<T> = {
+  items: T[]
+  next_cursor?: string
+  has_more: boolean
+  total?: number
+}
+
 type ApiResponse<T> = {
   status?: 'ok' | 'error'
   message?: string
@@ -51,5 +58,11 @@ export const useApi = () => {
     return unwrap(response)
   }
 
-  return { fetcher, authFetch }
+  // etagOf returns the ETag of a resource, to send as If-Match with its update.
+  const etagOf = async (path: string) => {
+    const response = await $fetch.raw(resolveUrl(path))
+    return response.headers.get('etag') ?? ''
+  }
+
+  return { fetcher, authFetch, etagOf }
 }
@@ -0,0 +1,16 @@
+export type CarCategory = {
+  code: string
+  display_name: string
+  default_deposit: number
+  min_driver_age: number
+  sort_order: number
+}
+
+export const useCategories = async () => {
+  const { fetcher } = useApi()
+  const { data } = await useAsyncData<CarCategory[]>('car-categories', () =>
+    fetcher(`/api/v1/categories`)
+  )
+  const categories = computed(() => data.value ?? [])
+  return { categories }
+}
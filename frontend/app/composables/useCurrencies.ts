@@ -0,0 +1,20 @@
+export type ExchangeRate = {
+  currency: string
+  rate: number
+  effective_from: string
+}
+
+type ExchangeRates = {
+  base_currency: string
+  rates: ExchangeRate[]
+}
+
+export const useCurrencies = async () => {
+  const { fetcher } = useApi()
+  const { data } = await useAsyncData<ExchangeRates>('exchange-rates', () =>
+    fetcher(`/api/v1/exchange-rates`)
+  )
+  const baseCurrency = computed(() => data.value?.base_currency ?? 'KZT')
+  const currencies = computed(() => [baseCurrency.value, ...(data.value?.rates ?? []).map((r) => r.currency)])
+  return { baseCurrency, currencies }
+}
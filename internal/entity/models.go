@@ -6,12 +6,20 @@ import (
 	"gorm.io/gorm"
 )
 
+// BaseCurrency is the currency of every stored price, balance and report.
+// Other currencies are shown and paid in at the rate of an ExchangeRate.
+const BaseCurrency = "KZT"
+
+// DefaultJurisdiction is the tax jurisdiction of cars that do not set one.
+const DefaultJurisdiction = "KZ"
+
 const (
 	UserRoleAdmin     = "admin"
 	UserRoleClient    = "client"
 	UserRoleCorporate = "corporate"
 )
 
+// Categories seeded into a fresh database; admins manage the rest.
 const (
 	CarCategoryEconomy  = "economy"
 	CarCategoryBusiness = "business"
@@ -24,11 +32,96 @@ const (
 	CarStatusMaintenance = "maintenance"
 )
 
+// Rental statuses; the transitions between them are declared in usecase/rentalstate.
 const (
 	RentalStatusPending   = "pending"
+	RentalStatusConfirmed = "confirmed"
 	RentalStatusActive    = "active"
+	RentalStatusOverdue   = "overdue"
 	RentalStatusCompleted = "completed"
 	RentalStatusCancelled = "cancelled"
+	RentalStatusExpired   = "expired"
+	RentalStatusNoShow    = "no_show"
+)
+
+const (
+	CarDocumentRegistration = "registration"
+	CarDocumentInspection   = "inspection"
+	CarDocumentInsurance    = "insurance"
+)
+
+const (
+	CarEventCreated         = "created"
+	CarEventUpdated         = "updated"
+	CarEventStatusChanged   = "status_changed"
+	CarEventPriceChanged    = "price_changed"
+	CarEventMaintenance     = "maintenance"
+	CarEventDamage          = "damage"
+	CarEventDeleted         = "deleted"
+	CarEventRestored        = "restored"
+	CarEventRentalCreated   = "rental_created"
+	CarEventRentalPaid      = "rental_paid"
+	CarEventRentalPickedUp  = "rental_picked_up"
+	CarEventRentalCompleted = "rental_completed"
+	CarEventRentalCancelled = "rental_cancelled"
+	CarEventRentalModified  = "rental_modified"
+)
+
+const (
+	TransactionTypePayment = "payment"
+	TransactionTypeTopUp   = "topup"
+	TransactionTypeRefund  = "refund"
+	// Deposit transactions move money between Balance and HeldBalance (hold,
+	// release) or out of HeldBalance (capture).
+	TransactionTypeDepositHold    = "deposit_hold"
+	TransactionTypeDepositRelease = "deposit_release"
+	TransactionTypeDepositCapture = "deposit_capture"
+	// What a rental charge takes from Balance once the deposit is used up.
+	TransactionTypeCharge = "charge"
+)
+
+const (
+	RentalChargeDamage  = "damage"
+	RentalChargeLateFee = "late_fee"
+	RentalChargeFine    = "fine"
+)
+
+// EarlyReturnPolicy.Refund values.
+const (
+	EarlyReturnRefundNone    = "none"
+	EarlyReturnRefundFull    = "full"
+	EarlyReturnRefundPartial = "partial"
+)
+
+// How the price of a RentalExtension was settled.
+const (
+	RentalExtensionCharged = "charged"
+	RentalExtensionDue     = "due"
+)
+
+const (
+	NotificationRentalExpired = "rental_expired"
+	NotificationRentalOverdue = "rental_overdue"
+	NotificationRentalDelayed = "rental_delayed"
+	NotificationRentalNoShow  = "rental_no_show"
+)
+
+const (
+	PromoDiscountPercent = "percent"
+	PromoDiscountFixed   = "fixed"
+)
+
+const (
+	LoyaltyEntryEarn   = "earn"
+	LoyaltyEntryRedeem = "redeem"
+	LoyaltyEntryExpire = "expire"
+	LoyaltyEntryRefund = "refund"
+)
+
+const (
+	LoyaltyTierBronze = "bronze"
+	LoyaltyTierSilver = "silver"
+	LoyaltyTierGold   = "gold"
 )
 
 const (
@@ -38,47 +131,527 @@ const (
 
 type User struct {
 	gorm.Model
-	FirstName    string   `json:"first_name" gorm:"column:first_name" validate:"required"`
-	LastName     string   `json:"last_name" gorm:"column:last_name" validate:"required"`
-	Email        string   `json:"email" gorm:"column:email;uniqueIndex" validate:"required,email"`
-	PasswordHash string   `json:"password_hash" gorm:"column:password_hash" validate:"required"`
-	Role         string   `json:"role" gorm:"column:role" validate:"required,oneof=admin client corporate"`
-	Balance      float64  `json:"balance" gorm:"column:balance" validate:"gte=0"`
-	Rating       float64  `json:"rating" gorm:"column:rating" validate:"gte=0,lte=5"`
-	Rentals      []Rental `json:"rentals" gorm:"foreignKey:UserID"`
+	FirstName    string `json:"first_name" gorm:"column:first_name" validate:"required"`
+	LastName     string `json:"last_name" gorm:"column:last_name" validate:"required"`
+	Email        string `json:"email" gorm:"column:email;uniqueIndex" validate:"required,email"`
+	PasswordHash string `json:"password_hash" gorm:"column:password_hash" validate:"required"`
+	Role         string `json:"role" gorm:"column:role" validate:"required,oneof=admin client corporate"`
+	Balance      Money  `json:"balance" gorm:"column:balance" validate:"gte=0"`
+	// HeldBalance is the sum of the deposits held for the user's rentals; it is not part of Balance.
+	HeldBalance Money      `json:"held_balance" gorm:"column:held_balance;default:0" validate:"gte=0"`
+	Rating      float64    `json:"rating" gorm:"column:rating" validate:"gte=0,lte=5"`
+	BirthDate   *time.Time `json:"birth_date,omitempty" gorm:"column:birth_date"`
+	// Currency is the currency prices are shown and paid in; the balance itself is kept in BaseCurrency.
+	Currency string   `json:"currency" gorm:"column:currency;default:KZT"`
+	Rentals  []Rental `json:"rentals" gorm:"foreignKey:UserID"`
+}
+
+// CarCategory is an admin-managed car class with its rental defaults.
+type CarCategory struct {
+	gorm.Model
+	Code           string `json:"code" gorm:"column:code;uniqueIndex" validate:"required"`
+	DisplayName    string `json:"display_name" gorm:"column:display_name" validate:"required"`
+	DefaultDeposit Money  `json:"default_deposit" gorm:"column:default_deposit" validate:"gte=0"`
+	MinDriverAge   int    `json:"min_driver_age" gorm:"column:min_driver_age" validate:"gte=0"`
+	SortOrder      int    `json:"sort_order" gorm:"column:sort_order"`
+	// CancellationPolicy refunds paid rentals cancelled before the start; empty uses the default tiers.
+	CancellationPolicy []CancellationTier `json:"cancellation_policy,omitempty" gorm:"column:cancellation_policy;type:text;serializer:json"`
+}
+
+// CancellationTier refunds RefundPercent of what was paid for a rental
+// cancelled at least MinHoursBefore hours before its start. The tier with the
+// largest matching MinHoursBefore wins.
+type CancellationTier struct {
+	MinHoursBefore float64 `json:"min_hours_before"`
+	RefundPercent  float64 `json:"refund_percent"`
 }
 
 type Car struct {
 	gorm.Model
-	Mark         string   `json:"mark" gorm:"column:mark" validate:"required"`
-	CarModel     string   `json:"model" gorm:"column:model" validate:"required"`
-	Category     string   `json:"category" gorm:"column:category" validate:"required,oneof=economy business luxury"`
-	Status       string   `json:"status" gorm:"column:status" validate:"required,oneof=available booked maintenance"`
-	PricePerHour float64  `json:"price_per_hour" gorm:"column:price_per_hour" validate:"required,gt=0"`
-	Metadata     string   `json:"metadata" gorm:"column:metadata;type:text"`
+	Mark         string `json:"mark" gorm:"column:mark" validate:"required"`
+	CarModel     string `json:"model" gorm:"column:model" validate:"required"`
+	Category     string `json:"category" gorm:"column:category" validate:"required"`
+	Status       string `json:"status" gorm:"column:status" validate:"required,oneof=available booked maintenance"`
+	PricePerHour Money  `json:"price_per_hour" gorm:"column:price_per_hour" validate:"required,gt=0"`
+	Metadata     string `json:"metadata" gorm:"column:metadata;type:text"`
+	// Jurisdiction is the ISO 3166 code of the branch the car is rented from; it picks the TaxRate.
+	Jurisdiction string   `json:"jurisdiction" gorm:"column:jurisdiction;default:KZ"`
 	Rating       float64  `json:"rating" gorm:"column:rating" validate:"gte=0,lte=5"`
 	Rentals      []Rental `json:"rentals" gorm:"foreignKey:CarID"`
 }
 
 type Rental struct {
 	gorm.Model
-	UserID      uint        `json:"user_id" gorm:"column:user_id;index" validate:"required"`
-	CarID       uint        `json:"car_id" gorm:"column:car_id;index" validate:"required"`
-	StartDate   time.Time   `json:"start_date" gorm:"column:start_date" validate:"required"`
-	EndDate     time.Time   `json:"end_date" gorm:"column:end_date" validate:"required,gtfield=StartDate"`
-	TotalPrice  float64     `json:"total_price" gorm:"column:total_price" validate:"required,gt=0"`
-	Status      string      `json:"status" gorm:"column:status" validate:"required,oneof=pending active completed cancelled"`
-	User        *User       `json:"user" gorm:"foreignKey:UserID"`
-	Car         *Car        `json:"car" gorm:"foreignKey:CarID"`
-	Transaction *Transaction `json:"transaction" gorm:"foreignKey:RentalID"`
+	UserID     uint      `json:"user_id" gorm:"column:user_id;index" validate:"required"`
+	CarID      uint      `json:"car_id" gorm:"column:car_id;index" validate:"required"`
+	StartDate  time.Time `json:"start_date" gorm:"column:start_date" validate:"required"`
+	EndDate    time.Time `json:"end_date" gorm:"column:end_date" validate:"required,gtfield=StartDate"`
+	TotalPrice Money     `json:"total_price" gorm:"column:total_price" validate:"required,gt=0"`
+	Status     string    `json:"status" gorm:"column:status" validate:"required,oneof=pending confirmed active overdue completed cancelled expired no_show"`
+	// PricingVersion is the PricingRuleSet version that priced the rental; 0 for rentals priced before versioning.
+	PricingVersion uint `json:"pricing_version" gorm:"column:pricing_version"`
+	// PriceBreakdown is the itemized price shown to the renter when booking.
+	PriceBreakdown *PriceBreakdown `json:"price_breakdown,omitempty" gorm:"column:price_breakdown;type:text;serializer:json"`
+	// PointsRedeemed are loyalty points spent on the payment of this rental.
+	PointsRedeemed int `json:"points_redeemed,omitempty" gorm:"column:points_redeemed"`
+	// Currency and ExchangeRate are the renter's currency and its rate at booking
+	// time; TotalInCurrency is TotalPrice converted at that rate.
+	Currency        string  `json:"currency" gorm:"column:currency;default:KZT"`
+	ExchangeRate    float64 `json:"exchange_rate" gorm:"column:exchange_rate;default:1"`
+	TotalInCurrency Money   `json:"total_in_currency" gorm:"column:total_in_currency"`
+	// NetPrice and TaxAmount split TotalPrice, which is gross.
+	NetPrice  Money `json:"net_price" gorm:"column:net_price"`
+	TaxAmount Money `json:"tax_amount" gorm:"column:tax_amount"`
+	// PickedUpAt is when the car was handed over and the rental became active.
+	PickedUpAt *time.Time `json:"picked_up_at,omitempty" gorm:"column:picked_up_at"`
+	// ReturnedAt is when the car was brought back; Refunded is what was paid
+	// back of the price, e.g. for an early return.
+	ReturnedAt *time.Time `json:"returned_at,omitempty" gorm:"column:returned_at"`
+	Refunded   Money      `json:"refunded" gorm:"column:refunded;default:0"`
+	// DepositHeld is what is still held of the deposit taken at payment;
+	// DepositCaptured is what charges have taken from it.
+	DepositHeld     Money        `json:"deposit_held" gorm:"column:deposit_held;default:0"`
+	DepositCaptured Money        `json:"deposit_captured" gorm:"column:deposit_captured;default:0"`
+	User            *User        `json:"user" gorm:"foreignKey:UserID"`
+	Car             *Car         `json:"car" gorm:"foreignKey:CarID"`
+	Transaction     *Transaction `json:"transaction" gorm:"foreignKey:RentalID"`
+}
+
+// ExchangeRate is the price of one unit of Currency in BaseCurrency from
+// EffectiveFrom on. Rates are never edited: a newer row replaces the rate, so
+// the table is also the rate history.
+type ExchangeRate struct {
+	gorm.Model
+	Currency      string    `json:"currency" gorm:"column:currency;index" validate:"required,len=3"`
+	Rate          float64   `json:"rate" gorm:"column:rate" validate:"required,gt=0"`
+	EffectiveFrom time.Time `json:"effective_from" gorm:"column:effective_from;index" validate:"required"`
+	CreatedBy     *uint     `json:"created_by,omitempty" gorm:"column:created_by"`
+}
+
+// TaxRate is the VAT rate of a jurisdiction from EffectiveFrom on. Rates are
+// never edited: a newer row replaces the rate, so the table is the history.
+type TaxRate struct {
+	gorm.Model
+	Jurisdiction  string    `json:"jurisdiction" gorm:"column:jurisdiction;index" validate:"required"`
+	Name          string    `json:"name" gorm:"column:name" validate:"required"`
+	Percent       float64   `json:"percent" gorm:"column:percent" validate:"gte=0,lte=100"`
+	EffectiveFrom time.Time `json:"effective_from" gorm:"column:effective_from;index" validate:"required"`
+	CreatedBy     *uint     `json:"created_by,omitempty" gorm:"column:created_by"`
+}
+
+// CompanyRequisites are the seller details printed on invoices.
+type CompanyRequisites struct {
+	Name     string `json:"name" gorm:"column:name"`
+	TaxID    string `json:"tax_id" gorm:"column:tax_id"`
+	Address  string `json:"address" gorm:"column:address"`
+	BankName string `json:"bank_name" gorm:"column:bank_name"`
+	IBAN     string `json:"iban" gorm:"column:iban"`
+	BIC      string `json:"bic" gorm:"column:bic"`
+}
+
+// CompanyProfile holds our requisites; there is a single row.
+type CompanyProfile struct {
+	gorm.Model
+	CompanyRequisites
+}
+
+// InvoiceBuyer are the customer details printed on an invoice.
+type InvoiceBuyer struct {
+	Name    string `json:"name"`
+	Email   string `json:"email"`
+	Company string `json:"company,omitempty"`
+	TaxID   string `json:"tax_id,omitempty"`
+	Address string `json:"address,omitempty"`
+}
+
+// Invoice is a VAT invoice for a paid rental. Numbers run without gaps within
+// a calendar year (Year, Sequence) and invoices are never deleted or changed.
+type Invoice struct {
+	gorm.Model
+	Number   string            `json:"number" gorm:"column:number;uniqueIndex" validate:"required"`
+	Year     int               `json:"year" gorm:"column:year;uniqueIndex:idx_invoices_year_sequence" validate:"required"`
+	Sequence int               `json:"sequence" gorm:"column:sequence;uniqueIndex:idx_invoices_year_sequence" validate:"required"`
+	RentalID uint              `json:"rental_id" gorm:"column:rental_id;uniqueIndex" validate:"required"`
+	UserID   uint              `json:"user_id" gorm:"column:user_id;index" validate:"required"`
+	IssuedAt time.Time         `json:"issued_at" gorm:"column:issued_at" validate:"required"`
+	Seller   CompanyRequisites `json:"seller" gorm:"column:seller;type:text;serializer:json"`
+	Buyer    InvoiceBuyer      `json:"buyer" gorm:"column:buyer;type:text;serializer:json"`
+	Lines    []TaxSplit        `json:"lines" gorm:"column:lines;type:text;serializer:json"`
+	Net      Money             `json:"net" gorm:"column:net"`
+	Tax      Money             `json:"tax" gorm:"column:tax"`
+	Gross    Money             `json:"gross" gorm:"column:gross"`
+	Currency string            `json:"currency" gorm:"column:currency"`
+}
+
+// PricingRuleSet is one immutable version of the pricing rules. A rental is
+// priced by the latest version whose EffectiveFrom has passed at booking time.
+type PricingRuleSet struct {
+	gorm.Model
+	Version       uint         `json:"version" gorm:"column:version;uniqueIndex" validate:"required"`
+	EffectiveFrom time.Time    `json:"effective_from" gorm:"column:effective_from;index" validate:"required"`
+	Rules         PricingRules `json:"rules" gorm:"column:rules;type:text;serializer:json"`
+	Note          string       `json:"note,omitempty" gorm:"column:note"`
+	CreatedBy     *uint        `json:"created_by,omitempty" gorm:"column:created_by"`
+}
+
+// PricingRules is the rule document of a PricingRuleSet. Multipliers scale
+// the price: 1.2 is a 20% surcharge, 0.9 a 10% discount; 0 means "not set".
+type PricingRules struct {
+	// TimeZone decides which hours fall on weekends, holidays and seasons. Defaults to UTC.
+	TimeZone          string            `json:"time_zone,omitempty"`
+	Tiers             []PricingTier     `json:"tiers,omitempty"`
+	WeekendMultiplier float64           `json:"weekend_multiplier,omitempty"`
+	Holidays          []PricingHoliday  `json:"holidays,omitempty"`
+	Seasons           []PricingSeason   `json:"seasons,omitempty"`
+	RatingModifiers   []RatingModifier  `json:"rating_modifiers,omitempty"`
+	Overrides         []PricingOverride `json:"overrides,omitempty"`
+	Extras            []PricingExtra    `json:"extras,omitempty"`
+	Dynamic           *DynamicPricing   `json:"dynamic,omitempty"`
+	// LateReturn prices returning the car after the rental's end; unset uses the defaults.
+	LateReturn *LateReturnPolicy `json:"late_return,omitempty"`
+	// EarlyReturn refunds the hours left when the car comes back before the end; unset refunds nothing.
+	EarlyReturn *EarlyReturnPolicy `json:"early_return,omitempty"`
+}
+
+// EarlyReturnPolicy refunds a car returned at least MinUnusedHours before the
+// rental's end: the unused whole hours' share of what the renter paid, in
+// full or Percent of it.
+type EarlyReturnPolicy struct {
+	Refund         string  `json:"refund"`
+	MinUnusedHours float64 `json:"min_unused_hours,omitempty"`
+	Percent        float64 `json:"percent,omitempty"`
+}
+
+// LateReturnPolicy charges a car returned more than GraceMinutes after the
+// rental's end: Flat plus every started hour late at HourlyMultiplier times
+// the rental's hourly rate, at most Cap (0 means no cap).
+type LateReturnPolicy struct {
+	GraceMinutes     int     `json:"grace_minutes"`
+	HourlyMultiplier float64 `json:"hourly_multiplier"`
+	Flat             Money   `json:"flat,omitempty"`
+	Cap              Money   `json:"cap,omitempty"`
+}
+
+// DynamicPricing scales prices with the projected utilization of the car's
+// category during the rental: 1 + Sensitivity × (utilization − TargetUtilization),
+// clamped to [Floor, Ceiling].
+type DynamicPricing struct {
+	Enabled           bool     `json:"enabled"`
+	TargetUtilization float64  `json:"target_utilization"`
+	Sensitivity       float64  `json:"sensitivity"`
+	Floor             float64  `json:"floor"`
+	Ceiling           float64  `json:"ceiling"`
+	Categories        []string `json:"categories,omitempty"`
+}
+
+// PricingTier applies to rentals of at least MinHours, e.g. daily or weekly rates.
+// The tier with the largest matching MinHours wins.
+type PricingTier struct {
+	Name       string  `json:"name"`
+	MinHours   float64 `json:"min_hours"`
+	Multiplier float64 `json:"multiplier"`
+}
+
+// PricingHoliday surcharges every hour of Date (YYYY-MM-DD). It replaces the weekend surcharge.
+type PricingHoliday struct {
+	Date       string  `json:"date"`
+	Name       string  `json:"name,omitempty"`
+	Multiplier float64 `json:"multiplier"`
+}
+
+// PricingSeason applies every year from From to To inclusive (MM-DD); the range may wrap the new year.
+type PricingSeason struct {
+	Name       string  `json:"name"`
+	From       string  `json:"from"`
+	To         string  `json:"to"`
+	Multiplier float64 `json:"multiplier"`
+}
+
+// RatingModifier applies when Above < user rating < Below; nil bounds are open.
+// The first matching modifier wins.
+type RatingModifier struct {
+	Above      *float64 `json:"above,omitempty"`
+	Below      *float64 `json:"below,omitempty"`
+	Multiplier float64  `json:"multiplier"`
+}
+
+// PricingOverride changes the rules for one category or one car. Car overrides
+// are applied on top of category overrides, field by field.
+type PricingOverride struct {
+	Category          string        `json:"category,omitempty"`
+	CarID             uint          `json:"car_id,omitempty"`
+	HourlyRate        *Money        `json:"hourly_rate,omitempty"`
+	Multiplier        *float64      `json:"multiplier,omitempty"`
+	WeekendMultiplier *float64      `json:"weekend_multiplier,omitempty"`
+	Tiers             []PricingTier `json:"tiers,omitempty"`
+}
+
+// PricingExtra is an optional add-on the renter can pick, e.g. a child seat.
+type PricingExtra struct {
+	Code string `json:"code"`
+	Name string `json:"name"`
+	Fee  Money  `json:"fee"`
+	// PerDay charges Fee for every started day instead of once per rental.
+	PerDay bool `json:"per_day,omitempty"`
+}
+
+// PriceBreakdown is the itemized price of a rental. Total is Base plus every
+// line amount (discounts are negative); the deposit is held separately.
+type PriceBreakdown struct {
+	PricingVersion uint           `json:"pricing_version"`
+	HourlyRate     Money          `json:"hourly_rate"`
+	Hours          float64        `json:"hours"`
+	Base           Money          `json:"base"`
+	Surcharges     []PriceLine    `json:"surcharges"`
+	DurationTier   *PriceLine     `json:"duration_tier,omitempty"`
+	RatingModifier *PriceLine     `json:"rating_modifier,omitempty"`
+	Demand         *DemandPricing `json:"demand,omitempty"`
+	Discounts      []PriceLine    `json:"discounts"`
+	Fees           []PriceLine    `json:"fees"`
+	Taxes          []PriceLine    `json:"taxes"`
+	Total          Money          `json:"total"`
+	Deposit        Money          `json:"deposit"`
+	// Net and Tax split Total; TaxSplits shows the split of the rental and of each fee.
+	Net       Money      `json:"net"`
+	Tax       Money      `json:"tax"`
+	TaxSplits []TaxSplit `json:"tax_splits,omitempty"`
+	// Currency, ExchangeRate and TotalInCurrency show Total in the renter's currency.
+	Currency        string  `json:"currency,omitempty"`
+	ExchangeRate    float64 `json:"exchange_rate,omitempty"`
+	TotalInCurrency Money   `json:"total_in_currency,omitempty"`
+}
+
+// DemandPricing explains the dynamic multiplier applied to a price.
+type DemandPricing struct {
+	Category          string  `json:"category"`
+	Cars              int     `json:"cars"`
+	WindowHours       float64 `json:"window_hours"`
+	BookedHours       float64 `json:"booked_hours"`
+	Utilization       float64 `json:"utilization"`
+	TargetUtilization float64 `json:"target_utilization"`
+	Sensitivity       float64 `json:"sensitivity"`
+	RawMultiplier     float64 `json:"raw_multiplier"`
+	Floor             float64 `json:"floor"`
+	Ceiling           float64 `json:"ceiling"`
+	Multiplier        float64 `json:"multiplier"`
+	Amount            Money   `json:"amount"`
+	Explanation       string  `json:"explanation"`
+}
+
+// TaxSplit is the net, tax and gross amount of one taxed part of a price:
+// the rental itself or one fee.
+type TaxSplit struct {
+	Code       string  `json:"code"`
+	Name       string  `json:"name"`
+	Net        Money   `json:"net"`
+	TaxPercent float64 `json:"tax_percent"`
+	Tax        Money   `json:"tax"`
+	Gross      Money   `json:"gross"`
+}
+
+// PriceLine is one line of a PriceBreakdown.
+type PriceLine struct {
+	Code   string `json:"code"`
+	Name   string `json:"name,omitempty"`
+	Amount Money  `json:"amount"`
+}
+
+// PromoCode is a marketing campaign code that discounts a rental.
+type PromoCode struct {
+	gorm.Model
+	Code         string `json:"code" gorm:"column:code;uniqueIndex" validate:"required"`
+	Description  string `json:"description,omitempty" gorm:"column:description"`
+	DiscountType string `json:"discount_type" gorm:"column:discount_type" validate:"required,oneof=percent fixed"`
+	// DiscountValue is the amount of a fixed discount and DiscountPercent the
+	// basis points of a percent one (1000 is 10%); the other one is zero.
+	DiscountValue   Money      `json:"discount_value" gorm:"column:discount_value" validate:"gte=0"`
+	DiscountPercent int        `json:"discount_percent" gorm:"column:discount_percent;not null;default:0" validate:"gte=0,lte=10000"`
+	ValidFrom       *time.Time `json:"valid_from,omitempty" gorm:"column:valid_from"`
+	ValidUntil      *time.Time `json:"valid_until,omitempty" gorm:"column:valid_until"`
+	// MaxUses and MaxUsesPerUser are 0 for unlimited.
+	MaxUses         int      `json:"max_uses" gorm:"column:max_uses" validate:"gte=0"`
+	MaxUsesPerUser  int      `json:"max_uses_per_user" gorm:"column:max_uses_per_user" validate:"gte=0"`
+	UsedCount       int      `json:"used_count" gorm:"column:used_count"`
+	MinHours        float64  `json:"min_hours" gorm:"column:min_hours" validate:"gte=0"`
+	Categories      []string `json:"categories" gorm:"column:categories;type:text;serializer:json"`
+	FirstRentalOnly bool     `json:"first_rental_only" gorm:"column:first_rental_only"`
+	Active          bool     `json:"active" gorm:"column:active"`
+}
+
+// PromoRedemption records that a promo code was used for a rental.
+type PromoRedemption struct {
+	gorm.Model
+	PromoCodeID uint  `json:"promo_code_id" gorm:"column:promo_code_id;index" validate:"required"`
+	UserID      uint  `json:"user_id" gorm:"column:user_id;index" validate:"required"`
+	RentalID    uint  `json:"rental_id" gorm:"column:rental_id;uniqueIndex" validate:"required"`
+	Amount      Money `json:"amount" gorm:"column:amount"`
+}
+
+// LoyaltyEntry is one line of a user's points ledger; the balance is the sum
+// of Points. Earn and refund entries are lots spent oldest first: Remaining is
+// what is left of the lot and expires at ExpiresAt.
+type LoyaltyEntry struct {
+	gorm.Model
+	UserID    uint       `json:"user_id" gorm:"column:user_id;index" validate:"required"`
+	Type      string     `json:"type" gorm:"column:type;index" validate:"required,oneof=earn redeem expire refund"`
+	Points    int        `json:"points" gorm:"column:points"`
+	RentalID  *uint      `json:"rental_id,omitempty" gorm:"column:rental_id;index"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty" gorm:"column:expires_at;index"`
+	Remaining int        `json:"-" gorm:"column:remaining"`
+	Note      string     `json:"note,omitempty" gorm:"column:note"`
 }
 
 type Transaction struct {
 	gorm.Model
-	UserID   uint     `json:"user_id" gorm:"column:user_id;index" validate:"required"`
-	RentalID *uint    `json:"rental_id,omitempty" gorm:"column:rental_id;index"`
-	Type     string   `json:"type" gorm:"column:type" validate:"required,oneof=payment topup"`
-	Amount   float64  `json:"amount" gorm:"column:amount" validate:"required,gt=0"`
-	Status   string   `json:"status" gorm:"column:status" validate:"required,oneof=success failed"`
-	Rental   *Rental  `json:"rental,omitempty" gorm:"foreignKey:RentalID"`
+	UserID   uint   `json:"user_id" gorm:"column:user_id;index" validate:"required"`
+	RentalID *uint  `json:"rental_id,omitempty" gorm:"column:rental_id;index"`
+	Type     string `json:"type" gorm:"column:type" validate:"required,oneof=payment topup refund deposit_hold deposit_release deposit_capture"`
+	Amount   Money  `json:"amount" gorm:"column:amount" validate:"required,gt=0"`
+	// Currency and ExchangeRate are what the user paid or received in;
+	// CurrencyAmount is Amount in that currency. Amount is always in BaseCurrency.
+	Currency       string  `json:"currency" gorm:"column:currency;default:KZT"`
+	ExchangeRate   float64 `json:"exchange_rate" gorm:"column:exchange_rate;default:1"`
+	CurrencyAmount Money   `json:"currency_amount" gorm:"column:currency_amount"`
+	Status         string  `json:"status" gorm:"column:status" validate:"required,oneof=success failed"`
+	Rental         *Rental `json:"rental,omitempty" gorm:"foreignKey:RentalID"`
+}
+
+// RentalTransition is one entry of a rental's status history. The booking
+// itself is recorded as a transition from the empty status to pending.
+type RentalTransition struct {
+	gorm.Model
+	RentalID   uint      `json:"rental_id" gorm:"column:rental_id;index" validate:"required"`
+	Event      string    `json:"event" gorm:"column:event" validate:"required"`
+	FromStatus string    `json:"from_status" gorm:"column:from_status"`
+	ToStatus   string    `json:"to_status" gorm:"column:to_status" validate:"required"`
+	ActorID    *uint     `json:"actor_id,omitempty" gorm:"column:actor_id"`
+	Reason     string    `json:"reason,omitempty" gorm:"column:reason"`
+	At         time.Time `json:"at" gorm:"column:at" validate:"required"`
+}
+
+// RentalCharge is damage, a late fee or a fine raised against a rental. It is
+// captured from the held deposit, then taken FromBalance; what neither covers
+// is Outstanding, taken from later top-ups, and no new booking can be made
+// until it is paid.
+type RentalCharge struct {
+	gorm.Model
+	RentalID    uint   `json:"rental_id" gorm:"column:rental_id;index" validate:"required"`
+	UserID      uint   `json:"user_id" gorm:"column:user_id;index" validate:"required"`
+	Type        string `json:"type" gorm:"column:type" validate:"required,oneof=damage late_fee fine"`
+	Amount      Money  `json:"amount" gorm:"column:amount" validate:"required,gt=0"`
+	Captured    Money  `json:"captured" gorm:"column:captured"`
+	FromBalance Money  `json:"from_balance" gorm:"column:from_balance"`
+	Outstanding Money  `json:"outstanding" gorm:"column:outstanding"`
+	Note        string `json:"note,omitempty" gorm:"column:note"`
+	CreatedBy   *uint  `json:"created_by,omitempty" gorm:"column:created_by"`
+}
+
+// RentalExtension moves a rental's end from PreviousEnd to NewEnd. The extra
+// hours are priced on their own with the rules in effect when it was asked
+// for; a paid rental is Charged the price, an unpaid one owes it with the rest (Due).
+type RentalExtension struct {
+	gorm.Model
+	RentalID       uint            `json:"rental_id" gorm:"column:rental_id;index" validate:"required"`
+	PreviousEnd    time.Time       `json:"previous_end" gorm:"column:previous_end" validate:"required"`
+	NewEnd         time.Time       `json:"new_end" gorm:"column:new_end" validate:"required,gtfield=PreviousEnd"`
+	Amount         Money           `json:"amount" gorm:"column:amount"`
+	PriceBreakdown *PriceBreakdown `json:"price_breakdown,omitempty" gorm:"column:price_breakdown;type:text;serializer:json"`
+	Settlement     string          `json:"settlement" gorm:"column:settlement" validate:"required,oneof=charged due"`
+	TransactionID  *uint           `json:"transaction_id,omitempty" gorm:"column:transaction_id"`
+	CreatedBy      *uint           `json:"created_by,omitempty" gorm:"column:created_by"`
+}
+
+// RentalModification is one change of the car or the dates of a rental that
+// has not started. The rental is repriced as a whole; Difference is what the
+// renter paid (positive) or got back (negative) for a paid rental, or what the
+// unpaid price changed by.
+type RentalModification struct {
+	gorm.Model
+	RentalID      uint            `json:"rental_id" gorm:"column:rental_id;index" validate:"required"`
+	PreviousCarID uint            `json:"previous_car_id" gorm:"column:previous_car_id" validate:"required"`
+	CarID         uint            `json:"car_id" gorm:"column:car_id" validate:"required"`
+	PreviousStart time.Time       `json:"previous_start" gorm:"column:previous_start" validate:"required"`
+	PreviousEnd   time.Time       `json:"previous_end" gorm:"column:previous_end" validate:"required"`
+	StartDate     time.Time       `json:"start_date" gorm:"column:start_date" validate:"required"`
+	EndDate       time.Time       `json:"end_date" gorm:"column:end_date" validate:"required,gtfield=StartDate"`
+	PreviousTotal Money           `json:"previous_total" gorm:"column:previous_total"`
+	Total         Money           `json:"total" gorm:"column:total"`
+	Difference    Money           `json:"difference" gorm:"column:difference"`
+	PreviousPrice *PriceBreakdown `json:"previous_price,omitempty" gorm:"column:previous_price;type:text;serializer:json"`
+	Reason        string          `json:"reason,omitempty" gorm:"column:reason"`
+	CreatedBy     *uint           `json:"created_by,omitempty" gorm:"column:created_by"`
+}
+
+// Notification is a message to a user, kept in their inbox until read.
+type Notification struct {
+	gorm.Model
+	UserID   uint       `json:"user_id" gorm:"column:user_id;index" validate:"required"`
+	Type     string     `json:"type" gorm:"column:type" validate:"required"`
+	Title    string     `json:"title" gorm:"column:title" validate:"required"`
+	Body     string     `json:"body" gorm:"column:body"`
+	RentalID *uint      `json:"rental_id,omitempty" gorm:"column:rental_id;index"`
+	ReadAt   *time.Time `json:"read_at,omitempty" gorm:"column:read_at"`
+}
+
+// CarEvent is one entry in a car's history timeline.
+type CarEvent struct {
+	gorm.Model
+	CarID    uint   `json:"car_id" gorm:"column:car_id;index" validate:"required"`
+	Type     string `json:"type" gorm:"column:type;index" validate:"required"`
+	ActorID  *uint  `json:"actor_id,omitempty" gorm:"column:actor_id"`
+	RentalID *uint  `json:"rental_id,omitempty" gorm:"column:rental_id;index"`
+	OldValue string `json:"old_value,omitempty" gorm:"column:old_value"`
+	NewValue string `json:"new_value,omitempty" gorm:"column:new_value"`
+	Note     string `json:"note,omitempty" gorm:"column:note;type:text"`
+}
+
+// CarDevice holds the credentials of the tracker installed in a car.
+type CarDevice struct {
+	gorm.Model
+	CarID      uint       `json:"car_id" gorm:"column:car_id;uniqueIndex" validate:"required"`
+	DeviceID   string     `json:"device_id" gorm:"column:device_id;uniqueIndex" validate:"required"`
+	SecretHash string     `json:"-" gorm:"column:secret_hash" validate:"required"`
+	LastSeenAt *time.Time `json:"last_seen_at,omitempty" gorm:"column:last_seen_at"`
+}
+
+// TelemetryPoint is one tracker reading. Coordinates are stored in microdegrees
+// and the odometer in meters to keep rows small.
+type TelemetryPoint struct {
+	ID           uint      `json:"-" gorm:"primaryKey"`
+	CarID        uint      `json:"car_id" gorm:"column:car_id;uniqueIndex:idx_telemetry_car_time,priority:1"`
+	RecordedAt   time.Time `json:"recorded_at" gorm:"column:recorded_at;uniqueIndex:idx_telemetry_car_time,priority:2"`
+	RentalID     *uint     `json:"rental_id,omitempty" gorm:"column:rental_id;index"`
+	LatE6        int32     `json:"-" gorm:"column:lat_e6"`
+	LngE6        int32     `json:"-" gorm:"column:lng_e6"`
+	OdometerM    int64     `json:"-" gorm:"column:odometer_m"`
+	FuelLevel    *uint8    `json:"fuel_level,omitempty" gorm:"column:fuel_level"`
+	BatteryLevel *uint8    `json:"battery_level,omitempty" gorm:"column:battery_level"`
+	Ignition     bool      `json:"ignition" gorm:"column:ignition"`
+	Downsampled  bool      `json:"downsampled" gorm:"column:downsampled;index"`
+}
+
+// CarTelemetryState is the latest known reading of a car.
+type CarTelemetryState struct {
+	CarID        uint      `json:"car_id" gorm:"column:car_id;primaryKey;autoIncrement:false"`
+	RecordedAt   time.Time `json:"recorded_at" gorm:"column:recorded_at"`
+	RentalID     *uint     `json:"rental_id,omitempty" gorm:"column:rental_id"`
+	LatE6        int32     `json:"-" gorm:"column:lat_e6"`
+	LngE6        int32     `json:"-" gorm:"column:lng_e6"`
+	OdometerM    int64     `json:"-" gorm:"column:odometer_m"`
+	FuelLevel    *uint8    `json:"fuel_level,omitempty" gorm:"column:fuel_level"`
+	BatteryLevel *uint8    `json:"battery_level,omitempty" gorm:"column:battery_level"`
+	Ignition     bool      `json:"ignition" gorm:"column:ignition"`
+	UpdatedAt    time.Time `json:"updated_at"`
+}
+
+// CarDocument is a registration, technical inspection or insurance document of a car.
+type CarDocument struct {
+	gorm.Model
+	CarID     uint      `json:"car_id" gorm:"column:car_id;index" validate:"required"`
+	Type      string    `json:"type" gorm:"column:type" validate:"required,oneof=registration inspection insurance"`
+	Number    string    `json:"number" gorm:"column:number" validate:"required"`
+	IssuedAt  time.Time `json:"issued_at" gorm:"column:issued_at" validate:"required"`
+	ExpiresAt time.Time `json:"expires_at" gorm:"column:expires_at;index" validate:"required,gtfield=IssuedAt"`
+	FileRef   string    `json:"file_ref,omitempty" gorm:"column:file_ref"`
+	Car       *Car      `json:"car,omitempty" gorm:"foreignKey:CarID"`
 }
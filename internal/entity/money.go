@@ -0,0 +1,92 @@
+package entity
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"strings"
+)
+
+// MinorUnits is the number of minor units in one currency unit.
+const MinorUnits = 100
+
+var errInvalidMoney = errors.New("invalid money amount")
+
+// Money is an amount in minor units. It is stored as an integer and added and
+// subtracted exactly; JSON carries it as a decimal number with two fractional
+// digits, e.g. 1234 is 12.34.
+//
+// Rounding rules:
+//   - a decimal with more than two fractional digits is rounded to the nearest
+//     minor unit, halves away from zero (10.005 becomes 10.01, -10.005 becomes -10.01);
+//   - an amount scaled by a multiplier or a part of an hour is rounded the same
+//     way, once per price line; totals are the exact sum of their lines.
+type Money int64
+
+// RoundMoney rounds an amount given in fractional minor units.
+func RoundMoney(minor float64) Money {
+	return Money(math.Round(minor))
+}
+
+// Mul scales the amount and rounds the result.
+func (m Money) Mul(f float64) Money {
+	return RoundMoney(float64(m) * f)
+}
+
+// Float returns the amount in currency units, for display and statistics only.
+func (m Money) Float() float64 {
+	return float64(m) / MinorUnits
+}
+
+func (m Money) String() string {
+	sign, v := "", int64(m)
+	if v < 0 {
+		sign, v = "-", -v
+	}
+	return fmt.Sprintf("%s%d.%02d", sign, v/MinorUnits, v%MinorUnits)
+}
+
+func (m Money) MarshalJSON() ([]byte, error) {
+	return []byte(m.String()), nil
+}
+
+func (m *Money) UnmarshalJSON(data []byte) error {
+	data = bytes.TrimSpace(data)
+	if string(data) == "null" {
+		return nil
+	}
+	if len(data) > 0 && data[0] == '"' {
+		return fmt.Errorf("%w: %s must be a number", errInvalidMoney, data)
+	}
+	v, err := ParseMoney(string(data))
+	if err != nil {
+		return err
+	}
+	*m = v
+	return nil
+}
+
+// ParseMoney parses a decimal amount in currency units, e.g. "12.34" or "1e3",
+// applying the rounding rules of Money.
+func ParseMoney(s string) (Money, error) {
+	s = strings.TrimSpace(s)
+	if strings.ContainsAny(s, "/xXpP_") {
+		return 0, fmt.Errorf("%w: %q", errInvalidMoney, s)
+	}
+	r, ok := new(big.Rat).SetString(s)
+	if !ok {
+		return 0, fmt.Errorf("%w: %q", errInvalidMoney, s)
+	}
+	r.Mul(r, big.NewRat(MinorUnits, 1))
+
+	q, rem := new(big.Int).QuoRem(r.Num(), r.Denom(), new(big.Int))
+	if rem.Sign() != 0 && new(big.Int).Abs(new(big.Int).Lsh(rem, 1)).Cmp(r.Denom()) >= 0 {
+		q.Add(q, big.NewInt(int64(rem.Sign())))
+	}
+	if !q.IsInt64() {
+		return 0, fmt.Errorf("%w: %q is out of range", errInvalidMoney, s)
+	}
+	return Money(q.Int64()), nil
+}
@@ -0,0 +1,114 @@
+package entity
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+func TestParseMoney(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    Money
+		wantErr bool
+	}{
+		{"12.34", 1234, false},
+		{"12", 1200, false},
+		{"0.1", 10, false},
+		{" 7.5 ", 750, false},
+		{"1e3", 100000, false},
+		{"-3.21", -321, false},
+		{"10.005", 1001, false},
+		{"-10.005", -1001, false},
+		{"10.0049", 1000, false},
+		{"0.004", 0, false},
+		{"", 0, true},
+		{"abc", 0, true},
+		{"1/3", 0, true},
+		{"0x10", 0, true},
+		{"1_000", 0, true},
+		{"1e30", 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			got, err := ParseMoney(tt.in)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil && !errors.Is(err, errInvalidMoney) {
+				t.Errorf("err = %v, want errInvalidMoney", err)
+			}
+			if got != tt.want {
+				t.Errorf("got %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRoundMoney(t *testing.T) {
+	tests := []struct {
+		minor float64
+		want  Money
+	}{
+		{100.4, 100},
+		{100.5, 101},
+		{-100.5, -101},
+		{-100.4, -100},
+		{0, 0},
+	}
+	for _, tt := range tests {
+		if got := RoundMoney(tt.minor); got != tt.want {
+			t.Errorf("RoundMoney(%v) = %d, want %d", tt.minor, got, tt.want)
+		}
+	}
+}
+
+func TestMoneyMul(t *testing.T) {
+	tests := []struct {
+		m    Money
+		f    float64
+		want Money
+	}{
+		{1000, 1.5, 1500},
+		{999, 0.5, 500},
+		{333, 1.0 / 3, 111},
+		{-999, 0.5, -500},
+		{1000, 0, 0},
+	}
+	for _, tt := range tests {
+		if got := tt.m.Mul(tt.f); got != tt.want {
+			t.Errorf("%d.Mul(%v) = %d, want %d", tt.m, tt.f, got, tt.want)
+		}
+	}
+}
+
+func TestMoneyJSON(t *testing.T) {
+	tests := []struct {
+		m    Money
+		want string
+	}{
+		{1234, "12.34"},
+		{5, "0.05"},
+		{0, "0.00"},
+		{-5, "-0.05"},
+		{-1234, "-12.34"},
+	}
+	for _, tt := range tests {
+		raw, err := json.Marshal(tt.m)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(raw) != tt.want {
+			t.Errorf("Marshal(%d) = %s, want %s", tt.m, raw, tt.want)
+		}
+		var back Money
+		if err := json.Unmarshal(raw, &back); err != nil || back != tt.m {
+			t.Errorf("Unmarshal(%s) = %d, %v, want %d", raw, back, err, tt.m)
+		}
+	}
+
+	var m Money
+	if err := json.Unmarshal([]byte(`"12.34"`), &m); err == nil {
+		t.Error("a string amount was accepted")
+	}
+}
@@ -0,0 +1,30 @@
+package database
+
+import (
+	"log"
+
+	"gorm.io/gorm"
+
+	"github.com/CMPNION/Car-Rental-API.git/internal/entity"
+)
+
+// seedCarCategories заполняет пустую таблицу категорий исходным набором.
+// Если администратор уже правил категории, таблица не трогается.
+func seedCarCategories(db *gorm.DB) {
+	var count int64
+	if err := db.Unscoped().Model(&entity.CarCategory{}).Count(&count).Error; err != nil {
+		log.Fatalf("Failed to read car categories: %v", err)
+	}
+	if count > 0 {
+		return
+	}
+
+	categories := []entity.CarCategory{
+		{Code: entity.CarCategoryEconomy, DisplayName: "Economy", DefaultDeposit: 100 * entity.MinorUnits, MinDriverAge: 18, SortOrder: 10},
+		{Code: entity.CarCategoryBusiness, DisplayName: "Business", DefaultDeposit: 300 * entity.MinorUnits, MinDriverAge: 21, SortOrder: 20},
+		{Code: entity.CarCategoryLuxury, DisplayName: "Luxury", DefaultDeposit: 1000 * entity.MinorUnits, MinDriverAge: 25, SortOrder: 30},
+	}
+	if err := db.Create(&categories).Error; err != nil {
+		log.Fatalf("Failed to seed car categories: %v", err)
+	}
+}
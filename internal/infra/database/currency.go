@@ -0,0 +1,22 @@
+package database
+
+import (
+	"log"
+
+	"gorm.io/gorm"
+)
+
+// backfillCurrencyAmounts заполняет суммы в валюте клиента у аренд и транзакций,
+// созданных до мультивалютности: они шли в базовой валюте по курсу 1, а колонки
+// currency и exchange_rate AutoMigrate уже заполнил значениями по умолчанию.
+func backfillCurrencyAmounts(db *gorm.DB) {
+	stmts := []string{
+		"UPDATE rentals SET total_in_currency = total_price WHERE total_in_currency IS NULL",
+		"UPDATE transactions SET currency_amount = amount WHERE currency_amount IS NULL",
+	}
+	for _, stmt := range stmts {
+		if err := db.Exec(stmt).Error; err != nil {
+			log.Fatalf("Failed to backfill currency amounts: %v", err)
+		}
+	}
+}
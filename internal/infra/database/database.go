@@ -2,6 +2,7 @@ package database
 
 import (
 	"log"
+	"time"
 
 	"gorm.io/driver/sqlite"
 	"gorm.io/gorm"
@@ -18,25 +19,57 @@ func InitDB(filepath string) *gorm.DB {
 
 	db, err := gorm.Open(sqlite.Open(filepath), &gorm.Config{
 		Logger: logger.Default.LogMode(logger.Info), // Логируем все SQL запросы
+		// SQLite сравнивает даты как текст, поэтому created_at и updated_at
+		// пишем в UTC, как и даты аренды: тогда все границы в запросах тоже в UTC.
+		NowFunc: func() time.Time { return time.Now().UTC() },
 	})
 
 	if err != nil {
 		log.Fatalf("Failed to connect to database: %v", err)
 	}
 
+	migrateMoneyToMinorUnits(db)
+
 	// Автоматическое создание таблиц на основе структур (Auto-Migration)
 	// Добавляйте сюда все ваши модели
 	err = db.AutoMigrate(
 		&entity.User{},
+		&entity.CarCategory{},
 		&entity.Car{},
 		&entity.Rental{},
+		&entity.PricingRuleSet{},
+		&entity.ExchangeRate{},
+		&entity.TaxRate{},
+		&entity.CompanyProfile{},
+		&entity.Invoice{},
+		&entity.PromoCode{},
+		&entity.PromoRedemption{},
+		&entity.LoyaltyEntry{},
 		&entity.Transaction{},
+		&entity.RentalCharge{},
+		&entity.RentalExtension{},
+		&entity.RentalModification{},
+		&entity.RentalTransition{},
+		&entity.Notification{},
+		&entity.CarEvent{},
+		&entity.CarDevice{},
+		&entity.TelemetryPoint{},
+		&entity.CarTelemetryState{},
+		&entity.CarDocument{},
 	)
 
 	if err != nil {
 		log.Fatalf("Failed to migrate database: %v", err)
 	}
 
+	normalizeTimestampsToUTC(db)
+	backfillCurrencyAmounts(db)
+	backfillTaxAmounts(db)
+	movePercentPromoDiscounts(db)
+	seedCarCategories(db)
+	seedPricingRules(db)
+	setupCarSearch(db)
+
 	log.Println("Database connection established and migrated")
 	return db
 }
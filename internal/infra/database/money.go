@@ -0,0 +1,73 @@
+package database
+
+import (
+	"fmt"
+	"log"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// moneyColumns — денежные колонки, которые раньше хранились как REAL в рублях,
+// а теперь хранятся как INTEGER в копейках (entity.Money).
+var moneyColumns = []struct{ table, column string }{
+	{"users", "balance"},
+	{"car_categories", "default_deposit"},
+	{"cars", "price_per_hour"},
+	{"rentals", "total_price"},
+	{"transactions", "amount"},
+	{"promo_codes", "discount_value"},
+	{"promo_redemptions", "amount"},
+}
+
+// migrateMoneyToMinorUnits переводит старые REAL-колонки в копейки. Запускается до
+// AutoMigrate, чтобы GORM не поменял тип колонки сам, без пересчёта значений.
+// Колонка, которая уже INTEGER (или которой ещё нет), пропускается, поэтому
+// миграция идемпотентна. Округление — до ближайшей копейки, половина от нуля
+// (ROUND в SQLite). Суммы в JSON-колонках (price_breakdown, rules) остаются
+// десятичными и читаются entity.Money как есть.
+func migrateMoneyToMinorUnits(db *gorm.DB) {
+	err := db.Transaction(func(tx *gorm.DB) error {
+		for _, c := range moneyColumns {
+			var columnType string
+			if err := tx.Raw("SELECT type FROM pragma_table_info(?) WHERE name = ?", c.table, c.column).
+				Scan(&columnType).Error; err != nil {
+				return err
+			}
+			if !strings.EqualFold(columnType, "real") && !strings.EqualFold(columnType, "numeric") {
+				continue
+			}
+
+			tmp := c.column + "_minor"
+			stmts := []string{
+				fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s integer", c.table, tmp),
+				fmt.Sprintf("UPDATE %s SET %s = CAST(ROUND(%s * 100) AS INTEGER)", c.table, tmp, c.column),
+				fmt.Sprintf("ALTER TABLE %s DROP COLUMN %s", c.table, c.column),
+				fmt.Sprintf("ALTER TABLE %s RENAME COLUMN %s TO %s", c.table, tmp, c.column),
+			}
+			for _, stmt := range stmts {
+				if err := tx.Exec(stmt).Error; err != nil {
+					return fmt.Errorf("%s.%s: %w", c.table, c.column, err)
+				}
+			}
+			log.Printf("Migrated %s.%s to minor units", c.table, c.column)
+		}
+		return nil
+	})
+	if err != nil {
+		log.Fatalf("Failed to migrate money columns: %v", err)
+	}
+}
+
+// movePercentPromoDiscounts переносит процентные скидки старых промокодов из
+// discount_value (сотые доли процента в Money) в discount_percent (базисные
+// пункты). Масштаб у них один и тот же — 10% было 1000 и осталось 1000, — поэтому
+// значение копируется как есть, а discount_value обнуляется. Повторный запуск
+// ничего не меняет: у перенесённых строк discount_value уже 0.
+func movePercentPromoDiscounts(db *gorm.DB) {
+	err := db.Exec(`UPDATE promo_codes SET discount_percent = discount_value, discount_value = 0
+		WHERE discount_type = 'percent' AND discount_value <> 0`).Error
+	if err != nil {
+		log.Fatalf("Failed to migrate percent promo discounts: %v", err)
+	}
+}
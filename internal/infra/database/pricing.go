@@ -0,0 +1,38 @@
+package database
+
+import (
+	"log"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/CMPNION/Car-Rental-API.git/internal/entity"
+)
+
+// seedPricingRules создаёт первую версию правил ценообразования, повторяющую
+// прежнюю логику: -10% при рейтинге выше 4.5 и +20% при рейтинге ниже 2.0.
+func seedPricingRules(db *gorm.DB) {
+	var count int64
+	if err := db.Unscoped().Model(&entity.PricingRuleSet{}).Count(&count).Error; err != nil {
+		log.Fatalf("Failed to read pricing rules: %v", err)
+	}
+	if count > 0 {
+		return
+	}
+
+	high, zero, low := 4.5, 0.0, 2.0
+	initial := entity.PricingRuleSet{
+		Version:       1,
+		EffectiveFrom: time.Unix(0, 0).UTC(),
+		Note:          "Initial rules",
+		Rules: entity.PricingRules{
+			RatingModifiers: []entity.RatingModifier{
+				{Above: &high, Multiplier: 0.9},
+				{Above: &zero, Below: &low, Multiplier: 1.2},
+			},
+		},
+	}
+	if err := db.Create(&initial).Error; err != nil {
+		log.Fatalf("Failed to seed pricing rules: %v", err)
+	}
+}
@@ -0,0 +1,81 @@
+package database
+
+import (
+	"log"
+
+	"gorm.io/gorm"
+)
+
+// carSearchSchema создаёт FTS5-индекс по каталогу и триггеры, которые держат его
+// в актуальном состоянии при любых изменениях таблицы cars (включая soft delete).
+var carSearchSchema = []string{
+	`CREATE VIRTUAL TABLE IF NOT EXISTS cars_fts USING fts5(
+		mark, model, category, metadata,
+		tokenize = 'unicode61 remove_diacritics 2',
+		prefix = '2 3'
+	)`,
+	`CREATE VIRTUAL TABLE IF NOT EXISTS cars_fts_vocab USING fts5vocab(cars_fts, 'row')`,
+	`CREATE TRIGGER IF NOT EXISTS cars_fts_ai AFTER INSERT ON cars
+	WHEN new.deleted_at IS NULL BEGIN
+		INSERT INTO cars_fts(rowid, mark, model, category, metadata)
+		VALUES (new.id, new.mark, new.model, new.category, COALESCE(new.metadata, ''));
+	END`,
+	`CREATE TRIGGER IF NOT EXISTS cars_fts_au AFTER UPDATE ON cars BEGIN
+		DELETE FROM cars_fts WHERE rowid = old.id;
+		INSERT INTO cars_fts(rowid, mark, model, category, metadata)
+		SELECT new.id, new.mark, new.model, new.category, COALESCE(new.metadata, '')
+		WHERE new.deleted_at IS NULL;
+	END`,
+	`CREATE TRIGGER IF NOT EXISTS cars_fts_ad AFTER DELETE ON cars BEGIN
+		DELETE FROM cars_fts WHERE rowid = old.id;
+	END`,
+}
+
+// carSearchTriggers — триггеры из carSearchSchema.
+var carSearchTriggers = []string{"cars_fts_ai", "cars_fts_au", "cars_fts_ad"}
+
+// setupCarSearch поднимает полнотекстовый индекс каталога. Если SQLite собран без
+// FTS5 (нужен build tag sqlite_fts5), поиск работает через LIKE и индекс не создаётся.
+func setupCarSearch(db *gorm.DB) {
+	err := db.Transaction(func(tx *gorm.DB) error {
+		for _, stmt := range carSearchSchema {
+			if err := tx.Exec(stmt).Error; err != nil {
+				return err
+			}
+		}
+
+		// Переиндексируем каталог при старте, чтобы подхватить строки,
+		// появившиеся до создания триггеров.
+		if err := tx.Exec("DELETE FROM cars_fts").Error; err != nil {
+			return err
+		}
+		return tx.Exec(`INSERT INTO cars_fts(rowid, mark, model, category, metadata)
+			SELECT id, mark, model, category, COALESCE(metadata, '') FROM cars
+			WHERE deleted_at IS NULL`).Error
+	})
+
+	if err != nil {
+		log.Printf("Full-text search disabled, falling back to LIKE: %v", err)
+		dropCarSearchTriggers(db)
+		return
+	}
+	log.Println("Full-text search index ready")
+}
+
+// dropCarSearchTriggers убирает триггеры индекса, оставшиеся от сборки с FTS5:
+// без модуля fts5 они ломают любую запись в cars ("no such module: fts5").
+// Сама таблица cars_fts без модуля не удаляется, но и не мешает.
+func dropCarSearchTriggers(db *gorm.DB) {
+	for _, name := range carSearchTriggers {
+		if err := db.Exec("DROP TRIGGER IF EXISTS " + name).Error; err != nil {
+			log.Printf("Failed to drop search trigger %s: %v", name, err)
+		}
+	}
+}
+
+// HasCarSearchIndex сообщает, доступен ли FTS5-индекс каталога. Таблица cars_fts
+// может остаться в базе от сборки с FTS5, поэтому индекс читаем, а не ищем в sqlite_master.
+func HasCarSearchIndex(db *gorm.DB) bool {
+	var one int
+	return db.Raw("SELECT 1 FROM cars_fts LIMIT 1").Scan(&one).Error == nil
+}
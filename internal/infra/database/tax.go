@@ -0,0 +1,21 @@
+package database
+
+import (
+	"log"
+
+	"gorm.io/gorm"
+)
+
+// backfillTaxAmounts раскладывает цену аренд, созданных до учёта налогов:
+// налог по ним не начислялся, поэтому нетто равно брутто.
+func backfillTaxAmounts(db *gorm.DB) {
+	stmts := []string{
+		"UPDATE rentals SET net_price = total_price WHERE net_price IS NULL",
+		"UPDATE rentals SET tax_amount = 0 WHERE tax_amount IS NULL",
+	}
+	for _, stmt := range stmts {
+		if err := db.Exec(stmt).Error; err != nil {
+			log.Fatalf("Failed to backfill tax amounts: %v", err)
+		}
+	}
+}
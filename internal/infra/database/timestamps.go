@@ -0,0 +1,123 @@
+package database
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// timestampsInUTCVersion — значение PRAGMA user_version, после которого все
+// даты в базе уже хранятся в UTC.
+const timestampsInUTCVersion = 1
+
+// storedTimestampFormat — формат, в котором драйвер sqlite пишет time.Time.
+const storedTimestampFormat = "2006-01-02 15:04:05.999999999-07:00"
+
+// offsetTimestampFormats — форматы со смещением, которые понимает драйвер.
+// Даты без смещения драйвер и так читает как UTC, их не трогаем.
+var offsetTimestampFormats = []string{
+	storedTimestampFormat,
+	"2006-01-02T15:04:05.999999999-07:00",
+}
+
+// calendarDateColumns — колонки с календарной датой, а не моментом времени:
+// перевод в UTC сдвинул бы дату на день.
+var calendarDateColumns = map[string]bool{
+	"users.birth_date": true,
+}
+
+// normalizeTimestampsToUTC один раз переписывает в UTC даты, сохранённые до
+// перехода NowFunc на UTC: SQLite сравнивает даты как текст, и строка
+// "2026-03-01 12:00:00+06:00" оказалась бы позже "2026-03-01 07:00:00+00:00",
+// хотя это более ранний момент. Обходит все колонки типа datetime, переводит
+// значения со смещением в UTC и ставит user_version, чтобы не сканировать
+// таблицы при каждом запуске.
+func normalizeTimestampsToUTC(db *gorm.DB) {
+	var version int
+	if err := db.Raw("PRAGMA user_version").Scan(&version).Error; err != nil {
+		log.Fatalf("Failed to read schema version: %v", err)
+	}
+	if version >= timestampsInUTCVersion {
+		return
+	}
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		var columns []struct{ Table, Column string }
+		if err := tx.Raw(`SELECT m.name AS "table", p.name AS "column"
+			FROM sqlite_master m, pragma_table_info(m.name) p
+			WHERE m.type = 'table' AND lower(p.type) = 'datetime'`).Scan(&columns).Error; err != nil {
+			return err
+		}
+		for _, c := range columns {
+			if calendarDateColumns[c.Table+"."+c.Column] {
+				continue
+			}
+			n, err := normalizeTimestampColumn(tx, c.Table, c.Column)
+			if err != nil {
+				return fmt.Errorf("%s.%s: %w", c.Table, c.Column, err)
+			}
+			if n > 0 {
+				log.Printf("Normalized %d values of %s.%s to UTC", n, c.Table, c.Column)
+			}
+		}
+		return tx.Exec(fmt.Sprintf("PRAGMA user_version = %d", timestampsInUTCVersion)).Error
+	})
+	if err != nil {
+		log.Fatalf("Failed to normalize timestamps to UTC: %v", err)
+	}
+}
+
+// normalizeTimestampColumn переводит в UTC значения одной колонки и
+// возвращает число изменённых строк.
+func normalizeTimestampColumn(tx *gorm.DB, table, column string) (int, error) {
+	// CAST отдаёт строку как есть: иначе драйвер сам разобрал бы её в time.Time.
+	rows, err := tx.Raw(fmt.Sprintf(
+		`SELECT rowid, CAST(%[1]s AS TEXT) FROM %[2]s WHERE %[1]s IS NOT NULL AND CAST(%[1]s AS TEXT) NOT LIKE '%%+00:00'`,
+		column, table)).Rows()
+	if err != nil {
+		return 0, err
+	}
+	type change struct {
+		rowid int64
+		value string
+	}
+	var changes []change
+	for rows.Next() {
+		var rowid int64
+		var value string
+		if err := rows.Scan(&rowid, &value); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		if t, ok := parseOffsetTimestamp(value); ok {
+			changes = append(changes, change{rowid, t.UTC().Format(storedTimestampFormat)})
+		}
+	}
+	if err := rows.Err(); err != nil {
+		rows.Close()
+		return 0, err
+	}
+	if err := rows.Close(); err != nil {
+		return 0, err
+	}
+
+	for _, c := range changes {
+		if err := tx.Exec(fmt.Sprintf("UPDATE %s SET %s = ? WHERE rowid = ?", table, column), c.value, c.rowid).Error; err != nil {
+			return 0, err
+		}
+	}
+	return len(changes), nil
+}
+
+func parseOffsetTimestamp(value string) (time.Time, bool) {
+	value = strings.TrimSpace(value)
+	for _, format := range offsetTimestampFormats {
+		if t, err := time.Parse(format, value); err == nil {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
@@ -43,6 +43,8 @@ func (h *Handler) register(w http.ResponseWriter, r *http.Request) {
 		switch {
 		case errors.Is(err, authuc.ErrEmailTaken):
 			writeError(w, http.StatusBadRequest, "email already taken")
+		case errors.Is(err, authuc.ErrInvalidBirthDate):
+			writeError(w, http.StatusBadRequest, err.Error())
 		case isValidationError(err):
 			writeError(w, http.StatusBadRequest, err.Error())
 		default:
@@ -1,12 +1,25 @@
 package server
 
 import (
+	"fmt"
 	"net/http"
 	"time"
 
 	"github.com/CMPNION/Car-Rental-API.git/internal/entity"
+	"github.com/CMPNION/Car-Rental-API.git/internal/usecase/rentalstate"
 )
 
+// revenueTypes are the transactions revenue is made of: payments, deposit
+// captures and charges taken from the balance, less refunds.
+var revenueTypes = []string{entity.TransactionTypePayment, entity.TransactionTypeDepositCapture,
+	entity.TransactionTypeCharge, entity.TransactionTypeRefund}
+
+// revenueAmount is the SQL of what a transaction of the table adds to revenue.
+func revenueAmount(table string) string {
+	return fmt.Sprintf("CASE WHEN %[1]s.type = '%[2]s' THEN -%[1]s.amount ELSE %[1]s.amount END",
+		table, entity.TransactionTypeRefund)
+}
+
 func (s *Server) adminMetricsHandler(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
 		RespondWithError(w, http.StatusMethodNotAllowed, "method not allowed")
@@ -18,10 +31,10 @@ func (s *Server) adminMetricsHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	var totalRevenue float64
+	var totalRevenue entity.Money
 	_ = s.db.Model(&entity.Transaction{}).
-		Where("type = ? AND status = ?", "payment", entity.TransactionStatusSuccess).
-		Select("COALESCE(SUM(amount), 0)").
+		Where("type IN ? AND status = ?", revenueTypes, entity.TransactionStatusSuccess).
+		Select("COALESCE(SUM(" + revenueAmount("transactions") + "), 0)").
 		Scan(&totalRevenue).Error
 
 	var totalUsers int64
@@ -30,30 +43,33 @@ func (s *Server) adminMetricsHandler(w http.ResponseWriter, r *http.Request) {
 	var totalRentals int64
 	_ = s.db.Model(&entity.Rental{}).Count(&totalRentals).Error
 
-	var activeRentals int64
-	_ = s.db.Model(&entity.Rental{}).
-		Where("status = ?", entity.RentalStatusActive).
-		Count(&activeRentals).Error
-
-	var pendingRentals int64
-	_ = s.db.Model(&entity.Rental{}).
-		Where("status = ?", entity.RentalStatusPending).
-		Count(&pendingRentals).Error
-
-	var completedRentals int64
-	_ = s.db.Model(&entity.Rental{}).
-		Where("status = ?", entity.RentalStatusCompleted).
-		Count(&completedRentals).Error
-
-	var cancelledRentals int64
+	var statusCounts []struct {
+		Status string
+		Count  int64
+	}
 	_ = s.db.Model(&entity.Rental{}).
-		Where("status = ?", entity.RentalStatusCancelled).
-		Count(&cancelledRentals).Error
+		Select("status, COUNT(*) as count").
+		Group("status").
+		Scan(&statusCounts).Error
+	rentalsByStatus := map[string]int64{}
+	for _, t := range rentalstate.Transitions {
+		rentalsByStatus[t.To] = 0
+		for _, from := range t.From {
+			rentalsByStatus[from] = 0
+		}
+	}
+	for _, c := range statusCounts {
+		rentalsByStatus[c.Status] = c.Count
+	}
 
 	var totalCars int64
 	_ = s.db.Model(&entity.Car{}).Count(&totalCars).Error
-	var bookedCars int64
-	_ = s.db.Model(&entity.Car{}).Where("status = ?", entity.CarStatusBooked).Count(&bookedCars).Error
+	// Cars on the road; booked but not yet picked up ones do not load the fleet.
+	var rentedCars int64
+	_ = s.db.Model(&entity.Rental{}).
+		Where("status IN ?", rentalstate.Underway).
+		Distinct("car_id").
+		Count(&rentedCars).Error
 
 	var averageCarRating float64
 	_ = s.db.Model(&entity.Car{}).
@@ -66,21 +82,21 @@ func (s *Server) adminMetricsHandler(w http.ResponseWriter, r *http.Request) {
 		Scan(&averageUserRating).Error
 
 	since30 := time.Now().UTC().AddDate(0, 0, -30)
-	var revenueLast30 float64
+	var revenueLast30 entity.Money
 	_ = s.db.Model(&entity.Transaction{}).
-		Where("type = ? AND status = ? AND created_at >= ?", "payment", entity.TransactionStatusSuccess, since30).
-		Select("COALESCE(SUM(amount), 0)").
+		Where("type IN ? AND status = ? AND created_at >= ?", revenueTypes, entity.TransactionStatusSuccess, since30).
+		Select("COALESCE(SUM(" + revenueAmount("transactions") + "), 0)").
 		Scan(&revenueLast30).Error
 
 	since7 := time.Now().UTC().AddDate(0, 0, -7)
 	type revenueByDay struct {
-		Day     string  `json:"day"`
-		Revenue float64 `json:"revenue"`
+		Day     string       `json:"day"`
+		Revenue entity.Money `json:"revenue"`
 	}
 	var revenueLast7 []revenueByDay
 	_ = s.db.Table("transactions").
-		Select("date(created_at) as day, COALESCE(SUM(amount), 0) as revenue").
-		Where("type = ? AND status = ? AND created_at >= ?", "payment", entity.TransactionStatusSuccess, since7).
+		Select("date(created_at) as day, COALESCE(SUM("+revenueAmount("transactions")+"), 0) as revenue").
+		Where("type IN ? AND status = ? AND created_at >= ?", revenueTypes, entity.TransactionStatusSuccess, since7).
 		Group("day").
 		Order("day asc").
 		Scan(&revenueLast7).Error
@@ -95,23 +111,23 @@ func (s *Server) adminMetricsHandler(w http.ResponseWriter, r *http.Request) {
 	_ = s.db.Table("rentals").
 		Select("cars.id as car_id, cars.mark as mark, cars.model as model, COUNT(rentals.id) as rentals").
 		Joins("JOIN cars ON cars.id = rentals.car_id").
-		Where("rentals.status != ?", entity.RentalStatusCancelled).
+		Where("rentals.status NOT IN ?", rentalstate.Released).
 		Group("cars.id, cars.mark, cars.model").
 		Order("rentals desc").
 		Limit(5).
 		Scan(&topCars).Error
 
 	type topUser struct {
-		UserID uint    `json:"user_id"`
-		Name   string  `json:"name"`
-		Email  string  `json:"email"`
-		Spend  float64 `json:"spend"`
+		UserID uint         `json:"user_id"`
+		Name   string       `json:"name"`
+		Email  string       `json:"email"`
+		Spend  entity.Money `json:"spend"`
 	}
 	var topUsers []topUser
 	_ = s.db.Table("transactions").
-		Select("users.id as user_id, users.first_name || ' ' || users.last_name as name, users.email as email, COALESCE(SUM(transactions.amount), 0) as spend").
+		Select("users.id as user_id, users.first_name || ' ' || users.last_name as name, users.email as email, COALESCE(SUM("+revenueAmount("transactions")+"), 0) as spend").
 		Joins("JOIN users ON users.id = transactions.user_id").
-		Where("transactions.type = ? AND transactions.status = ?", "payment", entity.TransactionStatusSuccess).
+		Where("transactions.type IN ? AND transactions.status = ?", revenueTypes, entity.TransactionStatusSuccess).
 		Group("users.id, users.first_name, users.last_name, users.email").
 		Order("spend desc").
 		Limit(5).
@@ -119,26 +135,22 @@ func (s *Server) adminMetricsHandler(w http.ResponseWriter, r *http.Request) {
 
 	fleetLoad := 0.0
 	if totalCars > 0 {
-		fleetLoad = (float64(bookedCars) / float64(totalCars)) * 100
+		fleetLoad = (float64(rentedCars) / float64(totalCars)) * 100
 	}
 
 	RespondWithJSON(w, http.StatusOK, map[string]any{
+		"currency":             entity.BaseCurrency,
 		"total_revenue":        totalRevenue,
 		"revenue_last_30_days": revenueLast30,
 		"revenue_last_7_days":  revenueLast7,
 		"total_users":          totalUsers,
 		"total_cars":           totalCars,
 		"total_rentals":        totalRentals,
-		"rentals_by_status": map[string]int64{
-			"pending":   pendingRentals,
-			"active":    activeRentals,
-			"completed": completedRentals,
-			"cancelled": cancelledRentals,
-		},
-		"fleet_load":          fleetLoad,
-		"average_car_rating":  averageCarRating,
-		"average_user_rating": averageUserRating,
-		"top_cars_by_rentals": topCars,
-		"top_users_by_spend":  topUsers,
+		"rentals_by_status":    rentalsByStatus,
+		"fleet_load":           fleetLoad,
+		"average_car_rating":   averageCarRating,
+		"average_user_rating":  averageUserRating,
+		"top_cars_by_rentals":  topCars,
+		"top_users_by_spend":   topUsers,
 	})
 }
@@ -0,0 +1,136 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/CMPNION/Car-Rental-API.git/internal/entity"
+	authhttp "github.com/CMPNION/Car-Rental-API.git/internal/interface/http/auth"
+	"github.com/CMPNION/Car-Rental-API.git/internal/usecase/rentalstate"
+	"gorm.io/gorm"
+)
+
+// withUser returns r as sent by an authenticated user.
+func withUser(r *http.Request, userID uint, role string) *http.Request {
+	ctx := context.WithValue(r.Context(), authhttp.ContextUserIDKey, userID)
+	ctx = context.WithValue(ctx, authhttp.ContextRoleKey, role)
+	return r.WithContext(ctx)
+}
+
+// metricsRevenue returns total_revenue of GET /api/v1/admin/metrics.
+func metricsRevenue(t *testing.T, s *Server) entity.Money {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	s.adminMetricsHandler(rec, withUser(httptest.NewRequest(http.MethodGet, "/api/v1/admin/metrics", nil), 1, entity.UserRoleAdmin))
+	var resp struct {
+		Data struct {
+			TotalRevenue entity.Money `json:"total_revenue"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+	return resp.Data.TotalRevenue
+}
+
+func TestAdminMetricsRevenueIsNet(t *testing.T) {
+	s := newTestServer(t)
+
+	user := entity.User{FirstName: "U", LastName: "S", Email: "u@example.com", PasswordHash: "x", Role: entity.UserRoleClient}
+	if err := s.db.Create(&user).Error; err != nil {
+		t.Fatal(err)
+	}
+	for _, tx := range []entity.Transaction{
+		{Type: entity.TransactionTypePayment, Amount: 10000, Status: entity.TransactionStatusSuccess},
+		{Type: entity.TransactionTypeRefund, Amount: 3000, Status: entity.TransactionStatusSuccess},
+		{Type: entity.TransactionTypeDepositCapture, Amount: 2000, Status: entity.TransactionStatusSuccess},
+		// Neither wallet movements nor failed payments are revenue.
+		{Type: entity.TransactionTypeDepositHold, Amount: 5000, Status: entity.TransactionStatusSuccess},
+		{Type: entity.TransactionTypeDepositRelease, Amount: 3000, Status: entity.TransactionStatusSuccess},
+		{Type: entity.TransactionTypeTopUp, Amount: 50000, Status: entity.TransactionStatusSuccess},
+		{Type: entity.TransactionTypePayment, Amount: 7000, Status: entity.TransactionStatusFailed},
+	} {
+		tx.UserID = user.ID
+		if err := s.db.Create(&tx).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	rec := httptest.NewRecorder()
+	s.adminMetricsHandler(rec, withUser(httptest.NewRequest(http.MethodGet, "/api/v1/admin/metrics", nil), 1, entity.UserRoleAdmin))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status %d: %s", rec.Code, rec.Body)
+	}
+	var resp struct {
+		Data struct {
+			TotalRevenue     entity.Money `json:"total_revenue"`
+			RevenueLast30    entity.Money `json:"revenue_last_30_days"`
+			RevenueLast7Days []struct {
+				Revenue entity.Money `json:"revenue"`
+			} `json:"revenue_last_7_days"`
+			TopUsers []struct {
+				UserID uint         `json:"user_id"`
+				Spend  entity.Money `json:"spend"`
+			} `json:"top_users_by_spend"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+
+	const want entity.Money = 9000
+	d := resp.Data
+	if d.TotalRevenue != want || d.RevenueLast30 != want {
+		t.Errorf("total_revenue = %s, revenue_last_30_days = %s, want %s", d.TotalRevenue, d.RevenueLast30, want)
+	}
+	if len(d.RevenueLast7Days) != 1 || d.RevenueLast7Days[0].Revenue != want {
+		t.Errorf("revenue_last_7_days = %+v, want one day of %s", d.RevenueLast7Days, want)
+	}
+	if len(d.TopUsers) != 1 || d.TopUsers[0].UserID != user.ID || d.TopUsers[0].Spend != want {
+		t.Errorf("top_users_by_spend = %+v, want user %d with %s", d.TopUsers, user.ID, want)
+	}
+}
+
+func TestAdminMetricsRevenueLessEarlyReturnRefund(t *testing.T) {
+	s := newTestServer(t)
+	now := time.Now().UTC()
+
+	set := entity.PricingRuleSet{Version: 100, EffectiveFrom: now.Add(-time.Hour),
+		Rules: entity.PricingRules{EarlyReturn: &entity.EarlyReturnPolicy{Refund: entity.EarlyReturnRefundFull}}}
+	user := entity.User{FirstName: "U", LastName: "S", Email: "u@example.com", PasswordHash: "x", Role: entity.UserRoleClient}
+	car := entity.Car{Mark: "Kia", CarModel: "Rio", Category: entity.CarCategoryEconomy,
+		Status: entity.CarStatusBooked, PricePerHour: 100}
+	for _, v := range []any{&set, &user, &car} {
+		if err := s.db.Create(v).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Ten hours booked and paid, just under two of them used so far.
+	pickedUp := now.Add(-2*time.Hour + time.Minute)
+	rental := entity.Rental{UserID: user.ID, CarID: car.ID, StartDate: pickedUp, EndDate: pickedUp.Add(10 * time.Hour),
+		PickedUpAt: &pickedUp, TotalPrice: 1000, Status: entity.RentalStatusActive, PricingVersion: set.Version}
+	if err := s.db.Create(&rental).Error; err != nil {
+		t.Fatal(err)
+	}
+	payment := rentalTransaction(rental, entity.TransactionTypePayment, rental.TotalPrice)
+	if err := s.db.Create(&payment).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		return s.rentals.Fire(tx, &rental, rentalstate.EventFinish, nil, "")
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rental.Refunded != 800 {
+		t.Fatalf("refunded %s, want 8.00", rental.Refunded)
+	}
+	if got := metricsRevenue(t, s); got != 200 {
+		t.Errorf("total_revenue = %s after the early-return refund, want 2.00", got)
+	}
+}
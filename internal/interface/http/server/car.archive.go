@@ -0,0 +1,170 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/CMPNION/Car-Rental-API.git/internal/entity"
+	"github.com/CMPNION/Car-Rental-API.git/internal/usecase/rentalstate"
+	"gorm.io/gorm"
+)
+
+var errCarHasRentals = errors.New("car has upcoming rentals")
+
+// deleteCar handles DELETE /api/v1/cars/{id}[?force=true]. A car with pending or
+// active rentals is only deleted with force, which cancels those rentals and
+// refunds the paid ones in the same transaction.
+func (s *Server) deleteCar(w http.ResponseWriter, r *http.Request, id uint) {
+	force := false
+	if v := r.URL.Query().Get("force"); v != "" {
+		parsed, err := strconv.ParseBool(v)
+		if err != nil {
+			RespondWithError(w, http.StatusBadRequest, "invalid force")
+			return
+		}
+		force = parsed
+	}
+
+	blocking := 0
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		var car entity.Car
+		if err := tx.First(&car, id).Error; err != nil {
+			return err
+		}
+
+		var rentals []entity.Rental
+		if err := tx.Where("car_id = ? AND status IN ?", id, rentalstate.Open).
+			Find(&rentals).Error; err != nil {
+			return err
+		}
+		blocking = len(rentals)
+		if blocking > 0 && !force {
+			return errCarHasRentals
+		}
+
+		for _, rental := range rentals {
+			if err := s.rentals.Fire(tx, &rental, rentalstate.EventVoid, requestActor(r), "car withdrawn from service"); err != nil {
+				return err
+			}
+		}
+
+		if car.Status == entity.CarStatusBooked {
+			updates := map[string]any{"status": entity.CarStatusAvailable}
+			if err := tx.Model(&car).Updates(updates).Error; err != nil {
+				return err
+			}
+			if err := recordCarChanges(tx, r, car.ID, map[string]any{"status": entity.CarStatusBooked}, updates); err != nil {
+				return err
+			}
+		}
+
+		if err := tx.Delete(&car).Error; err != nil {
+			return err
+		}
+		event := entity.CarEvent{CarID: id, Type: entity.CarEventDeleted}
+		if blocking > 0 {
+			event.Note = fmt.Sprintf("forced, %d rental(s) cancelled", blocking)
+		}
+		return recordCarEvent(tx, r, &event)
+	})
+
+	if err != nil {
+		switch {
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			RespondWithError(w, http.StatusNotFound, "car not found")
+		case errors.Is(err, errCarHasRentals):
+			RespondWithError(w, http.StatusConflict,
+				fmt.Sprintf("car has %d pending or active rental(s); use force=true to cancel and refund them", blocking))
+		default:
+			RespondWithError(w, http.StatusInternalServerError, "database error")
+		}
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// adminCarsHandler handles GET /api/v1/admin/cars/deleted, POST /api/v1/admin/cars/{id}/restore
+// and POST /api/v1/admin/cars/{id}/device
+func (s *Server) adminCarsHandler(w http.ResponseWriter, r *http.Request) {
+	trimmed := strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/v1/admin/cars/"), "/")
+
+	if trimmed == "deleted" {
+		if r.Method != http.MethodGet {
+			RespondWithError(w, http.StatusMethodNotAllowed, "method not allowed")
+			return
+		}
+		s.listDeletedCars(w, r)
+		return
+	}
+
+	parts := strings.Split(trimmed, "/")
+	if len(parts) != 2 {
+		RespondWithError(w, http.StatusNotFound, "not found")
+		return
+	}
+	id, err := strconv.Atoi(parts[0])
+	if err != nil || id <= 0 {
+		RespondWithError(w, http.StatusBadRequest, "invalid id")
+		return
+	}
+
+	switch parts[1] {
+	case "restore":
+		if r.Method != http.MethodPost {
+			RespondWithError(w, http.StatusMethodNotAllowed, "method not allowed")
+			return
+		}
+		s.restoreCar(w, r, uint(id))
+	case "device":
+		s.carDeviceHandler(w, r, uint(id))
+	default:
+		RespondWithError(w, http.StatusNotFound, "not found")
+	}
+}
+
+func (s *Server) listDeletedCars(w http.ResponseWriter, r *http.Request) {
+	page, err := parsePageRequest(r)
+	if err != nil {
+		RespondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	q := s.db.Unscoped().Model(&entity.Car{}).Where("deleted_at IS NOT NULL")
+	key := sortKey{Name: "deleted_at:desc", Column: "deleted_at", IDColumn: "id", Desc: true, IsTime: true}
+	result, err := paginate(q, page, key, func(car entity.Car) (any, uint) {
+		return car.DeletedAt.Time, car.ID
+	})
+	respondWithPage(w, result, err)
+}
+
+func (s *Server) restoreCar(w http.ResponseWriter, r *http.Request, id uint) {
+	var restored entity.Car
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		res := tx.Unscoped().Model(&entity.Car{}).
+			Where("id = ? AND deleted_at IS NOT NULL", id).
+			Update("deleted_at", nil)
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		if err := recordCarEvent(tx, r, &entity.CarEvent{CarID: id, Type: entity.CarEventRestored}); err != nil {
+			return err
+		}
+		return tx.First(&restored, id).Error
+	})
+
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			RespondWithError(w, http.StatusNotFound, "deleted car not found")
+			return
+		}
+		RespondWithError(w, http.StatusInternalServerError, "database error")
+		return
+	}
+	RespondWithJSON(w, http.StatusOK, restored)
+}
@@ -0,0 +1,174 @@
+package server
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/CMPNION/Car-Rental-API.git/internal/entity"
+)
+
+var errPreconditionFailed = errors.New("precondition failed")
+
+const (
+	catalogCacheTTL        = 30 * time.Second
+	catalogCacheMaxEntries = 256
+)
+
+// cachedResponse is a rendered JSON response together with its validators.
+type cachedResponse struct {
+	body         []byte
+	etag         string
+	lastModified time.Time
+	expiresAt    time.Time
+}
+
+// responseCache keeps rendered catalog list responses in memory, keyed by the
+// catalog version they were rendered at, see catalogVersion. Entries of older
+// versions are never read again; the TTL and the size cap bound the memory.
+type responseCache struct {
+	mu      sync.RWMutex
+	entries map[string]cachedResponse
+}
+
+func newResponseCache() *responseCache {
+	return &responseCache{entries: map[string]cachedResponse{}}
+}
+
+func (c *responseCache) get(key string) (cachedResponse, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	entry, ok := c.entries[key]
+	if !ok || time.Now().After(entry.expiresAt) {
+		return cachedResponse{}, false
+	}
+	return entry, true
+}
+
+func (c *responseCache) set(key string, entry cachedResponse) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if len(c.entries) >= catalogCacheMaxEntries {
+		c.entries = map[string]cachedResponse{}
+	}
+	entry.expiresAt = time.Now().Add(catalogCacheTTL)
+	c.entries[key] = entry
+}
+
+// catalogVersion identifies the committed state of the catalog: its latest
+// change and how many rows cars has, so that hard deletes count too. Keying
+// the cache by it means a read never sees a response rendered before a
+// write committed, however the write was made.
+func (s *Server) catalogVersion() (string, time.Time, error) {
+	lastModified, err := s.catalogLastModified()
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	var rows int64
+	if err := s.db.Unscoped().Model(&entity.Car{}).Count(&rows).Error; err != nil {
+		return "", time.Time{}, err
+	}
+	return fmt.Sprintf("%d.%d", lastModified.UnixNano(), rows), lastModified, nil
+}
+
+// catalogLastModified is the latest change to any car, including deletions.
+func (s *Server) catalogLastModified() (time.Time, error) {
+	var cars []entity.Car
+	if err := s.db.Unscoped().Select("updated_at", "deleted_at").
+		Order("updated_at desc").Limit(1).Find(&cars).Error; err != nil {
+		return time.Time{}, err
+	}
+	var lastDeleted []entity.Car
+	if err := s.db.Unscoped().Select("updated_at", "deleted_at").
+		Where("deleted_at IS NOT NULL").
+		Order("deleted_at desc").Limit(1).Find(&lastDeleted).Error; err != nil {
+		return time.Time{}, err
+	}
+
+	var last time.Time
+	if len(cars) > 0 {
+		last = cars[0].UpdatedAt
+	}
+	if len(lastDeleted) > 0 && lastDeleted[0].DeletedAt.Time.After(last) {
+		last = lastDeleted[0].DeletedAt.Time
+	}
+	return last, nil
+}
+
+// renderJSON renders the standard success envelope into a cacheable response.
+func renderJSON(data any, lastModified time.Time) (cachedResponse, error) {
+	body, err := json.Marshal(APIResponse{Status: "ok", Data: data})
+	if err != nil {
+		return cachedResponse{}, err
+	}
+	sum := sha1.Sum(body)
+	return cachedResponse{
+		body:         body,
+		etag:         `"` + hex.EncodeToString(sum[:]) + `"`,
+		lastModified: lastModified,
+	}, nil
+}
+
+// carETag is the entity tag of a single car, derived from its UpdatedAt.
+func carETag(car entity.Car) string {
+	return fmt.Sprintf(`"car-%d-%d"`, car.ID, car.UpdatedAt.UnixNano())
+}
+
+// writeConditional answers with 304 when the client's validators are still
+// fresh, otherwise writes the response with ETag and Last-Modified set.
+func writeConditional(w http.ResponseWriter, r *http.Request, resp cachedResponse) {
+	w.Header().Set("ETag", resp.etag)
+	if !resp.lastModified.IsZero() {
+		w.Header().Set("Last-Modified", resp.lastModified.UTC().Format(http.TimeFormat))
+	}
+	w.Header().Set("Cache-Control", "no-cache")
+
+	if notModified(r, resp.etag, resp.lastModified) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write(resp.body)
+	_, _ = w.Write([]byte("\n"))
+}
+
+// notModified evaluates If-None-Match, falling back to If-Modified-Since only
+// when no entity tag was sent (RFC 9110, section 13.2.2).
+func notModified(r *http.Request, etag string, lastModified time.Time) bool {
+	if inm := r.Header.Get("If-None-Match"); inm != "" {
+		return etagListMatches(inm, etag, true)
+	}
+	if ims := r.Header.Get("If-Modified-Since"); ims != "" && !lastModified.IsZero() {
+		t, err := http.ParseTime(ims)
+		return err == nil && !lastModified.Truncate(time.Second).After(t)
+	}
+	return false
+}
+
+// etagListMatches reports whether etag is in the comma separated header value.
+// Weak comparison (used by If-None-Match) ignores the W/ prefix.
+func etagListMatches(header, etag string, weak bool) bool {
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" {
+			return true
+		}
+		if weak {
+			candidate = strings.TrimPrefix(candidate, "W/")
+		} else if strings.HasPrefix(candidate, "W/") {
+			continue
+		}
+		if candidate == etag {
+			return true
+		}
+	}
+	return false
+}
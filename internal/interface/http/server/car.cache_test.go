@@ -0,0 +1,128 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/CMPNION/Car-Rental-API.git/internal/entity"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// asAdmin returns r signed with an admin token, for handlers behind adminOnly.
+func asAdmin(t *testing.T, s *Server, r *http.Request) *http.Request {
+	t.Helper()
+	if s.jwtSecret == "" {
+		s.jwtSecret = "test-secret"
+	}
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"user_id": 1, "role": entity.UserRoleAdmin,
+	}).SignedString([]byte(s.jwtSecret))
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.Header.Set("Authorization", "Bearer "+token)
+	return r
+}
+
+func TestUpdateCarRequiresIfMatch(t *testing.T) {
+	s := newTestServer(t)
+	car := entity.Car{Mark: "Kia", CarModel: "Rio", Category: entity.CarCategoryEconomy,
+		Status: entity.CarStatusAvailable, PricePerHour: 1000}
+	if err := s.db.Create(&car).Error; err != nil {
+		t.Fatal(err)
+	}
+	path := fmt.Sprintf("/api/v1/cars/%d", car.ID)
+
+	get := func() string {
+		rec := httptest.NewRecorder()
+		s.carByIDHandler(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		return rec.Header().Get("ETag")
+	}
+	put := func(ifMatch, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPut, path, strings.NewReader(body))
+		if ifMatch != "" {
+			req.Header.Set("If-Match", ifMatch)
+		}
+		rec := httptest.NewRecorder()
+		s.carByIDHandler(rec, asAdmin(t, s, req))
+		return rec
+	}
+
+	if rec := put("", `{"metadata":"blind"}`); rec.Code != http.StatusPreconditionRequired {
+		t.Errorf("without If-Match: status %d, want 428", rec.Code)
+	}
+
+	seen := get()
+	rec := put(seen, `{"metadata":"first"}`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("with the current ETag: status %d: %s", rec.Code, rec.Body)
+	}
+	if rec.Header().Get("ETag") == seen || rec.Header().Get("ETag") != get() {
+		t.Errorf("update returned ETag %s, want the new one %s", rec.Header().Get("ETag"), get())
+	}
+
+	// A second editor still holding the old ETag does not overwrite the first.
+	if rec := put(seen, `{"metadata":"second"}`); rec.Code != http.StatusPreconditionFailed {
+		t.Errorf("with a stale ETag: status %d, want 412", rec.Code)
+	}
+	if err := s.db.First(&car, car.ID).Error; err != nil {
+		t.Fatal(err)
+	}
+	if car.Metadata != "first" {
+		t.Errorf("metadata = %q, want %q", car.Metadata, "first")
+	}
+}
+
+func TestCatalogCacheSeesCommittedWrites(t *testing.T) {
+	s := newTestServer(t)
+	car := entity.Car{Mark: "Kia", CarModel: "Rio", Category: entity.CarCategoryEconomy,
+		Status: entity.CarStatusAvailable, PricePerHour: 1000}
+	if err := s.db.Create(&car).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	list := func() string {
+		rec := httptest.NewRecorder()
+		s.carsHandler(rec, httptest.NewRequest(http.MethodGet, "/api/v1/cars?status=available", nil))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("status %d: %s", rec.Code, rec.Body)
+		}
+		return rec.Body.String()
+	}
+	if body := list(); !strings.Contains(body, `"model":"Rio"`) {
+		t.Fatalf("car not listed: %s", body)
+	}
+
+	tests := []struct {
+		name  string
+		write func() error
+	}{
+		// A raw statement runs no GORM callbacks.
+		{"raw update", func() error {
+			return s.db.Exec("UPDATE cars SET status = ?, updated_at = CURRENT_TIMESTAMP WHERE id = ?",
+				entity.CarStatusMaintenance, car.ID).Error
+		}},
+		{"hard delete", func() error {
+			if err := s.db.Model(&car).Update("status", entity.CarStatusAvailable).Error; err != nil {
+				return err
+			}
+			if !strings.Contains(list(), `"model":"Rio"`) {
+				return fmt.Errorf("car not listed again")
+			}
+			return s.db.Unscoped().Delete(&car).Error
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.write(); err != nil {
+				t.Fatal(err)
+			}
+			if body := list(); strings.Contains(body, `"model":"Rio"`) {
+				t.Errorf("cached list still shows the car: %s", body)
+			}
+		})
+	}
+}
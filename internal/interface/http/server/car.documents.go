@@ -0,0 +1,239 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/CMPNION/Car-Rental-API.git/internal/entity"
+	"gorm.io/gorm"
+)
+
+var errCarDocumentsExpired = errors.New("car documents expired")
+
+// carDocumentRequest is the payload of POST/PUT /api/v1/cars/{id}/documents.
+type carDocumentRequest struct {
+	Type      *string    `json:"type"`
+	Number    *string    `json:"number"`
+	IssuedAt  *time.Time `json:"issued_at"`
+	ExpiresAt *time.Time `json:"expires_at"`
+	FileRef   *string    `json:"file_ref"`
+}
+
+// checkCarDocuments fails with errCarDocumentsExpired when any document of the
+// car expires before until, so the car cannot be booked past that date.
+func checkCarDocuments(db *gorm.DB, carID uint, until time.Time) error {
+	var expired int64
+	if err := db.Model(&entity.CarDocument{}).
+		Where("car_id = ? AND expires_at < ?", carID, until).
+		Count(&expired).Error; err != nil {
+		return err
+	}
+	if expired > 0 {
+		return errCarDocumentsExpired
+	}
+	return nil
+}
+
+// carDocumentsHandler handles /api/v1/cars/{id}/documents[/{docID}] (admin)
+func (s *Server) carDocumentsHandler(w http.ResponseWriter, r *http.Request) {
+	s.adminOnly(func(w http.ResponseWriter, r *http.Request) {
+		parts := strings.Split(strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/v1/cars/"), "/"), "/")
+		carID, err := strconv.Atoi(parts[0])
+		if err != nil || carID <= 0 {
+			RespondWithError(w, http.StatusBadRequest, "invalid id")
+			return
+		}
+
+		switch len(parts) {
+		case 2:
+			switch r.Method {
+			case http.MethodGet:
+				s.listCarDocuments(w, r, uint(carID))
+			case http.MethodPost:
+				s.createCarDocument(w, r, uint(carID))
+			default:
+				RespondWithError(w, http.StatusMethodNotAllowed, "method not allowed")
+			}
+		case 3:
+			docID, err := strconv.Atoi(parts[2])
+			if err != nil || docID <= 0 {
+				RespondWithError(w, http.StatusBadRequest, "invalid document id")
+				return
+			}
+			switch r.Method {
+			case http.MethodPut:
+				s.updateCarDocument(w, r, uint(carID), uint(docID))
+			case http.MethodDelete:
+				res := s.db.Where("car_id = ?", carID).Delete(&entity.CarDocument{}, docID)
+				if res.Error != nil {
+					RespondWithError(w, http.StatusInternalServerError, "database error")
+					return
+				}
+				if res.RowsAffected == 0 {
+					RespondWithError(w, http.StatusNotFound, "document not found")
+					return
+				}
+				w.WriteHeader(http.StatusNoContent)
+			default:
+				RespondWithError(w, http.StatusMethodNotAllowed, "method not allowed")
+			}
+		default:
+			RespondWithError(w, http.StatusNotFound, "not found")
+		}
+	})(w, r)
+}
+
+func (s *Server) listCarDocuments(w http.ResponseWriter, r *http.Request, carID uint) {
+	page, err := parsePageRequest(r)
+	if err != nil {
+		RespondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	q := s.db.Model(&entity.CarDocument{}).Where("car_id = ?", carID)
+	key := sortKey{Name: "expires_at", Column: "expires_at", IDColumn: "id", IsTime: true}
+	result, err := paginate(q, page, key, func(doc entity.CarDocument) (any, uint) {
+		return doc.ExpiresAt, doc.ID
+	})
+	respondWithPage(w, result, err)
+}
+
+func (s *Server) createCarDocument(w http.ResponseWriter, r *http.Request, carID uint) {
+	var req carDocumentRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		RespondWithError(w, http.StatusBadRequest, "invalid JSON")
+		return
+	}
+	if req.Type == nil || req.Number == nil || req.IssuedAt == nil || req.ExpiresAt == nil {
+		RespondWithError(w, http.StatusBadRequest, "type, number, issued_at and expires_at are required")
+		return
+	}
+
+	doc := entity.CarDocument{CarID: carID}
+	if msg := applyCarDocument(&doc, req); msg != "" {
+		RespondWithError(w, http.StatusBadRequest, msg)
+		return
+	}
+
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		var car entity.Car
+		if err := tx.First(&car, carID).Error; err != nil {
+			return err
+		}
+		return tx.Create(&doc).Error
+	})
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			RespondWithError(w, http.StatusNotFound, "car not found")
+			return
+		}
+		RespondWithError(w, http.StatusInternalServerError, "database error")
+		return
+	}
+	RespondWithJSON(w, http.StatusCreated, doc)
+}
+
+func (s *Server) updateCarDocument(w http.ResponseWriter, r *http.Request, carID, docID uint) {
+	var req carDocumentRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		RespondWithError(w, http.StatusBadRequest, "invalid JSON")
+		return
+	}
+
+	var doc entity.CarDocument
+	var msg string
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("car_id = ?", carID).First(&doc, docID).Error; err != nil {
+			return err
+		}
+		if msg = applyCarDocument(&doc, req); msg != "" {
+			return nil
+		}
+		return tx.Save(&doc).Error
+	})
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			RespondWithError(w, http.StatusNotFound, "document not found")
+			return
+		}
+		RespondWithError(w, http.StatusInternalServerError, "database error")
+		return
+	}
+	if msg != "" {
+		RespondWithError(w, http.StatusBadRequest, msg)
+		return
+	}
+	RespondWithJSON(w, http.StatusOK, doc)
+}
+
+// applyCarDocument copies the provided fields onto doc and validates the result.
+func applyCarDocument(doc *entity.CarDocument, req carDocumentRequest) string {
+	if req.Type != nil {
+		doc.Type = strings.ToLower(strings.TrimSpace(*req.Type))
+	}
+	if req.Number != nil {
+		doc.Number = strings.TrimSpace(*req.Number)
+	}
+	if req.IssuedAt != nil {
+		doc.IssuedAt = req.IssuedAt.UTC()
+	}
+	if req.ExpiresAt != nil {
+		doc.ExpiresAt = req.ExpiresAt.UTC()
+	}
+	if req.FileRef != nil {
+		doc.FileRef = strings.TrimSpace(*req.FileRef)
+	}
+
+	switch {
+	case doc.Type != entity.CarDocumentRegistration &&
+		doc.Type != entity.CarDocumentInspection &&
+		doc.Type != entity.CarDocumentInsurance:
+		return "invalid type"
+	case doc.Number == "":
+		return "number cannot be empty"
+	case !doc.ExpiresAt.After(doc.IssuedAt):
+		return "expires_at must be after issued_at"
+	}
+	return ""
+}
+
+// expiringDocumentsHandler handles GET /api/v1/admin/documents/expiring?days=N (admin).
+// Already expired documents are included first.
+func (s *Server) expiringDocumentsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		RespondWithError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	days := 30
+	if v := r.URL.Query().Get("days"); v != "" {
+		d, err := strconv.Atoi(v)
+		if err != nil || d < 0 || d > 3650 {
+			RespondWithError(w, http.StatusBadRequest, "invalid days")
+			return
+		}
+		days = d
+	}
+
+	page, err := parsePageRequest(r)
+	if err != nil {
+		RespondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	until := time.Now().UTC().AddDate(0, 0, days)
+	q := s.db.Model(&entity.CarDocument{}).Preload("Car").Where("expires_at <= ?", until)
+	if v := r.URL.Query().Get("type"); v != "" {
+		q = q.Where("type = ?", strings.ToLower(v))
+	}
+
+	key := sortKey{Name: "expires_at", Column: "expires_at", IDColumn: "id", IsTime: true}
+	result, err := paginate(q, page, key, func(doc entity.CarDocument) (any, uint) {
+		return doc.ExpiresAt, doc.ID
+	})
+	respondWithPage(w, result, err)
+}
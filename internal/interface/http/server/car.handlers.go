@@ -2,12 +2,15 @@ package server
 
 import (
 	"encoding/json"
+	"errors"
 	"net/http"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/CMPNION/Car-Rental-API.git/internal/entity"
 	authhttp "github.com/CMPNION/Car-Rental-API.git/internal/interface/http/auth"
+	"github.com/CMPNION/Car-Rental-API.git/internal/usecase/rentalstate"
 	"gorm.io/gorm"
 )
 
@@ -33,10 +36,27 @@ func (s *Server) adminOnly(next http.HandlerFunc) http.HandlerFunc {
 	}
 }
 
+// carListItem is a catalog row together with its search relevance, if any.
+type carListItem struct {
+	entity.Car
+	SearchRank float64 `json:"-" gorm:"column:search_rank"`
+}
+
 func (s *Server) carsHandler(w http.ResponseWriter, r *http.Request) {
 	switch r.Method {
 
 	case http.MethodGet:
+		version, lastModified, err := s.catalogVersion()
+		if err != nil {
+			RespondWithError(w, http.StatusInternalServerError, "database error")
+			return
+		}
+		cacheKey := version + "?" + r.URL.Query().Encode()
+		if cached, ok := s.catalogCache.get(cacheKey); ok {
+			writeConditional(w, r, cached)
+			return
+		}
+
 		q := s.db.Model(&entity.Car{})
 
 		if v := r.URL.Query().Get("mark"); v != "" {
@@ -49,7 +69,7 @@ func (s *Server) carsHandler(w http.ResponseWriter, r *http.Request) {
 			q = q.Where("status = ?", strings.ToLower(v))
 		}
 		if v := r.URL.Query().Get("min_price"); v != "" {
-			p, err := strconv.ParseFloat(v, 64)
+			p, err := entity.ParseMoney(v)
 			if err != nil {
 				RespondWithError(w, http.StatusBadRequest, "invalid min_price")
 				return
@@ -57,7 +77,7 @@ func (s *Server) carsHandler(w http.ResponseWriter, r *http.Request) {
 			q = q.Where("price_per_hour >= ?", p)
 		}
 		if v := r.URL.Query().Get("max_price"); v != "" {
-			p, err := strconv.ParseFloat(v, 64)
+			p, err := entity.ParseMoney(v)
 			if err != nil {
 				RespondWithError(w, http.StatusBadRequest, "invalid max_price")
 				return
@@ -65,44 +85,79 @@ func (s *Server) carsHandler(w http.ResponseWriter, r *http.Request) {
 			q = q.Where("price_per_hour <= ?", p)
 		}
 
-		sort := r.URL.Query().Get("sort")
-		order := strings.ToLower(r.URL.Query().Get("order"))
-		if order != "desc" {
-			order = "asc"
+		ranked := false
+		if v := strings.TrimSpace(r.URL.Query().Get("q")); v != "" {
+			q, ranked, err = s.applyCarSearch(q, v)
+			if err != nil {
+				RespondWithError(w, http.StatusInternalServerError, "search error")
+				return
+			}
+		}
+
+		page, err := parsePageRequest(r)
+		if err != nil {
+			RespondWithError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		sortField := "id"
+		if ranked {
+			sortField = "relevance"
+			q = q.Select("cars.*, search.search_rank AS search_rank")
+		} else {
+			q = q.Select("cars.*, 0 AS search_rank")
 		}
-		if sort != "" {
-			switch sort {
+		if v := r.URL.Query().Get("sort"); v != "" {
+			switch v {
 			case "price_per_hour", "rating", "created_at":
-				q = q.Order(sort + " " + order)
+				sortField = v
+			case "relevance":
+				if !ranked {
+					RespondWithError(w, http.StatusBadRequest, "sort=relevance requires q")
+					return
+				}
 			default:
 				RespondWithError(w, http.StatusBadRequest, "invalid sort field")
 				return
 			}
 		}
 
-		if v := r.URL.Query().Get("limit"); v != "" {
-			lim, err := strconv.Atoi(v)
-			if err != nil || lim <= 0 || lim > 200 {
-				RespondWithError(w, http.StatusBadRequest, "invalid limit")
-				return
-			}
-			q = q.Limit(lim)
+		key := sortKey{Column: "cars." + sortField, IDColumn: "cars.id", IsTime: sortField == "created_at"}
+		if sortField == "relevance" {
+			key.Column = "search.search_rank"
 		}
-		if v := r.URL.Query().Get("offset"); v != "" {
-			off, err := strconv.Atoi(v)
-			if err != nil || off < 0 {
-				RespondWithError(w, http.StatusBadRequest, "invalid offset")
-				return
+		key.Desc = strings.ToLower(r.URL.Query().Get("order")) == "desc"
+		key.Name = sortField
+		if key.Desc {
+			key.Name += ":desc"
+		}
+
+		result, err := paginate(q, page, key, func(car carListItem) (any, uint) {
+			switch sortField {
+			case "price_per_hour":
+				// The column holds minor units; Money would encode a decimal.
+				return int64(car.PricePerHour), car.ID
+			case "rating":
+				return car.Rating, car.ID
+			case "created_at":
+				return car.CreatedAt, car.ID
+			case "relevance":
+				return car.SearchRank, car.ID
 			}
-			q = q.Offset(off)
+			return car.ID, car.ID
+		})
+		if err != nil {
+			respondWithPage(w, result, err)
+			return
 		}
 
-		var cars []entity.Car
-		if err := q.Find(&cars).Error; err != nil {
-			RespondWithError(w, http.StatusInternalServerError, "database error")
+		resp, err := renderJSON(result, lastModified)
+		if err != nil {
+			RespondWithError(w, http.StatusInternalServerError, "could not render response")
 			return
 		}
-		RespondWithJSON(w, http.StatusOK, cars)
+		s.catalogCache.set(cacheKey, resp)
+		writeConditional(w, r, resp)
 
 	case http.MethodPost:
 		s.adminOnly(func(w http.ResponseWriter, r *http.Request) {
@@ -116,6 +171,7 @@ func (s *Server) carsHandler(w http.ResponseWriter, r *http.Request) {
 			payload.CarModel = strings.TrimSpace(payload.CarModel)
 			payload.Category = strings.ToLower(strings.TrimSpace(payload.Category))
 			payload.Status = strings.ToLower(strings.TrimSpace(payload.Status))
+			payload.Jurisdiction = normalizeJurisdiction(payload.Jurisdiction)
 
 			if payload.Mark == "" || payload.CarModel == "" {
 				RespondWithError(w, http.StatusBadRequest, "mark and model are required")
@@ -125,11 +181,8 @@ func (s *Server) carsHandler(w http.ResponseWriter, r *http.Request) {
 				RespondWithError(w, http.StatusBadRequest, "price_per_hour must be > 0")
 				return
 			}
-
-			if payload.Category != entity.CarCategoryEconomy &&
-				payload.Category != entity.CarCategoryBusiness &&
-				payload.Category != entity.CarCategoryLuxury {
-				RespondWithError(w, http.StatusBadRequest, "invalid category")
+			if !jurisdictionPattern.MatchString(payload.Jurisdiction) {
+				RespondWithError(w, http.StatusBadRequest, "jurisdiction must be an ISO 3166 code, e.g. KZ or KZ-ALA")
 				return
 			}
 
@@ -143,7 +196,24 @@ func (s *Server) carsHandler(w http.ResponseWriter, r *http.Request) {
 				return
 			}
 
-			if err := s.db.Create(&payload).Error; err != nil {
+			err := s.db.Transaction(func(tx *gorm.DB) error {
+				if _, err := findCarCategory(tx, payload.Category); err != nil {
+					return err
+				}
+				if err := tx.Create(&payload).Error; err != nil {
+					return err
+				}
+				return recordCarEvent(tx, r, &entity.CarEvent{
+					CarID:    payload.ID,
+					Type:     entity.CarEventCreated,
+					NewValue: payload.Status,
+				})
+			})
+			if err != nil {
+				if errors.Is(err, errUnknownCategory) {
+					RespondWithError(w, http.StatusBadRequest, "invalid category")
+					return
+				}
 				RespondWithError(w, http.StatusInternalServerError, "database error")
 				return
 			}
@@ -160,6 +230,18 @@ func (s *Server) carByIDHandler(w http.ResponseWriter, r *http.Request) {
 		s.carBookingsHandler(w, r)
 		return
 	}
+	if strings.HasSuffix(strings.TrimSuffix(r.URL.Path, "/"), "/history") {
+		s.carHistoryHandler(w, r)
+		return
+	}
+	if strings.Contains(r.URL.Path, "/documents") {
+		s.carDocumentsHandler(w, r)
+		return
+	}
+	if strings.Contains(r.URL.Path, "/telemetry") {
+		s.carTelemetryHandler(w, r)
+		return
+	}
 
 	idStr := strings.TrimPrefix(r.URL.Path, "/api/v1/cars/")
 	id, err := strconv.Atoi(idStr)
@@ -180,17 +262,25 @@ func (s *Server) carByIDHandler(w http.ResponseWriter, r *http.Request) {
 			RespondWithError(w, http.StatusInternalServerError, "database error")
 			return
 		}
-		RespondWithJSON(w, http.StatusOK, car)
+
+		resp, err := renderJSON(car, car.UpdatedAt)
+		if err != nil {
+			RespondWithError(w, http.StatusInternalServerError, "could not render response")
+			return
+		}
+		resp.etag = carETag(car)
+		writeConditional(w, r, resp)
 
 	case http.MethodPut:
 		s.adminOnly(func(w http.ResponseWriter, r *http.Request) {
 			var payload struct {
-				Mark         *string  `json:"mark"`
-				CarModel     *string  `json:"model"`
-				Category     *string  `json:"category"`
-				Status       *string  `json:"status"`
-				PricePerHour *float64 `json:"price_per_hour"`
-				Metadata     *string  `json:"metadata"`
+				Mark         *string       `json:"mark"`
+				CarModel     *string       `json:"model"`
+				Category     *string       `json:"category"`
+				Status       *string       `json:"status"`
+				PricePerHour *entity.Money `json:"price_per_hour"`
+				Metadata     *string       `json:"metadata"`
+				Jurisdiction *string       `json:"jurisdiction"`
 			}
 			if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
 				RespondWithError(w, http.StatusBadRequest, "invalid JSON")
@@ -216,12 +306,7 @@ func (s *Server) carByIDHandler(w http.ResponseWriter, r *http.Request) {
 				updates["model"] = v
 			}
 			if payload.Category != nil {
-				v := strings.ToLower(strings.TrimSpace(*payload.Category))
-				if v != entity.CarCategoryEconomy && v != entity.CarCategoryBusiness && v != entity.CarCategoryLuxury {
-					RespondWithError(w, http.StatusBadRequest, "invalid category")
-					return
-				}
-				updates["category"] = v
+				updates["category"] = strings.ToLower(strings.TrimSpace(*payload.Category))
 			}
 			if payload.Status != nil {
 				v := strings.ToLower(strings.TrimSpace(*payload.Status))
@@ -241,39 +326,77 @@ func (s *Server) carByIDHandler(w http.ResponseWriter, r *http.Request) {
 			if payload.Metadata != nil {
 				updates["metadata"] = *payload.Metadata
 			}
+			if payload.Jurisdiction != nil {
+				v := normalizeJurisdiction(*payload.Jurisdiction)
+				if !jurisdictionPattern.MatchString(v) {
+					RespondWithError(w, http.StatusBadRequest, "jurisdiction must be an ISO 3166 code, e.g. KZ or KZ-ALA")
+					return
+				}
+				updates["jurisdiction"] = v
+			}
 
 			if len(updates) == 0 {
 				RespondWithError(w, http.StatusBadRequest, "no fields to update")
 				return
 			}
 
-			res := s.db.Model(&entity.Car{}).Where("id = ?", id).Updates(updates)
-			if res.Error != nil {
-				RespondWithError(w, http.StatusInternalServerError, "database error")
+			// Without If-Match the update could silently overwrite an edit the
+			// client never saw.
+			ifMatch := r.Header.Get("If-Match")
+			if ifMatch == "" {
+				RespondWithError(w, http.StatusPreconditionRequired, "If-Match is required, send the ETag of GET /api/v1/cars/{id}")
 				return
 			}
-			if res.RowsAffected == 0 {
-				RespondWithError(w, http.StatusNotFound, "car not found")
+			var updated entity.Car
+			err := s.db.Transaction(func(tx *gorm.DB) error {
+				var current entity.Car
+				if err := tx.First(&current, id).Error; err != nil {
+					return err
+				}
+				if !etagListMatches(ifMatch, carETag(current), false) {
+					return errPreconditionFailed
+				}
+				if category, ok := updates["category"].(string); ok {
+					if _, err := findCarCategory(tx, category); err != nil {
+						return err
+					}
+				}
+				before := carSnapshot(current)
+				res := tx.Model(&current).Where("updated_at = ?", current.UpdatedAt).Updates(updates)
+				if res.Error != nil {
+					return res.Error
+				}
+				if res.RowsAffected == 0 {
+					return errPreconditionFailed
+				}
+				if err := recordCarChanges(tx, r, current.ID, before, updates); err != nil {
+					return err
+				}
+				return tx.First(&updated, id).Error
+			})
+			if err != nil {
+				if errors.Is(err, gorm.ErrRecordNotFound) {
+					RespondWithError(w, http.StatusNotFound, "car not found")
+					return
+				}
+				if errors.Is(err, errPreconditionFailed) {
+					RespondWithError(w, http.StatusPreconditionFailed, "car was modified by someone else, reload and retry")
+					return
+				}
+				if errors.Is(err, errUnknownCategory) {
+					RespondWithError(w, http.StatusBadRequest, "invalid category")
+					return
+				}
+				RespondWithError(w, http.StatusInternalServerError, "database error")
 				return
 			}
-
-			var updated entity.Car
-			_ = s.db.First(&updated, id).Error
+			w.Header().Set("ETag", carETag(updated))
 			RespondWithJSON(w, http.StatusOK, updated)
 		})(w, r)
 
 	case http.MethodDelete:
 		s.adminOnly(func(w http.ResponseWriter, r *http.Request) {
-			res := s.db.Delete(&entity.Car{}, id)
-			if res.Error != nil {
-				RespondWithError(w, http.StatusInternalServerError, "database error")
-				return
-			}
-			if res.RowsAffected == 0 {
-				RespondWithError(w, http.StatusNotFound, "car not found")
-				return
-			}
-			w.WriteHeader(http.StatusNoContent)
+			s.deleteCar(w, r, uint(id))
 		})(w, r)
 
 	default:
@@ -281,6 +404,14 @@ func (s *Server) carByIDHandler(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// carBooking is the public view of a rental interval on a car.
+type carBooking struct {
+	ID        uint      `json:"-"`
+	StartDate time.Time `json:"start_date"`
+	EndDate   time.Time `json:"end_date"`
+	Status    string    `json:"status"`
+}
+
 func (s *Server) carBookingsHandler(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
 		RespondWithError(w, http.StatusMethodNotAllowed, "method not allowed")
@@ -296,23 +427,18 @@ func (s *Server) carBookingsHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	var rentals []entity.Rental
-	if err := s.db.
-		Where("car_id = ? AND status != ?", id, entity.RentalStatusCancelled).
-		Order("start_date asc").
-		Find(&rentals).Error; err != nil {
-		RespondWithError(w, http.StatusInternalServerError, "database error")
+	page, err := parsePageRequest(r)
+	if err != nil {
+		RespondWithError(w, http.StatusBadRequest, err.Error())
 		return
 	}
 
-	bookings := make([]map[string]any, 0, len(rentals))
-	for _, rental := range rentals {
-		bookings = append(bookings, map[string]any{
-			"start_date": rental.StartDate,
-			"end_date":   rental.EndDate,
-			"status":     rental.Status,
-		})
-	}
-
-	RespondWithJSON(w, http.StatusOK, bookings)
+	q := s.db.Model(&entity.Rental{}).
+		Select("id", "start_date", "end_date", "status").
+		Where("car_id = ? AND status NOT IN ?", id, rentalstate.Released)
+	key := sortKey{Name: "start_date", Column: "start_date", IDColumn: "id", IsTime: true}
+	result, err := paginate(q, page, key, func(booking carBooking) (any, uint) {
+		return booking.StartDate, booking.ID
+	})
+	respondWithPage(w, result, err)
 }
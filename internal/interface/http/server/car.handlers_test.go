@@ -0,0 +1,89 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"testing"
+
+	"github.com/CMPNION/Car-Rental-API.git/internal/entity"
+	"github.com/CMPNION/Car-Rental-API.git/internal/infra/database"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// newTestServer returns a server on a fresh, migrated and seeded database.
+func newTestServer(t *testing.T) *Server {
+	t.Helper()
+	db := database.InitDB(filepath.Join(t.TempDir(), "test.db"))
+	return GetNewServer(":0", db.Session(&gorm.Session{Logger: logger.Discard}))
+}
+
+func TestListCarsPagesByPrice(t *testing.T) {
+	s := newTestServer(t)
+
+	// Equal prices make the id tie-breaker matter too.
+	prices := []entity.Money{2000, 1500, 2000, 999, 1500, 2000, 3050}
+	for _, price := range prices {
+		car := entity.Car{Mark: "Kia", CarModel: "Rio", Category: entity.CarCategoryEconomy,
+			Status: entity.CarStatusAvailable, PricePerHour: price}
+		if err := s.db.Create(&car).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var seen []entity.Car
+	cursor := ""
+	for pages := 1; ; pages++ {
+		if pages > len(prices) {
+			t.Fatalf("pagination does not end after %d pages", pages)
+		}
+		query := url.Values{"sort": {"price_per_hour"}, "limit": {"2"}}
+		if cursor != "" {
+			query.Set("cursor", cursor)
+		}
+		rec := httptest.NewRecorder()
+		s.carsHandler(rec, httptest.NewRequest(http.MethodGet, "/api/v1/cars?"+query.Encode(), nil))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("page %d: status %d: %s", pages, rec.Code, rec.Body)
+		}
+
+		var resp struct {
+			Data struct {
+				Items      []entity.Car `json:"items"`
+				NextCursor string       `json:"next_cursor"`
+				HasMore    bool         `json:"has_more"`
+			} `json:"data"`
+		}
+		if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+			t.Fatal(err)
+		}
+		seen = append(seen, resp.Data.Items...)
+		if !resp.Data.HasMore {
+			break
+		}
+		if resp.Data.NextCursor == cursor {
+			t.Fatalf("page %d repeats the cursor of the previous page", pages)
+		}
+		cursor = resp.Data.NextCursor
+	}
+
+	if len(seen) != len(prices) {
+		t.Fatalf("got %d cars over all pages, want %d", len(seen), len(prices))
+	}
+	ids := map[uint]bool{}
+	for i, car := range seen {
+		if ids[car.ID] {
+			t.Fatalf("car %d listed twice", car.ID)
+		}
+		ids[car.ID] = true
+		if i > 0 {
+			prev := seen[i-1]
+			if car.PricePerHour < prev.PricePerHour || (car.PricePerHour == prev.PricePerHour && car.ID < prev.ID) {
+				t.Fatalf("car %d (%s) listed after car %d (%s)", car.ID, car.PricePerHour, prev.ID, prev.PricePerHour)
+			}
+		}
+	}
+}
@@ -0,0 +1,210 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/CMPNION/Car-Rental-API.git/internal/entity"
+	authhttp "github.com/CMPNION/Car-Rental-API.git/internal/interface/http/auth"
+	"gorm.io/gorm"
+)
+
+var carEventTypes = map[string]bool{
+	entity.CarEventCreated:         true,
+	entity.CarEventUpdated:         true,
+	entity.CarEventStatusChanged:   true,
+	entity.CarEventPriceChanged:    true,
+	entity.CarEventMaintenance:     true,
+	entity.CarEventDamage:          true,
+	entity.CarEventDeleted:         true,
+	entity.CarEventRestored:        true,
+	entity.CarEventRentalCreated:   true,
+	entity.CarEventRentalPaid:      true,
+	entity.CarEventRentalPickedUp:  true,
+	entity.CarEventRentalCompleted: true,
+	entity.CarEventRentalCancelled: true,
+	entity.CarEventRentalModified:  true,
+}
+
+// recordCarEvent appends an entry to the car's history within the caller's transaction.
+func recordCarEvent(tx *gorm.DB, r *http.Request, event *entity.CarEvent) error {
+	if r != nil && event.ActorID == nil {
+		if userID, ok := authhttp.UserIDFromContext(r.Context()); ok {
+			event.ActorID = &userID
+		}
+	}
+	return tx.Create(event).Error
+}
+
+// recordRentalEvent records a rental lifecycle event on the rented car's history.
+func recordRentalEvent(tx *gorm.DB, r *http.Request, rental entity.Rental, eventType string) error {
+	rentalID := rental.ID
+	return recordCarEvent(tx, r, &entity.CarEvent{
+		CarID:    rental.CarID,
+		Type:     eventType,
+		RentalID: &rentalID,
+		Note:     rental.StartDate.Format(time.RFC3339) + " - " + rental.EndDate.Format(time.RFC3339),
+	})
+}
+
+// carSnapshot returns the editable car fields keyed by column name.
+func carSnapshot(car entity.Car) map[string]any {
+	return map[string]any{
+		"mark":           car.Mark,
+		"model":          car.CarModel,
+		"category":       car.Category,
+		"status":         car.Status,
+		"price_per_hour": car.PricePerHour,
+		"metadata":       car.Metadata,
+		"jurisdiction":   car.Jurisdiction,
+	}
+}
+
+// recordCarChanges turns a column update into history events: status and price
+// changes get their own entries, any other changed fields are listed in one "updated" entry.
+func recordCarChanges(tx *gorm.DB, r *http.Request, carID uint, before, updates map[string]any) error {
+	var changed []string
+	for column, value := range updates {
+		oldValue, newValue := fmt.Sprint(before[column]), fmt.Sprint(value)
+		if oldValue == newValue {
+			continue
+		}
+
+		switch column {
+		case "status":
+			if err := recordCarEvent(tx, r, &entity.CarEvent{
+				CarID:    carID,
+				Type:     entity.CarEventStatusChanged,
+				OldValue: oldValue,
+				NewValue: newValue,
+			}); err != nil {
+				return err
+			}
+		case "price_per_hour":
+			if err := recordCarEvent(tx, r, &entity.CarEvent{
+				CarID:    carID,
+				Type:     entity.CarEventPriceChanged,
+				OldValue: oldValue,
+				NewValue: newValue,
+			}); err != nil {
+				return err
+			}
+		default:
+			changed = append(changed, column)
+		}
+	}
+
+	if len(changed) == 0 {
+		return nil
+	}
+	sort.Strings(changed)
+	return recordCarEvent(tx, r, &entity.CarEvent{
+		CarID: carID,
+		Type:  entity.CarEventUpdated,
+		Note:  "changed: " + strings.Join(changed, ", "),
+	})
+}
+
+// carHistoryHandler handles GET/POST /api/v1/cars/{id}/history (admin)
+func (s *Server) carHistoryHandler(w http.ResponseWriter, r *http.Request) {
+	s.adminOnly(func(w http.ResponseWriter, r *http.Request) {
+		trimmed := strings.TrimPrefix(r.URL.Path, "/api/v1/cars/")
+		trimmed = strings.TrimSuffix(strings.Trim(trimmed, "/"), "/history")
+		id, err := strconv.Atoi(trimmed)
+		if err != nil || id <= 0 {
+			RespondWithError(w, http.StatusBadRequest, "invalid id")
+			return
+		}
+
+		var car entity.Car
+		if err := s.db.Unscoped().First(&car, id).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				RespondWithError(w, http.StatusNotFound, "car not found")
+				return
+			}
+			RespondWithError(w, http.StatusInternalServerError, "database error")
+			return
+		}
+
+		switch r.Method {
+		case http.MethodGet:
+			s.listCarHistory(w, r, car.ID)
+		case http.MethodPost:
+			s.addCarHistoryEntry(w, r, car.ID)
+		default:
+			RespondWithError(w, http.StatusMethodNotAllowed, "method not allowed")
+		}
+	})(w, r)
+}
+
+func (s *Server) listCarHistory(w http.ResponseWriter, r *http.Request, carID uint) {
+	q := s.db.Model(&entity.CarEvent{}).Where("car_id = ?", carID)
+
+	if v := r.URL.Query().Get("type"); v != "" {
+		types := strings.Split(strings.ToLower(v), ",")
+		for i, t := range types {
+			types[i] = strings.TrimSpace(t)
+			if !carEventTypes[types[i]] {
+				RespondWithError(w, http.StatusBadRequest, "invalid type")
+				return
+			}
+		}
+		q = q.Where("type IN ?", types)
+	}
+
+	page, err := parsePageRequest(r)
+	if err != nil {
+		RespondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	result, err := paginate(q, page, newestFirst, func(event entity.CarEvent) (any, uint) {
+		return event.ID, event.ID
+	})
+	respondWithPage(w, result, err)
+}
+
+type carHistoryEntryRequest struct {
+	Type     string `json:"type"`
+	Note     string `json:"note"`
+	RentalID *uint  `json:"rental_id"`
+}
+
+// addCarHistoryEntry lets staff log maintenance and damage, which have no
+// other write path in the API.
+func (s *Server) addCarHistoryEntry(w http.ResponseWriter, r *http.Request, carID uint) {
+	var req carHistoryEntryRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		RespondWithError(w, http.StatusBadRequest, "invalid JSON")
+		return
+	}
+
+	req.Type = strings.ToLower(strings.TrimSpace(req.Type))
+	if req.Type != entity.CarEventMaintenance && req.Type != entity.CarEventDamage {
+		RespondWithError(w, http.StatusBadRequest, fmt.Sprintf("type must be %s or %s", entity.CarEventMaintenance, entity.CarEventDamage))
+		return
+	}
+	req.Note = strings.TrimSpace(req.Note)
+	if req.Note == "" {
+		RespondWithError(w, http.StatusBadRequest, "note is required")
+		return
+	}
+
+	event := entity.CarEvent{
+		CarID:    carID,
+		Type:     req.Type,
+		RentalID: req.RentalID,
+		Note:     req.Note,
+	}
+	if err := recordCarEvent(s.db, r, &event); err != nil {
+		RespondWithError(w, http.StatusInternalServerError, "database error")
+		return
+	}
+	RespondWithJSON(w, http.StatusCreated, event)
+}
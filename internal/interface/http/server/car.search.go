@@ -0,0 +1,139 @@
+package server
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+
+	"gorm.io/gorm"
+)
+
+// carSearchMaxTerms caps how many words of a q= query are used for matching.
+const carSearchMaxTerms = 8
+
+// searchTerms splits a free-text query into lowercase words, dropping punctuation
+// so that the result is always safe to embed into an FTS5 MATCH expression.
+func searchTerms(query string) []string {
+	fields := strings.FieldsFunc(strings.ToLower(query), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	if len(fields) > carSearchMaxTerms {
+		fields = fields[:carSearchMaxTerms]
+	}
+	return fields
+}
+
+// applyCarSearch narrows the catalog query to cars matching the q= parameter.
+// With the FTS5 index every term is prefix-matched, misspelled terms are widened
+// with the closest indexed word, and the result exposes search.search_rank
+// (bm25, lower is better) for relevance ordering. Without the index it falls
+// back to substring matching and reports ranked=false.
+func (s *Server) applyCarSearch(q *gorm.DB, query string) (result *gorm.DB, ranked bool, err error) {
+	terms := searchTerms(query)
+	if len(terms) == 0 {
+		return q, false, nil
+	}
+
+	if !s.searchFTS {
+		for _, term := range terms {
+			q = q.Where("LOWER(mark || ' ' || model || ' ' || category || ' ' || COALESCE(metadata, '')) LIKE ?",
+				"%"+term+"%")
+		}
+		return q, false, nil
+	}
+
+	groups := make([]string, 0, len(terms))
+	for _, term := range terms {
+		alternatives := []string{fmt.Sprintf(`"%s"*`, term)}
+		corrected, err := s.correctSearchTerm(term)
+		if err != nil {
+			return nil, false, err
+		}
+		if corrected != "" && corrected != term {
+			alternatives = append(alternatives, fmt.Sprintf(`"%s"*`, corrected))
+		}
+		groups = append(groups, "("+strings.Join(alternatives, " OR ")+")")
+	}
+
+	q = q.Joins(`JOIN (
+		SELECT rowid AS car_id, bm25(cars_fts, 10.0, 10.0, 4.0, 1.0) AS search_rank
+		FROM cars_fts WHERE cars_fts MATCH ?
+	) AS search ON search.car_id = cars.id`, strings.Join(groups, " AND "))
+	return q, true, nil
+}
+
+// correctSearchTerm returns the indexed word closest to term when term itself
+// matches nothing, or "" when term is fine as-is or nothing is close enough.
+func (s *Server) correctSearchTerm(term string) (string, error) {
+	var hits int64
+	if err := s.db.Table("cars_fts_vocab").Where("term GLOB ?", term+"*").Count(&hits).Error; err != nil {
+		return "", err
+	}
+	if hits > 0 {
+		return "", nil
+	}
+
+	maxEdits := allowedTypos(term)
+	if maxEdits == 0 {
+		return "", nil
+	}
+
+	var vocab []string
+	if err := s.db.Table("cars_fts_vocab").
+		Where("length(term) >= ?", len([]rune(term))-maxEdits).
+		Pluck("term", &vocab).Error; err != nil {
+		return "", err
+	}
+
+	best, bestDist := "", maxEdits+1
+	for _, candidate := range vocab {
+		dist := levenshtein(term, candidate)
+		// Treat the query word as a possibly misspelled prefix of a longer word.
+		if cr := []rune(candidate); len(cr) > len([]rune(term)) {
+			if d := levenshtein(term, string(cr[:len([]rune(term))])); d < dist {
+				dist = d
+			}
+		}
+		if dist < bestDist || (dist == bestDist && candidate < best) {
+			best, bestDist = candidate, dist
+		}
+	}
+	if bestDist > maxEdits {
+		return "", nil
+	}
+	return best, nil
+}
+
+// allowedTypos scales the edit distance with word length so that short
+// words are not matched against unrelated ones.
+func allowedTypos(term string) int {
+	switch n := len([]rune(term)); {
+	case n < 3:
+		return 0
+	case n < 6:
+		return 1
+	default:
+		return 2
+	}
+}
+
+func levenshtein(a, b string) int {
+	ar, br := []rune(a), []rune(b)
+	prev := make([]int, len(br)+1)
+	curr := make([]int, len(br)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ar); i++ {
+		curr[0] = i
+		for j := 1; j <= len(br); j++ {
+			cost := 1
+			if ar[i-1] == br[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+	return prev[len(br)]
+}
@@ -0,0 +1,207 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"testing"
+
+	"github.com/CMPNION/Car-Rental-API.git/internal/entity"
+	"github.com/CMPNION/Car-Rental-API.git/internal/infra/database"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// newSearchCatalog creates the cars searched for below and returns them by name.
+func newSearchCatalog(t *testing.T, s *Server) map[string]entity.Car {
+	t.Helper()
+	cars := map[string]entity.Car{
+		"camry":   {Mark: "Toyota", CarModel: "Camry", Category: entity.CarCategoryBusiness, Metadata: "hybrid, leather seats"},
+		"corolla": {Mark: "Toyota", CarModel: "Corolla", Category: entity.CarCategoryEconomy},
+		"rio":     {Mark: "Kia", CarModel: "Rio", Category: entity.CarCategoryEconomy, Metadata: "bmw-style grille"},
+		"x5":      {Mark: "BMW", CarModel: "X5", Category: entity.CarCategoryLuxury},
+	}
+	// In a fixed order, so ids ascend by name.
+	for _, name := range []string{"camry", "corolla", "rio", "x5"} {
+		car := cars[name]
+		car.Status, car.PricePerHour = entity.CarStatusAvailable, 1000
+		if err := s.db.Create(&car).Error; err != nil {
+			t.Fatal(err)
+		}
+		cars[name] = car
+	}
+	return cars
+}
+
+// searchCars lists the catalog with the query and returns the status and the ids in order.
+func searchCars(t *testing.T, s *Server, query url.Values) (int, []uint) {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	s.carsHandler(rec, httptest.NewRequest(http.MethodGet, "/api/v1/cars?"+query.Encode(), nil))
+	if rec.Code != http.StatusOK {
+		return rec.Code, nil
+	}
+	var resp struct {
+		Data struct {
+			Items []entity.Car `json:"items"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+	ids := []uint{}
+	for _, car := range resp.Data.Items {
+		ids = append(ids, car.ID)
+	}
+	return rec.Code, ids
+}
+
+// requireSearchIndex skips the test unless SQLite was built with FTS5.
+func requireSearchIndex(t *testing.T, s *Server) {
+	t.Helper()
+	if !s.searchFTS {
+		t.Skip("full-text search needs -tags sqlite_fts5")
+	}
+}
+
+func TestCarSearchRanking(t *testing.T) {
+	s := newTestServer(t)
+	requireSearchIndex(t, s)
+	cars := newSearchCatalog(t, s)
+
+	// A match on the mark outranks one in the metadata.
+	_, ids := searchCars(t, s, url.Values{"q": {"bmw"}, "sort": {"relevance"}})
+	if len(ids) != 2 || ids[0] != cars["x5"].ID || ids[1] != cars["rio"].ID {
+		t.Errorf("q=bmw: got %v, want [%d %d]", ids, cars["x5"].ID, cars["rio"].ID)
+	}
+	// Terms are prefixes and all of them must match.
+	_, ids = searchCars(t, s, url.Values{"q": {"toy cor"}})
+	if len(ids) != 1 || ids[0] != cars["corolla"].ID {
+		t.Errorf("q=toy cor: got %v, want [%d]", ids, cars["corolla"].ID)
+	}
+}
+
+func TestCarSearchTypoFallback(t *testing.T) {
+	s := newTestServer(t)
+	requireSearchIndex(t, s)
+	cars := newSearchCatalog(t, s)
+
+	tests := []struct {
+		q    string
+		want []uint
+	}{
+		{"toyta", []uint{cars["camry"].ID, cars["corolla"].ID}},
+		{"camri", []uint{cars["camry"].ID}},
+		{"lether", []uint{cars["camry"].ID}},
+		// Too short to correct.
+		{"xz", []uint{}},
+		// Too far from anything indexed.
+		{"zzzzzz", []uint{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.q, func(t *testing.T) {
+			_, ids := searchCars(t, s, url.Values{"q": {tt.q}, "sort": {"price_per_hour"}})
+			if len(ids) != len(tt.want) {
+				t.Fatalf("got %v, want %v", ids, tt.want)
+			}
+			for i := range ids {
+				if ids[i] != tt.want[i] {
+					t.Fatalf("got %v, want %v", ids, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestCarSearchLike(t *testing.T) {
+	s := newTestServer(t)
+	s.searchFTS = false
+	cars := newSearchCatalog(t, s)
+
+	tests := []struct {
+		q    string
+		want []uint
+	}{
+		{"toyota", []uint{cars["camry"].ID, cars["corolla"].ID}},
+		{"Toyota CAMRY", []uint{cars["camry"].ID}},
+		{"leather", []uint{cars["camry"].ID}},
+		{"oroll", []uint{cars["corolla"].ID}},
+		{"bmw", []uint{cars["rio"].ID, cars["x5"].ID}},
+		// No typo fallback without the index.
+		{"toyta", []uint{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.q, func(t *testing.T) {
+			code, ids := searchCars(t, s, url.Values{"q": {tt.q}})
+			if code != http.StatusOK {
+				t.Fatalf("status %d", code)
+			}
+			if len(ids) != len(tt.want) {
+				t.Fatalf("got %v, want %v", ids, tt.want)
+			}
+			for i := range ids {
+				if ids[i] != tt.want[i] {
+					t.Fatalf("got %v, want %v", ids, tt.want)
+				}
+			}
+		})
+	}
+
+	if code, _ := searchCars(t, s, url.Values{"q": {"toyota"}, "sort": {"relevance"}}); code != http.StatusBadRequest {
+		t.Errorf("sort=relevance without the index: status %d, want 400", code)
+	}
+}
+
+// A database indexed by a build with FTS5 keeps cars_fts and its triggers;
+// opened by a build without it, search falls back to LIKE and cars can still
+// be written.
+func TestCarSearchStaleIndex(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.db")
+	db := database.InitDB(path).Session(&gorm.Session{Logger: logger.Discard})
+	if database.HasCarSearchIndex(db) {
+		t.Skip("needs a build without -tags sqlite_fts5")
+	}
+
+	// What a build with FTS5 leaves behind, see carSearchSchema.
+	err := db.Transaction(func(tx *gorm.DB) error {
+		for _, stmt := range []string{
+			`PRAGMA writable_schema = ON`,
+			`INSERT INTO sqlite_master (type, name, tbl_name, rootpage, sql) VALUES
+				('table', 'cars_fts', 'cars_fts', 0, 'CREATE VIRTUAL TABLE cars_fts USING fts5(mark, model, category, metadata)')`,
+			`PRAGMA writable_schema = OFF`,
+			`CREATE TRIGGER cars_fts_ai AFTER INSERT ON cars BEGIN
+				INSERT INTO cars_fts(rowid, mark, model, category, metadata) VALUES (new.id, new.mark, new.model, new.category, '');
+			END`,
+			`CREATE TRIGGER cars_fts_ad AFTER DELETE ON cars BEGIN
+				DELETE FROM cars_fts WHERE rowid = old.id;
+			END`,
+		} {
+			if err := tx.Exec(stmt).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	sqlDB.Close()
+
+	s := GetNewServer(":0", database.InitDB(path).Session(&gorm.Session{Logger: logger.Discard}))
+	if s.searchFTS {
+		t.Fatal("search uses an index SQLite cannot read")
+	}
+	cars := newSearchCatalog(t, s)
+	if err := s.db.Delete(&entity.Car{}, cars["rio"].ID).Error; err != nil {
+		t.Fatal(err)
+	}
+	if _, ids := searchCars(t, s, url.Values{"q": {"toyota"}}); len(ids) != 2 {
+		t.Errorf("q=toyota: got %v, want the two Toyotas", ids)
+	}
+}
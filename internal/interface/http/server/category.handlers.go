@@ -0,0 +1,259 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/CMPNION/Car-Rental-API.git/internal/entity"
+	"gorm.io/gorm"
+)
+
+var (
+	errUnknownCategory = errors.New("unknown category")
+	errCategoryInUse   = errors.New("category in use")
+	errDriverTooYoung  = errors.New("driver below minimum age")
+	errBirthDateNeeded = errors.New("birth date required")
+)
+
+var categoryCodePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]{0,31}$`)
+
+// carCategoryRequest is the payload of POST/PUT /api/v1/admin/categories.
+type carCategoryRequest struct {
+	Code           *string       `json:"code"`
+	DisplayName    *string       `json:"display_name"`
+	DefaultDeposit *entity.Money `json:"default_deposit"`
+	MinDriverAge   *int          `json:"min_driver_age"`
+	SortOrder      *int          `json:"sort_order"`
+	// CancellationPolicy replaces the tiers; an empty list restores the default.
+	CancellationPolicy *[]entity.CancellationTier `json:"cancellation_policy"`
+}
+
+// findCarCategory looks up a category by code, failing with errUnknownCategory.
+func findCarCategory(db *gorm.DB, code string) (entity.CarCategory, error) {
+	var category entity.CarCategory
+	if err := db.Where("code = ?", code).First(&category).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return category, errUnknownCategory
+		}
+		return category, err
+	}
+	return category, nil
+}
+
+// checkDriverAge fails when the user is younger than the category allows on
+// the pickup date, or when the category has an age limit and the user has not
+// told us their birth date.
+func checkDriverAge(category entity.CarCategory, user entity.User, pickup time.Time) error {
+	if category.MinDriverAge <= 0 {
+		return nil
+	}
+	if user.BirthDate == nil {
+		return errBirthDateNeeded
+	}
+	if user.BirthDate.AddDate(category.MinDriverAge, 0, 0).After(pickup) {
+		return errDriverTooYoung
+	}
+	return nil
+}
+
+// categoriesHandler handles GET /api/v1/categories
+func (s *Server) categoriesHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		RespondWithError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	var categories []entity.CarCategory
+	if err := s.db.Order("sort_order asc").Order("code asc").Find(&categories).Error; err != nil {
+		RespondWithError(w, http.StatusInternalServerError, "database error")
+		return
+	}
+	RespondWithJSON(w, http.StatusOK, categories)
+}
+
+// adminCategoriesHandler handles POST /api/v1/admin/categories and
+// PUT/DELETE /api/v1/admin/categories/{code} (admin)
+func (s *Server) adminCategoriesHandler(w http.ResponseWriter, r *http.Request) {
+	code := strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/v1/admin/categories"), "/")
+
+	if code == "" {
+		switch r.Method {
+		case http.MethodGet:
+			s.categoriesHandler(w, r)
+		case http.MethodPost:
+			s.createCategory(w, r)
+		default:
+			RespondWithError(w, http.StatusMethodNotAllowed, "method not allowed")
+		}
+		return
+	}
+
+	switch r.Method {
+	case http.MethodPut:
+		s.updateCategory(w, r, code)
+	case http.MethodDelete:
+		s.deleteCategory(w, code)
+	default:
+		RespondWithError(w, http.StatusMethodNotAllowed, "method not allowed")
+	}
+}
+
+func (s *Server) createCategory(w http.ResponseWriter, r *http.Request) {
+	var req carCategoryRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		RespondWithError(w, http.StatusBadRequest, "invalid JSON")
+		return
+	}
+	if req.Code == nil || req.DisplayName == nil {
+		RespondWithError(w, http.StatusBadRequest, "code and display_name are required")
+		return
+	}
+
+	category := entity.CarCategory{Code: strings.ToLower(strings.TrimSpace(*req.Code))}
+	if !categoryCodePattern.MatchString(category.Code) {
+		RespondWithError(w, http.StatusBadRequest, "code must be 1-32 characters of a-z, 0-9, '_' or '-'")
+		return
+	}
+	if msg := applyCarCategory(&category, req); msg != "" {
+		RespondWithError(w, http.StatusBadRequest, msg)
+		return
+	}
+
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		var existing int64
+		if err := tx.Unscoped().Model(&entity.CarCategory{}).
+			Where("code = ?", category.Code).Count(&existing).Error; err != nil {
+			return err
+		}
+		if existing > 0 {
+			return errCategoryInUse
+		}
+		return tx.Create(&category).Error
+	})
+	if err != nil {
+		if errors.Is(err, errCategoryInUse) {
+			RespondWithError(w, http.StatusConflict, "category already exists")
+			return
+		}
+		RespondWithError(w, http.StatusInternalServerError, "database error")
+		return
+	}
+	RespondWithJSON(w, http.StatusCreated, category)
+}
+
+// updateCategory changes everything but the code, which cars refer to.
+func (s *Server) updateCategory(w http.ResponseWriter, r *http.Request, code string) {
+	var req carCategoryRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		RespondWithError(w, http.StatusBadRequest, "invalid JSON")
+		return
+	}
+	if req.Code != nil && strings.ToLower(strings.TrimSpace(*req.Code)) != code {
+		RespondWithError(w, http.StatusBadRequest, "code cannot be changed")
+		return
+	}
+
+	var category entity.CarCategory
+	var msg string
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		var err error
+		if category, err = findCarCategory(tx, code); err != nil {
+			return err
+		}
+		if msg = applyCarCategory(&category, req); msg != "" {
+			return nil
+		}
+		return tx.Save(&category).Error
+	})
+	if err != nil {
+		if errors.Is(err, errUnknownCategory) {
+			RespondWithError(w, http.StatusNotFound, "category not found")
+			return
+		}
+		RespondWithError(w, http.StatusInternalServerError, "database error")
+		return
+	}
+	if msg != "" {
+		RespondWithError(w, http.StatusBadRequest, msg)
+		return
+	}
+	RespondWithJSON(w, http.StatusOK, category)
+}
+
+// deleteCategory removes a category no car refers to, archived cars included,
+// so restoring a car never brings back an unknown category.
+func (s *Server) deleteCategory(w http.ResponseWriter, code string) {
+	var cars int64
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		category, err := findCarCategory(tx, code)
+		if err != nil {
+			return err
+		}
+		if err := tx.Unscoped().Model(&entity.Car{}).
+			Where("category = ?", code).Count(&cars).Error; err != nil {
+			return err
+		}
+		if cars > 0 {
+			return errCategoryInUse
+		}
+		return tx.Unscoped().Delete(&category).Error
+	})
+	if err != nil {
+		switch {
+		case errors.Is(err, errUnknownCategory):
+			RespondWithError(w, http.StatusNotFound, "category not found")
+		case errors.Is(err, errCategoryInUse):
+			RespondWithError(w, http.StatusConflict, fmt.Sprintf("category is used by %d car(s)", cars))
+		default:
+			RespondWithError(w, http.StatusInternalServerError, "database error")
+		}
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// applyCarCategory copies the provided fields onto category and validates the result.
+func applyCarCategory(category *entity.CarCategory, req carCategoryRequest) string {
+	if req.DisplayName != nil {
+		category.DisplayName = strings.TrimSpace(*req.DisplayName)
+	}
+	if req.DefaultDeposit != nil {
+		category.DefaultDeposit = *req.DefaultDeposit
+	}
+	if req.MinDriverAge != nil {
+		category.MinDriverAge = *req.MinDriverAge
+	}
+	if req.SortOrder != nil {
+		category.SortOrder = *req.SortOrder
+	}
+	if req.CancellationPolicy != nil {
+		category.CancellationPolicy = *req.CancellationPolicy
+	}
+
+	switch {
+	case category.DisplayName == "":
+		return "display_name cannot be empty"
+	case category.DefaultDeposit < 0:
+		return "default_deposit must be >= 0"
+	case category.MinDriverAge < 0 || category.MinDriverAge > 99:
+		return "min_driver_age must be within 0..99"
+	}
+	seen := map[float64]bool{}
+	for i, tier := range category.CancellationPolicy {
+		switch {
+		case tier.MinHoursBefore < 0:
+			return fmt.Sprintf("cancellation_policy[%d].min_hours_before must be >= 0", i)
+		case tier.RefundPercent < 0 || tier.RefundPercent > 100:
+			return fmt.Sprintf("cancellation_policy[%d].refund_percent must be within 0..100", i)
+		case seen[tier.MinHoursBefore]:
+			return fmt.Sprintf("cancellation_policy[%d]: duplicate min_hours_before", i)
+		}
+		seen[tier.MinHoursBefore] = true
+	}
+	return ""
+}
@@ -0,0 +1,147 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/CMPNION/Car-Rental-API.git/internal/entity"
+	authhttp "github.com/CMPNION/Car-Rental-API.git/internal/interface/http/auth"
+)
+
+// exchangeRateRequest is the payload of POST /api/v1/admin/exchange-rates.
+type exchangeRateRequest struct {
+	Currency      string     `json:"currency"`
+	Rate          float64    `json:"rate"`
+	EffectiveFrom *time.Time `json:"effective_from"`
+}
+
+// exchangeRatesHandler handles GET /api/v1/exchange-rates: the base currency
+// and the rate in effect now for every currency.
+func (s *Server) exchangeRatesHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		RespondWithError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	var rates []entity.ExchangeRate
+	if err := s.db.Where("effective_from <= ?", time.Now().UTC()).
+		Order("effective_from desc").Order("id desc").
+		Find(&rates).Error; err != nil {
+		RespondWithError(w, http.StatusInternalServerError, "database error")
+		return
+	}
+	current := []entity.ExchangeRate{}
+	seen := map[string]bool{}
+	for _, rate := range rates {
+		if !seen[rate.Currency] {
+			seen[rate.Currency] = true
+			current = append(current, rate)
+		}
+	}
+
+	RespondWithJSON(w, http.StatusOK, map[string]any{
+		"base_currency": entity.BaseCurrency,
+		"rates":         current,
+	})
+}
+
+// adminExchangeRatesHandler handles /api/v1/admin/exchange-rates[/{id}] (admin).
+// Rates are never edited; a rate that is not in effect yet may be deleted.
+func (s *Server) adminExchangeRatesHandler(w http.ResponseWriter, r *http.Request) {
+	rest := strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/v1/admin/exchange-rates"), "/")
+
+	if rest == "" {
+		switch r.Method {
+		case http.MethodGet:
+			s.listExchangeRates(w, r)
+		case http.MethodPost:
+			s.createExchangeRate(w, r)
+		default:
+			RespondWithError(w, http.StatusMethodNotAllowed, "method not allowed")
+		}
+		return
+	}
+
+	id, err := strconv.Atoi(rest)
+	if err != nil || id <= 0 {
+		RespondWithError(w, http.StatusBadRequest, "invalid id")
+		return
+	}
+	if r.Method != http.MethodDelete {
+		RespondWithError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	res := s.db.Unscoped().Where("id = ? AND effective_from > ?", id, time.Now().UTC()).
+		Delete(&entity.ExchangeRate{})
+	if res.Error != nil {
+		RespondWithError(w, http.StatusInternalServerError, "database error")
+		return
+	}
+	if res.RowsAffected == 0 {
+		RespondWithError(w, http.StatusConflict, "only rates that are not in effect yet can be deleted")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) listExchangeRates(w http.ResponseWriter, r *http.Request) {
+	page, err := parsePageRequest(r)
+	if err != nil {
+		RespondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	q := s.db.Model(&entity.ExchangeRate{})
+	if v := r.URL.Query().Get("currency"); v != "" {
+		q = q.Where("currency = ?", normalizeCurrency(v))
+	}
+	result, err := paginate(q, page, newestFirst, func(rate entity.ExchangeRate) (any, uint) {
+		return rate.ID, rate.ID
+	})
+	respondWithPage(w, result, err)
+}
+
+func (s *Server) createExchangeRate(w http.ResponseWriter, r *http.Request) {
+	var req exchangeRateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		RespondWithError(w, http.StatusBadRequest, "invalid JSON")
+		return
+	}
+
+	now := time.Now().UTC()
+	rate := entity.ExchangeRate{
+		Currency:      normalizeCurrency(req.Currency),
+		Rate:          req.Rate,
+		EffectiveFrom: now,
+	}
+	switch {
+	case !currencyCodePattern.MatchString(rate.Currency):
+		RespondWithError(w, http.StatusBadRequest, "currency must be a 3-letter ISO 4217 code")
+		return
+	case rate.Currency == entity.BaseCurrency:
+		RespondWithError(w, http.StatusBadRequest, "the base currency has no exchange rate")
+		return
+	case rate.Rate <= 0:
+		RespondWithError(w, http.StatusBadRequest, "rate must be > 0")
+		return
+	}
+	if req.EffectiveFrom != nil {
+		if req.EffectiveFrom.Before(now.Add(-time.Minute)) {
+			RespondWithError(w, http.StatusBadRequest, "effective_from cannot be in the past")
+			return
+		}
+		rate.EffectiveFrom = req.EffectiveFrom.UTC()
+	}
+	if userID, ok := authhttp.UserIDFromContext(r.Context()); ok {
+		rate.CreatedBy = &userID
+	}
+
+	if err := s.db.Create(&rate).Error; err != nil {
+		RespondWithError(w, http.StatusInternalServerError, "database error")
+		return
+	}
+	RespondWithJSON(w, http.StatusCreated, rate)
+}
@@ -0,0 +1,101 @@
+package server
+
+import (
+	"errors"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/CMPNION/Car-Rental-API.git/internal/entity"
+	"gorm.io/gorm"
+)
+
+var errUnknownCurrency = errors.New("unknown currency")
+
+var currencyCodePattern = regexp.MustCompile(`^[A-Z]{3}$`)
+
+// normalizeCurrency upper-cases a currency code; an empty code is the base currency.
+func normalizeCurrency(code string) string {
+	code = strings.ToUpper(strings.TrimSpace(code))
+	if code == "" {
+		return entity.BaseCurrency
+	}
+	return code
+}
+
+// exchangeRate returns the price of one unit of currency in the base currency
+// at the given time. The base currency is always 1.
+func exchangeRate(db *gorm.DB, currency string, at time.Time) (float64, error) {
+	if currency == entity.BaseCurrency {
+		return 1, nil
+	}
+	var rate entity.ExchangeRate
+	err := db.Where("currency = ? AND effective_from <= ?", currency, at.UTC()).
+		Order("effective_from desc").Order("id desc").
+		First(&rate).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return 0, errUnknownCurrency
+	}
+	return rate.Rate, err
+}
+
+// fromBaseCurrency converts a base amount at rate, rounding like entity.Money.
+func fromBaseCurrency(amount entity.Money, rate float64) entity.Money {
+	return entity.RoundMoney(float64(amount) / rate)
+}
+
+// toBaseCurrency converts an amount in a currency with the given rate to the base currency.
+func toBaseCurrency(amount entity.Money, rate float64) entity.Money {
+	return amount.Mul(rate)
+}
+
+// userCurrency is the user's currency and its current rate. A user whose
+// currency has no rate yet is served in the base currency.
+func userCurrency(db *gorm.DB, user entity.User, at time.Time) (string, float64, error) {
+	currency := normalizeCurrency(user.Currency)
+	rate, err := exchangeRate(db, currency, at)
+	if errors.Is(err, errUnknownCurrency) {
+		return entity.BaseCurrency, 1, nil
+	}
+	return currency, rate, err
+}
+
+// applyCurrency shows the total of the breakdown in the renter's currency.
+func applyCurrency(db *gorm.DB, b *entity.PriceBreakdown, user entity.User, at time.Time) error {
+	currency, rate, err := userCurrency(db, user, at)
+	if err != nil {
+		return err
+	}
+	b.Currency = currency
+	b.ExchangeRate = rate
+	b.TotalInCurrency = fromBaseCurrency(b.Total, rate)
+	return nil
+}
+
+// rentalTransaction is a payment or refund transaction for a rental, recorded
+// in the currency and at the rate the rental was booked with.
+func rentalTransaction(rental entity.Rental, txType string, amount entity.Money) entity.Transaction {
+	rentalID := rental.ID
+	currency, rate := rental.Currency, rental.ExchangeRate
+	if currency == "" || rate <= 0 {
+		currency, rate = entity.BaseCurrency, 1
+	}
+	return entity.Transaction{
+		UserID:         rental.UserID,
+		RentalID:       &rentalID,
+		Type:           txType,
+		Amount:         amount,
+		Currency:       currency,
+		ExchangeRate:   rate,
+		CurrencyAmount: fromBaseCurrency(amount, rate),
+		Status:         entity.TransactionStatusSuccess,
+	}
+}
+
+// rentalExchangeRate is the rate the rental was booked at; 1 for rentals in the base currency.
+func rentalExchangeRate(rental entity.Rental) float64 {
+	if rental.Currency == "" || rental.ExchangeRate <= 0 {
+		return 1
+	}
+	return rental.ExchangeRate
+}
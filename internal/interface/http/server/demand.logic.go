@@ -0,0 +1,97 @@
+package server
+
+import (
+	"fmt"
+	"math"
+	"slices"
+	"time"
+
+	"github.com/CMPNION/Car-Rental-API.git/internal/entity"
+	"github.com/CMPNION/Car-Rental-API.git/internal/usecase/rentalstate"
+	"gorm.io/gorm"
+)
+
+// categoryDemand measures how busy a category is over [start, end): the
+// car-hours already booked there against the car-hours its fleet offers.
+// Cars in maintenance do not count as capacity. excludeRentalID keeps a rental
+// from counting against itself when it is priced again.
+func categoryDemand(db *gorm.DB, category string, start, end time.Time, excludeRentalID uint) (int, float64, error) {
+	var cars int64
+	if err := db.Model(&entity.Car{}).Where("category = ? AND status != ?", category, entity.CarStatusMaintenance).
+		Count(&cars).Error; err != nil {
+		return 0, 0, err
+	}
+
+	var rentals []entity.Rental
+	if err := db.Model(&entity.Rental{}).
+		Joins("JOIN cars ON cars.id = rentals.car_id AND cars.deleted_at IS NULL").
+		Where("cars.category = ? AND rentals.status NOT IN ? AND rentals.id != ? AND rentals.start_date < ? AND rentals.end_date > ?",
+			category, rentalstate.Released, excludeRentalID, end.UTC(), start.UTC()).
+		Select("rentals.id", "rentals.start_date", "rentals.end_date").
+		Find(&rentals).Error; err != nil {
+		return 0, 0, err
+	}
+
+	var booked float64
+	for _, rental := range rentals {
+		from, to := rental.StartDate, rental.EndDate
+		if from.Before(start) {
+			from = start
+		}
+		if to.After(end) {
+			to = end
+		}
+		booked += to.Sub(from).Hours()
+	}
+	return int(cars), booked, nil
+}
+
+// demandMultiplier is 1 + sensitivity × (utilization − target), clamped to
+// [floor, ceiling]. It returns the raw value as well for the explanation.
+func demandMultiplier(cfg entity.DynamicPricing, utilization float64) (float64, float64) {
+	raw := 1 + cfg.Sensitivity*(utilization-cfg.TargetUtilization)
+	return raw, math.Min(math.Max(raw, cfg.Floor), cfg.Ceiling)
+}
+
+// applyDemandPricing adds the dynamic pricing adjustment to the breakdown when
+// the rule set enables it for the car's category. The multiplier applies to
+// the rental subtotal; discounts and fees are not scaled.
+func applyDemandPricing(db *gorm.DB, b *entity.PriceBreakdown, rules entity.PricingRules, car entity.Car, start, end time.Time, excludeRentalID uint) error {
+	cfg := rules.Dynamic
+	if cfg == nil || !cfg.Enabled || (len(cfg.Categories) > 0 && !slices.Contains(cfg.Categories, car.Category)) {
+		return nil
+	}
+
+	cars, booked, err := categoryDemand(db, car.Category, start, end, excludeRentalID)
+	if err != nil {
+		return err
+	}
+	window := end.Sub(start).Hours()
+	var utilization float64
+	if cars > 0 && window > 0 {
+		utilization = math.Min(booked/(float64(cars)*window), 1)
+	}
+	raw, m := demandMultiplier(*cfg, utilization)
+
+	d := &entity.DemandPricing{
+		Category:          car.Category,
+		Cars:              cars,
+		WindowHours:       math.Round(window*100) / 100,
+		BookedHours:       math.Round(booked*100) / 100,
+		Utilization:       math.Round(utilization*10000) / 10000,
+		TargetUtilization: cfg.TargetUtilization,
+		Sensitivity:       cfg.Sensitivity,
+		RawMultiplier:     math.Round(raw*10000) / 10000,
+		Floor:             cfg.Floor,
+		Ceiling:           cfg.Ceiling,
+		Multiplier:        math.Round(m*10000) / 10000,
+		Amount:            rentalSubtotal(*b).Mul(m - 1),
+	}
+	d.Explanation = fmt.Sprintf("%.1f of %.1f car-hours booked in %s (%d cars × %.1f h) = %.1f%% utilization; "+
+		"1 + %g × (%.1f%% − %.1f%%) = %.4f, clamped to [%g, %g] = %.4f",
+		booked, float64(cars)*window, car.Category, cars, window, utilization*100,
+		cfg.Sensitivity, utilization*100, cfg.TargetUtilization*100, raw, cfg.Floor, cfg.Ceiling, m)
+	b.Demand = d
+	totalPriceBreakdown(b)
+	return nil
+}
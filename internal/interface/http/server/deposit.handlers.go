@@ -0,0 +1,117 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/CMPNION/Car-Rental-API.git/internal/entity"
+	authhttp "github.com/CMPNION/Car-Rental-API.git/internal/interface/http/auth"
+	"github.com/CMPNION/Car-Rental-API.git/internal/usecase/rentalstate"
+	"gorm.io/gorm"
+)
+
+// rentalChargeRequest is the payload of POST /api/v1/rentals/{id}/charges.
+type rentalChargeRequest struct {
+	Type   string       `json:"type"`
+	Amount entity.Money `json:"amount"`
+	Note   string       `json:"note"`
+}
+
+// rentalChargesHandler handles GET (owner or admin) and POST (admin)
+// /api/v1/rentals/{id}/charges: damage, late fees and fines captured from the
+// deposit held for a rental under way or in its inspection after the return.
+func (s *Server) rentalChargesHandler(w http.ResponseWriter, r *http.Request, rentalID uint) {
+	userID, ok := authhttp.UserIDFromContext(r.Context())
+	if !ok {
+		RespondWithError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+	isAdmin := getRoleFromContext(r) == entity.UserRoleAdmin
+
+	switch r.Method {
+	case http.MethodGet:
+		var rental entity.Rental
+		if err := s.db.First(&rental, rentalID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				RespondWithError(w, http.StatusNotFound, "rental not found")
+				return
+			}
+			RespondWithError(w, http.StatusInternalServerError, "database error")
+			return
+		}
+		if !isAdmin && rental.UserID != userID {
+			RespondWithError(w, http.StatusForbidden, "forbidden")
+			return
+		}
+		var charges []entity.RentalCharge
+		if err := s.db.Where("rental_id = ?", rentalID).Order("id asc").Find(&charges).Error; err != nil {
+			RespondWithError(w, http.StatusInternalServerError, "database error")
+			return
+		}
+		RespondWithJSON(w, http.StatusOK, map[string]any{
+			"deposit_held":       rental.DepositHeld,
+			"deposit_captured":   rental.DepositCaptured,
+			"deposit_release_at": s.depositReleaseAt(rental),
+			"charges":            charges,
+		})
+	case http.MethodPost:
+		if !isAdmin {
+			RespondWithError(w, http.StatusForbidden, "forbidden")
+			return
+		}
+		s.createRentalCharge(w, r, rentalID, userID)
+	default:
+		RespondWithError(w, http.StatusMethodNotAllowed, "method not allowed")
+	}
+}
+
+func (s *Server) createRentalCharge(w http.ResponseWriter, r *http.Request, rentalID, adminID uint) {
+	var req rentalChargeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		RespondWithError(w, http.StatusBadRequest, "invalid JSON")
+		return
+	}
+	charge := entity.RentalCharge{
+		Type:      strings.ToLower(strings.TrimSpace(req.Type)),
+		Amount:    req.Amount,
+		Note:      strings.TrimSpace(req.Note),
+		CreatedBy: &adminID,
+	}
+	switch charge.Type {
+	case entity.RentalChargeDamage, entity.RentalChargeLateFee, entity.RentalChargeFine:
+	default:
+		RespondWithError(w, http.StatusBadRequest, "type must be damage, late_fee or fine")
+		return
+	}
+	if charge.Amount <= 0 {
+		RespondWithError(w, http.StatusBadRequest, "amount must be > 0")
+		return
+	}
+
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		var rental entity.Rental
+		if err := tx.First(&rental, rentalID).Error; err != nil {
+			return err
+		}
+		if !slices.Contains(rentalstate.Underway, rental.Status) && !s.inInspection(rental, time.Now()) {
+			return errors.New("invalid status")
+		}
+		return raiseRentalCharge(tx, rental, &charge)
+	})
+	if err != nil {
+		switch {
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			RespondWithError(w, http.StatusNotFound, "rental not found")
+		case err.Error() == "invalid status":
+			RespondWithError(w, http.StatusBadRequest, "rental is neither under way nor in its inspection window")
+		default:
+			RespondWithError(w, http.StatusInternalServerError, "could not raise charge")
+		}
+		return
+	}
+	RespondWithJSON(w, http.StatusCreated, charge)
+}
@@ -0,0 +1,129 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/CMPNION/Car-Rental-API.git/internal/entity"
+)
+
+// raiseCharge posts a damage charge against the rental as an admin.
+func raiseCharge(t *testing.T, s *Server, rentalID uint, amount string) entity.RentalCharge {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, fmt.Sprintf("/api/v1/rentals/%d/charges", rentalID),
+		strings.NewReader(`{"type":"damage","amount":`+amount+`}`))
+	s.rentalChargesHandler(rec, withUser(req, 1, entity.UserRoleAdmin), rentalID)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("charge: status %d: %s", rec.Code, rec.Body)
+	}
+	var resp struct {
+		Data entity.RentalCharge `json:"data"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+	return resp.Data
+}
+
+func TestRentalChargeBeyondDeposit(t *testing.T) {
+	tests := []struct {
+		name            string
+		balance         entity.Money
+		wantFromBalance entity.Money
+		wantOutstanding entity.Money
+		wantBalance     entity.Money
+	}{
+		{"balance covers the rest", 1000, 350, 0, 650},
+		{"balance falls short", 100, 100, 250, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestServer(t)
+			birthDate := time.Date(1990, 5, 17, 0, 0, 0, 0, time.UTC)
+			user := entity.User{FirstName: "U", LastName: "S", Email: "u@example.com", PasswordHash: "x",
+				Role: entity.UserRoleClient, Balance: tt.balance, HeldBalance: 150, BirthDate: &birthDate}
+			car := entity.Car{Mark: "Kia", CarModel: "Rio", Category: entity.CarCategoryEconomy,
+				Status: entity.CarStatusBooked, PricePerHour: 1000}
+			for _, v := range []any{&user, &car} {
+				if err := s.db.Create(v).Error; err != nil {
+					t.Fatal(err)
+				}
+			}
+			start := time.Now().UTC().Add(-time.Hour)
+			rental := entity.Rental{UserID: user.ID, CarID: car.ID, StartDate: start, EndDate: start.Add(4 * time.Hour),
+				PickedUpAt: &start, TotalPrice: 4000, Status: entity.RentalStatusActive, DepositHeld: 150}
+			if err := s.db.Create(&rental).Error; err != nil {
+				t.Fatal(err)
+			}
+
+			charge := raiseCharge(t, s, rental.ID, "5")
+			if charge.Captured != 150 || charge.FromBalance != tt.wantFromBalance || charge.Outstanding != tt.wantOutstanding {
+				t.Errorf("captured %s, from balance %s, outstanding %s, want 1.50, %s and %s",
+					charge.Captured, charge.FromBalance, charge.Outstanding, tt.wantFromBalance, tt.wantOutstanding)
+			}
+			if err := s.db.First(&user, user.ID).Error; err != nil {
+				t.Fatal(err)
+			}
+			if user.Balance != tt.wantBalance || user.HeldBalance != 0 {
+				t.Errorf("balance %s, held %s, want %s and 0.00", user.Balance, user.HeldBalance, tt.wantBalance)
+			}
+			if got := metricsRevenue(t, s); got != 150+tt.wantFromBalance {
+				t.Errorf("total_revenue = %s, want what was collected, %s", got, 150+tt.wantFromBalance)
+			}
+
+			book := func() int {
+				start := time.Now().UTC().Add(48 * time.Hour)
+				body, _ := json.Marshal(RentalRequest{CarID: car.ID, StartDate: start, EndDate: start.Add(2 * time.Hour)})
+				if err := s.db.Model(&car).Update("status", entity.CarStatusAvailable).Error; err != nil {
+					t.Fatal(err)
+				}
+				rec := httptest.NewRecorder()
+				req := httptest.NewRequest(http.MethodPost, "/api/v1/rentals", strings.NewReader(string(body)))
+				s.createRental(rec, withUser(req, user.ID, entity.UserRoleClient))
+				return rec.Code
+			}
+			if tt.wantOutstanding == 0 {
+				if code := book(); code != http.StatusCreated {
+					t.Errorf("booking with nothing owed: status %d, want 201", code)
+				}
+				return
+			}
+
+			// The debt blocks bookings until a top-up pays it.
+			if code := book(); code != http.StatusPaymentRequired {
+				t.Errorf("booking while owing: status %d, want 402", code)
+			}
+			rec := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodPatch, "/api/v1/users/balance", strings.NewReader(`{"amount":3}`))
+			s.userBalanceHandler(rec, withUser(req, user.ID, entity.UserRoleClient))
+			var resp struct {
+				Data struct {
+					Balance            entity.Money `json:"balance"`
+					OutstandingCharges entity.Money `json:"outstanding_charges"`
+				} `json:"data"`
+			}
+			if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+				t.Fatal(err)
+			}
+			if resp.Data.Balance != 50 || resp.Data.OutstandingCharges != 0 {
+				t.Errorf("after top-up: balance %s, outstanding %s, want 0.50 and 0.00",
+					resp.Data.Balance, resp.Data.OutstandingCharges)
+			}
+			if err := s.db.First(&charge, charge.ID).Error; err != nil {
+				t.Fatal(err)
+			}
+			if charge.FromBalance != 350 || charge.Outstanding != 0 {
+				t.Errorf("charge from balance %s, outstanding %s, want 3.50 and 0.00", charge.FromBalance, charge.Outstanding)
+			}
+			if code := book(); code != http.StatusCreated {
+				t.Errorf("booking once paid: status %d, want 201", code)
+			}
+		})
+	}
+}
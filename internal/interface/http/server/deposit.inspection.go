@@ -0,0 +1,91 @@
+package server
+
+import (
+	"log"
+	"time"
+
+	"github.com/CMPNION/Car-Rental-API.git/internal/entity"
+	"gorm.io/gorm"
+)
+
+const (
+	// Default of DEPOSIT_INSPECTION_WINDOW.
+	defaultDepositInspectionWindow = 24 * time.Hour
+	depositReleaseTick             = time.Minute
+)
+
+// loadDepositInspectionWindow reads DEPOSIT_INSPECTION_WINDOW (a Go duration
+// such as "48h"): how long after the return the deposit stays held so that
+// damage found at inspection can still be charged against it.
+func loadDepositInspectionWindow() time.Duration {
+	return envDuration("DEPOSIT_INSPECTION_WINDOW", defaultDepositInspectionWindow)
+}
+
+// depositReleaseAt is when the deposit of a returned rental is released; nil
+// while the car is not back.
+func (s *Server) depositReleaseAt(rental entity.Rental) *time.Time {
+	if rental.ReturnedAt == nil {
+		return nil
+	}
+	at := rental.ReturnedAt.Add(s.depositInspection).UTC()
+	return &at
+}
+
+// inInspection reports whether charges can still be raised against the
+// deposit of a completed rental.
+func (s *Server) inInspection(rental entity.Rental, now time.Time) bool {
+	releaseAt := s.depositReleaseAt(rental)
+	return rental.Status == entity.RentalStatusCompleted && rental.DepositHeld > 0 &&
+		releaseAt != nil && now.Before(*releaseAt)
+}
+
+// runDepositRelease periodically releases the deposits of completed rentals
+// whose inspection window has passed.
+func (s *Server) runDepositRelease() {
+	ticker := time.NewTicker(depositReleaseTick)
+	defer ticker.Stop()
+
+	for {
+		if n, err := s.releaseInspectedDeposits(time.Now()); err != nil {
+			log.Printf("deposit release failed: %v", err)
+		} else if n > 0 {
+			log.Printf("released %d deposit(s)", n)
+		}
+		<-ticker.C
+	}
+}
+
+// releaseInspectedDeposits releases what is still held for every completed
+// rental returned more than the inspection window ago, each in its own
+// transaction.
+func (s *Server) releaseInspectedDeposits(now time.Time) (int, error) {
+	var ids []uint
+	if err := s.db.Model(&entity.Rental{}).
+		Where("status = ? AND deposit_held > 0 AND returned_at <= ?", entity.RentalStatusCompleted,
+			now.Add(-s.depositInspection).UTC()).
+		Order("id asc").
+		Pluck("id", &ids).Error; err != nil {
+		return 0, err
+	}
+
+	released := 0
+	for _, id := range ids {
+		var amount entity.Money
+		err := s.db.Transaction(func(tx *gorm.DB) error {
+			var rental entity.Rental
+			if err := tx.First(&rental, id).Error; err != nil {
+				return err
+			}
+			var err error
+			amount, err = releaseDeposit(tx, rental)
+			return err
+		})
+		if err != nil {
+			return released, err
+		}
+		if amount > 0 {
+			released++
+		}
+	}
+	return released, nil
+}
@@ -0,0 +1,68 @@
+package server
+
+import (
+	"testing"
+	"time"
+
+	"github.com/CMPNION/Car-Rental-API.git/internal/entity"
+)
+
+func TestReleaseInspectedDeposits(t *testing.T) {
+	s := newTestServer(t)
+	now := time.Now().UTC()
+
+	user := entity.User{FirstName: "U", LastName: "S", Email: "u@example.com", PasswordHash: "x",
+		Role: entity.UserRoleClient, Balance: 1000, HeldBalance: 300}
+	if err := s.db.Create(&user).Error; err != nil {
+		t.Fatal(err)
+	}
+	car := entity.Car{Mark: "Kia", CarModel: "Rio", Category: entity.CarCategoryEconomy,
+		Status: entity.CarStatusAvailable, PricePerHour: 1000}
+	if err := s.db.Create(&car).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	rental := func(returnedAgo time.Duration) entity.Rental {
+		returnedAt := now.Add(-returnedAgo)
+		r := entity.Rental{UserID: user.ID, CarID: car.ID, StartDate: returnedAt.Add(-4 * time.Hour),
+			EndDate: returnedAt, TotalPrice: 4000, Status: entity.RentalStatusCompleted,
+			ReturnedAt: &returnedAt, DepositHeld: 150}
+		if err := s.db.Create(&r).Error; err != nil {
+			t.Fatal(err)
+		}
+		return r
+	}
+	inspected := rental(s.depositInspection + time.Minute)
+	inspecting := rental(time.Hour)
+
+	if s.inInspection(inspected, now) {
+		t.Error("rental returned before the window is still in inspection")
+	}
+	if !s.inInspection(inspecting, now) {
+		t.Error("rental returned an hour ago is not in inspection")
+	}
+
+	n, err := s.releaseInspectedDeposits(now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 1 {
+		t.Fatalf("released %d deposits, want 1", n)
+	}
+
+	if err := s.db.First(&inspected, inspected.ID).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := s.db.First(&inspecting, inspecting.ID).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := s.db.First(&user, user.ID).Error; err != nil {
+		t.Fatal(err)
+	}
+	if inspected.DepositHeld != 0 || inspecting.DepositHeld != 150 {
+		t.Errorf("deposit_held = %s and %s, want 0.00 and 1.50", inspected.DepositHeld, inspecting.DepositHeld)
+	}
+	if user.Balance != 1150 || user.HeldBalance != 150 {
+		t.Errorf("balance = %s, held = %s, want 11.50 and 1.50", user.Balance, user.HeldBalance)
+	}
+}
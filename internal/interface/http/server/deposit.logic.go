@@ -0,0 +1,196 @@
+package server
+
+import (
+	"errors"
+
+	"github.com/CMPNION/Car-Rental-API.git/internal/entity"
+	"gorm.io/gorm"
+)
+
+var (
+	errInsufficientBalance = errors.New("insufficient balance")
+	errOutstandingCharges  = errors.New("outstanding charges")
+)
+
+// rentalDeposit is the deposit quoted when the rental was booked; rentals
+// without a breakdown use the current default of the car's category.
+func rentalDeposit(tx *gorm.DB, rental entity.Rental) (entity.Money, error) {
+	if rental.PriceBreakdown != nil {
+		return rental.PriceBreakdown.Deposit, nil
+	}
+	var car entity.Car
+	if err := tx.Unscoped().First(&car, rental.CarID).Error; err != nil {
+		return 0, err
+	}
+	category, err := findCarCategory(tx, car.Category)
+	if errors.Is(err, errUnknownCategory) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	return category.DefaultDeposit, nil
+}
+
+// holdDeposit moves the rental's deposit from the renter's balance to the
+// held balance, where it can no longer be spent.
+func holdDeposit(tx *gorm.DB, rental entity.Rental) (entity.Money, error) {
+	amount, err := rentalDeposit(tx, rental)
+	if err != nil || amount <= 0 {
+		return 0, err
+	}
+
+	res := tx.Model(&entity.User{}).
+		Where("id = ? AND balance >= ?", rental.UserID, amount).
+		Updates(map[string]any{
+			"balance":      gorm.Expr("balance - ?", amount),
+			"held_balance": gorm.Expr("held_balance + ?", amount),
+		})
+	if res.Error != nil {
+		return 0, res.Error
+	}
+	if res.RowsAffected == 0 {
+		return 0, errInsufficientBalance
+	}
+	if err := tx.Model(&entity.Rental{}).Where("id = ?", rental.ID).
+		Update("deposit_held", amount).Error; err != nil {
+		return 0, err
+	}
+
+	transaction := rentalTransaction(rental, entity.TransactionTypeDepositHold, amount)
+	return amount, tx.Create(&transaction).Error
+}
+
+// releaseDeposit returns what is still held for the rental to the renter's balance.
+func releaseDeposit(tx *gorm.DB, rental entity.Rental) (entity.Money, error) {
+	if err := tx.Select("deposit_held").First(&rental, rental.ID).Error; err != nil {
+		return 0, err
+	}
+	amount := rental.DepositHeld
+	if amount <= 0 {
+		return 0, nil
+	}
+
+	if err := tx.Model(&entity.Rental{}).Where("id = ?", rental.ID).
+		Update("deposit_held", 0).Error; err != nil {
+		return 0, err
+	}
+	if err := tx.Model(&entity.User{}).Where("id = ?", rental.UserID).
+		Updates(map[string]any{
+			"balance":      gorm.Expr("balance + ?", amount),
+			"held_balance": gorm.Expr("held_balance - ?", amount),
+		}).Error; err != nil {
+		return 0, err
+	}
+
+	transaction := rentalTransaction(rental, entity.TransactionTypeDepositRelease, amount)
+	return amount, tx.Create(&transaction).Error
+}
+
+// captureDeposit takes up to amount from what is held for the rental and
+// returns how much it took; the held money leaves the wallet for good.
+func captureDeposit(tx *gorm.DB, rental entity.Rental, amount entity.Money) (entity.Money, error) {
+	if err := tx.Select("deposit_held").First(&rental, rental.ID).Error; err != nil {
+		return 0, err
+	}
+	captured := min(amount, rental.DepositHeld)
+	if captured <= 0 {
+		return 0, nil
+	}
+
+	if err := tx.Model(&entity.Rental{}).Where("id = ?", rental.ID).
+		Updates(map[string]any{
+			"deposit_held":     gorm.Expr("deposit_held - ?", captured),
+			"deposit_captured": gorm.Expr("deposit_captured + ?", captured),
+		}).Error; err != nil {
+		return 0, err
+	}
+	if err := tx.Model(&entity.User{}).Where("id = ?", rental.UserID).
+		Update("held_balance", gorm.Expr("held_balance - ?", captured)).Error; err != nil {
+		return 0, err
+	}
+
+	transaction := rentalTransaction(rental, entity.TransactionTypeDepositCapture, captured)
+	return captured, tx.Create(&transaction).Error
+}
+
+// raiseRentalCharge records a charge against the rental: it is captured from
+// the held deposit as far as the deposit goes, the rest is taken from the
+// balance and what the balance does not cover stays outstanding.
+func raiseRentalCharge(tx *gorm.DB, rental entity.Rental, charge *entity.RentalCharge) error {
+	captured, err := captureDeposit(tx, rental, charge.Amount)
+	if err != nil {
+		return err
+	}
+	debited, err := debitBalance(tx, rental, charge.Amount-captured)
+	if err != nil {
+		return err
+	}
+	charge.RentalID = rental.ID
+	charge.UserID = rental.UserID
+	charge.Captured = captured
+	charge.FromBalance = debited
+	charge.Outstanding = charge.Amount - captured - debited
+	return tx.Create(charge).Error
+}
+
+// debitBalance takes up to amount from the renter's balance for a charge of
+// the rental and returns how much it took; the balance never goes negative.
+func debitBalance(tx *gorm.DB, rental entity.Rental, amount entity.Money) (entity.Money, error) {
+	if amount <= 0 {
+		return 0, nil
+	}
+	var user entity.User
+	if err := tx.Select("balance").First(&user, rental.UserID).Error; err != nil {
+		return 0, err
+	}
+	debited := min(amount, user.Balance)
+	if debited <= 0 {
+		return 0, nil
+	}
+
+	if err := tx.Model(&entity.User{}).Where("id = ?", rental.UserID).
+		Update("balance", gorm.Expr("balance - ?", debited)).Error; err != nil {
+		return 0, err
+	}
+	transaction := rentalTransaction(rental, entity.TransactionTypeCharge, debited)
+	return debited, tx.Create(&transaction).Error
+}
+
+// collectOutstandingCharges takes what the user owes on charges from the
+// balance, oldest charges first, as far as the balance goes.
+func collectOutstandingCharges(tx *gorm.DB, userID uint) error {
+	var charges []entity.RentalCharge
+	if err := tx.Where("user_id = ? AND outstanding > 0", userID).Order("id asc").Find(&charges).Error; err != nil {
+		return err
+	}
+	for _, charge := range charges {
+		var rental entity.Rental
+		if err := tx.First(&rental, charge.RentalID).Error; err != nil {
+			return err
+		}
+		debited, err := debitBalance(tx, rental, charge.Outstanding)
+		if err != nil {
+			return err
+		}
+		if debited == 0 {
+			return nil
+		}
+		if err := tx.Model(&entity.RentalCharge{}).Where("id = ?", charge.ID).
+			Updates(map[string]any{
+				"from_balance": gorm.Expr("from_balance + ?", debited),
+				"outstanding":  gorm.Expr("outstanding - ?", debited),
+			}).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// outstandingCharges is what the user still owes on charges.
+func outstandingCharges(tx *gorm.DB, userID uint) (entity.Money, error) {
+	var owed entity.Money
+	err := tx.Model(&entity.RentalCharge{}).Where("user_id = ? AND outstanding > 0", userID).
+		Select("COALESCE(SUM(outstanding), 0)").Scan(&owed).Error
+	return owed, err
+}
@@ -0,0 +1,279 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/CMPNION/Car-Rental-API.git/internal/entity"
+	authhttp "github.com/CMPNION/Car-Rental-API.git/internal/interface/http/auth"
+	"github.com/CMPNION/Car-Rental-API.git/internal/usecase/rentalstate"
+	"gorm.io/gorm"
+)
+
+var (
+	errInvoiceNotPaid       = errors.New("rental not paid")
+	errCompanyNotConfigured = errors.New("company requisites not configured")
+)
+
+// invoiceRequest is the optional payload of POST /api/v1/rentals/{id}/invoice:
+// the buyer's company details for a business invoice.
+type invoiceRequest struct {
+	Company string `json:"company"`
+	TaxID   string `json:"tax_id"`
+	Address string `json:"address"`
+}
+
+// issueInvoice issues the VAT invoice of a paid rental. A rental has one
+// invoice: asking again returns the invoice already issued.
+func (s *Server) issueInvoice(w http.ResponseWriter, r *http.Request, rentalID uint) {
+	userID, ok := authhttp.UserIDFromContext(r.Context())
+	if !ok {
+		RespondWithError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	var req invoiceRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		RespondWithError(w, http.StatusBadRequest, "invalid JSON")
+		return
+	}
+
+	var invoice entity.Invoice
+	created := false
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		var rental entity.Rental
+		if err := tx.Preload("User").Preload("Car").First(&rental, rentalID).Error; err != nil {
+			return err
+		}
+		if getRoleFromContext(r) != entity.UserRoleAdmin && rental.UserID != userID {
+			return errors.New("forbidden")
+		}
+
+		err := tx.Where("rental_id = ?", rental.ID).First(&invoice).Error
+		if err == nil {
+			return nil
+		}
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+		if !slices.Contains(rentalstate.Paid, rental.Status) {
+			return errInvoiceNotPaid
+		}
+
+		extensions, err := invoicedExtensions(tx, rental.ID)
+		if err != nil {
+			return err
+		}
+
+		company, err := companyProfile(tx)
+		if err != nil {
+			return err
+		}
+		if company.Name == "" || company.TaxID == "" {
+			return errCompanyNotConfigured
+		}
+
+		invoice = entity.Invoice{
+			RentalID: rental.ID,
+			UserID:   rental.UserID,
+			IssuedAt: time.Now().UTC(),
+			Seller:   company.CompanyRequisites,
+			Buyer: entity.InvoiceBuyer{
+				Company: strings.TrimSpace(req.Company),
+				TaxID:   strings.TrimSpace(req.TaxID),
+				Address: strings.TrimSpace(req.Address),
+			},
+			Lines:    invoiceLines(rental, extensions),
+			Net:      rental.NetPrice,
+			Tax:      rental.TaxAmount,
+			Gross:    rental.TotalPrice,
+			Currency: entity.BaseCurrency,
+		}
+		if rental.User != nil {
+			invoice.Buyer.Name = strings.TrimSpace(rental.User.FirstName + " " + rental.User.LastName)
+			invoice.Buyer.Email = rental.User.Email
+		}
+
+		// Numbers are taken inside the transaction, so they run without gaps;
+		// the unique (year, sequence) index rejects a concurrent duplicate.
+		invoice.Year = invoice.IssuedAt.Year()
+		var last int
+		if err := tx.Unscoped().Model(&entity.Invoice{}).Where("year = ?", invoice.Year).
+			Select("COALESCE(MAX(sequence), 0)").Scan(&last).Error; err != nil {
+			return err
+		}
+		invoice.Sequence = last + 1
+		invoice.Number = fmt.Sprintf("INV-%d-%06d", invoice.Year, invoice.Sequence)
+		created = true
+		return tx.Create(&invoice).Error
+	})
+	if err != nil {
+		switch {
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			RespondWithError(w, http.StatusNotFound, "rental not found")
+		case err.Error() == "forbidden":
+			RespondWithError(w, http.StatusForbidden, "forbidden")
+		case errors.Is(err, errInvoiceNotPaid):
+			RespondWithError(w, http.StatusConflict, "only paid rentals can be invoiced")
+		case errors.Is(err, errCompanyNotConfigured):
+			RespondWithError(w, http.StatusConflict, "company requisites are not configured")
+		default:
+			RespondWithError(w, http.StatusInternalServerError, "database error")
+		}
+		return
+	}
+
+	status := http.StatusOK
+	if created {
+		status = http.StatusCreated
+	}
+	RespondWithJSON(w, status, invoice)
+}
+
+// invoicedExtensions are the extensions not yet covered by the rental's own
+// price: a modification reprices the rental as a whole, including earlier extensions.
+func invoicedExtensions(tx *gorm.DB, rentalID uint) ([]entity.RentalExtension, error) {
+	q := tx.Where("rental_id = ?", rentalID)
+	var last entity.RentalModification
+	err := tx.Where("rental_id = ?", rentalID).Order("id desc").First(&last).Error
+	if err == nil {
+		q = q.Where("created_at > ?", last.CreatedAt)
+	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+	var extensions []entity.RentalExtension
+	return extensions, q.Order("id asc").Find(&extensions).Error
+}
+
+// invoiceLines turns the tax split of the rental and of its extensions into
+// invoice lines. Rentals booked before taxes were split get a single untaxed line.
+func invoiceLines(rental entity.Rental, extensions []entity.RentalExtension) []entity.TaxSplit {
+	description := "Rental"
+	if rental.Car != nil {
+		description = fmt.Sprintf("Rental of %s %s", rental.Car.Mark, rental.Car.CarModel)
+	}
+	// Extensions get lines of their own, so the rental line ends where it was booked to.
+	end := rental.EndDate
+	if len(extensions) > 0 && rental.PriceBreakdown != nil && len(rental.PriceBreakdown.TaxSplits) > 0 {
+		end = extensions[0].PreviousEnd
+	}
+	description += fmt.Sprintf(", %s - %s",
+		rental.StartDate.UTC().Format("2006-01-02 15:04"), end.UTC().Format("2006-01-02 15:04"))
+
+	if rental.PriceBreakdown == nil || len(rental.PriceBreakdown.TaxSplits) == 0 {
+		return []entity.TaxSplit{{
+			Code:  "rental",
+			Name:  description,
+			Net:   rental.TotalPrice,
+			Gross: rental.TotalPrice,
+		}}
+	}
+	lines := make([]entity.TaxSplit, len(rental.PriceBreakdown.TaxSplits))
+	copy(lines, rental.PriceBreakdown.TaxSplits)
+	for i := range lines {
+		if lines[i].Code == "rental" {
+			lines[i].Name = description
+		}
+	}
+	for _, extension := range extensions {
+		if extension.PriceBreakdown == nil {
+			continue
+		}
+		for _, split := range extension.PriceBreakdown.TaxSplits {
+			if split.Code == "rental" {
+				split.Code = "extension"
+				split.Name = fmt.Sprintf("Extension, %s - %s",
+					extension.PreviousEnd.UTC().Format("2006-01-02 15:04"), extension.NewEnd.UTC().Format("2006-01-02 15:04"))
+			}
+			lines = append(lines, split)
+		}
+	}
+	return lines
+}
+
+// invoicesHandler handles GET /api/v1/invoices, /api/v1/invoices/{id} and
+// /api/v1/invoices/{id}/pdf. Renters see their own invoices; admins see all
+// and may filter by ?user_id.
+func (s *Server) invoicesHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		RespondWithError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	userID, ok := authhttp.UserIDFromContext(r.Context())
+	if !ok {
+		RespondWithError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+	isAdmin := getRoleFromContext(r) == entity.UserRoleAdmin
+
+	rest := strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/v1/invoices"), "/")
+	if rest == "" {
+		s.listInvoices(w, r, userID, isAdmin)
+		return
+	}
+
+	idPart, format, _ := strings.Cut(rest, "/")
+	id, err := strconv.Atoi(idPart)
+	if err != nil || id <= 0 {
+		RespondWithError(w, http.StatusBadRequest, "invalid id")
+		return
+	}
+	if format != "" && format != "pdf" {
+		RespondWithError(w, http.StatusNotFound, "not found")
+		return
+	}
+
+	var invoice entity.Invoice
+	if err := s.db.First(&invoice, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			RespondWithError(w, http.StatusNotFound, "invoice not found")
+			return
+		}
+		RespondWithError(w, http.StatusInternalServerError, "database error")
+		return
+	}
+	if !isAdmin && invoice.UserID != userID {
+		RespondWithError(w, http.StatusForbidden, "forbidden")
+		return
+	}
+
+	if format == "pdf" {
+		w.Header().Set("Content-Type", "application/pdf")
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", invoice.Number+".pdf"))
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write(renderInvoicePDF(invoice))
+		return
+	}
+	RespondWithJSON(w, http.StatusOK, invoice)
+}
+
+func (s *Server) listInvoices(w http.ResponseWriter, r *http.Request, userID uint, isAdmin bool) {
+	page, err := parsePageRequest(r)
+	if err != nil {
+		RespondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	q := s.db.Model(&entity.Invoice{})
+	if !isAdmin {
+		q = q.Where("user_id = ?", userID)
+	} else if v := r.URL.Query().Get("user_id"); v != "" {
+		id, err := strconv.Atoi(v)
+		if err != nil || id <= 0 {
+			RespondWithError(w, http.StatusBadRequest, "invalid user_id")
+			return
+		}
+		q = q.Where("user_id = ?", id)
+	}
+	result, err := paginate(q, page, newestFirst, func(invoice entity.Invoice) (any, uint) {
+		return invoice.ID, invoice.ID
+	})
+	respondWithPage(w, result, err)
+}
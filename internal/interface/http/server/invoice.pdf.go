@@ -0,0 +1,206 @@
+package server
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/CMPNION/Car-Rental-API.git/internal/entity"
+)
+
+// A4 in points, and the margins of the invoice layout.
+const (
+	pdfPageWidth  = 595
+	pdfPageHeight = 842
+	pdfMargin     = 50
+)
+
+// pdfWriter lays out text-only pages with the standard Helvetica fonts, which
+// is all an invoice needs. The standard fonts only cover WinAnsi, so other
+// text is transliterated (Cyrillic) or replaced with '?'.
+type pdfWriter struct {
+	pages []*bytes.Buffer
+	y     float64
+}
+
+func newPDFWriter() *pdfWriter {
+	p := &pdfWriter{}
+	p.newPage()
+	return p
+}
+
+func (p *pdfWriter) newPage() {
+	p.pages = append(p.pages, &bytes.Buffer{})
+	p.y = pdfPageHeight - pdfMargin
+}
+
+// text writes s at x on the current line.
+func (p *pdfWriter) text(x float64, s string, size float64, bold bool) {
+	font := "F1"
+	if bold {
+		font = "F2"
+	}
+	fmt.Fprintf(p.pages[len(p.pages)-1], "BT /%s %.1f Tf %.1f %.1f Td (%s) Tj ET\n", font, size, x, p.y, pdfString(s))
+}
+
+// textRight writes s so that it ends at x, estimating the width of Helvetica.
+func (p *pdfWriter) textRight(x float64, s string, size float64, bold bool) {
+	p.text(x-float64(len(s))*size*0.55, s, size, bold)
+}
+
+// next moves down by dy, starting a new page when the current one is full.
+func (p *pdfWriter) next(dy float64) {
+	p.y -= dy
+	if p.y < pdfMargin {
+		p.newPage()
+	}
+}
+
+func (p *pdfWriter) rule() {
+	fmt.Fprintf(p.pages[len(p.pages)-1], "%d %.1f m %d %.1f l S\n", pdfMargin, p.y+4, pdfPageWidth-pdfMargin, p.y+4)
+}
+
+// bytes assembles the document: catalog, page tree, two fonts, then a page
+// and a content stream per page, followed by the cross-reference table.
+func (p *pdfWriter) bytes() []byte {
+	var objects []string
+	kids := make([]string, len(p.pages))
+	for i := range p.pages {
+		kids[i] = fmt.Sprintf("%d 0 R", 5+2*i)
+	}
+	objects = append(objects,
+		"<< /Type /Catalog /Pages 2 0 R >>",
+		fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(p.pages)),
+		"<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica /Encoding /WinAnsiEncoding >>",
+		"<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica-Bold /Encoding /WinAnsiEncoding >>",
+	)
+	for i, content := range p.pages {
+		objects = append(objects,
+			fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %d %d] /Resources << /Font << /F1 3 0 R /F2 4 0 R >> >> /Contents %d 0 R >>",
+				pdfPageWidth, pdfPageHeight, 6+2*i),
+			fmt.Sprintf("<< /Length %d >>\nstream\n%sendstream", content.Len(), content.String()),
+		)
+	}
+
+	var out bytes.Buffer
+	out.WriteString("%PDF-1.4\n")
+	offsets := make([]int, len(objects))
+	for i, obj := range objects {
+		offsets[i] = out.Len()
+		fmt.Fprintf(&out, "%d 0 obj\n%s\nendobj\n", i+1, obj)
+	}
+	xref := out.Len()
+	fmt.Fprintf(&out, "xref\n0 %d\n0000000000 65535 f \n", len(objects)+1)
+	for _, offset := range offsets {
+		fmt.Fprintf(&out, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(&out, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(objects)+1, xref)
+	return out.Bytes()
+}
+
+var cyrillicToLatin = map[rune]string{
+	'а': "a", 'б': "b", 'в': "v", 'г': "g", 'д': "d", 'е': "e", 'ё': "e", 'ж': "zh", 'з': "z",
+	'и': "i", 'й': "y", 'к': "k", 'л': "l", 'м': "m", 'н': "n", 'о': "o", 'п': "p", 'р': "r",
+	'с': "s", 'т': "t", 'у': "u", 'ф': "f", 'х': "kh", 'ц': "ts", 'ч': "ch", 'ш': "sh", 'щ': "shch",
+	'ъ': "", 'ы': "y", 'ь': "", 'э': "e", 'ю': "yu", 'я': "ya",
+	'ә': "a", 'ғ': "g", 'қ': "q", 'ң': "n", 'ө': "o", 'ұ': "u", 'ү': "u", 'һ': "h", 'і': "i",
+}
+
+// pdfString encodes s as the body of a PDF literal string in WinAnsi.
+func pdfString(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		switch {
+		case r == '(' || r == ')' || r == '\\':
+			b.WriteByte('\\')
+			b.WriteRune(r)
+		case r >= 0x20 && r < 0x7f:
+			b.WriteRune(r)
+		case r >= 0xa0 && r <= 0xff:
+			fmt.Fprintf(&b, "\\%03o", r)
+		default:
+			lower := []rune(strings.ToLower(string(r)))[0]
+			latin, ok := cyrillicToLatin[lower]
+			if !ok {
+				b.WriteByte('?')
+				continue
+			}
+			if lower != r && latin != "" {
+				latin = strings.ToUpper(latin[:1]) + latin[1:]
+			}
+			b.WriteString(latin)
+		}
+	}
+	return b.String()
+}
+
+// renderInvoicePDF lays out an invoice: header, seller and buyer, the lines
+// with their net, tax and gross amounts, and the totals.
+func renderInvoicePDF(inv entity.Invoice) []byte {
+	p := newPDFWriter()
+	p.text(pdfMargin, "VAT INVOICE No. "+inv.Number, 16, true)
+	p.next(20)
+	p.text(pdfMargin, "Date of issue: "+inv.IssuedAt.Format(time.DateOnly), 10, false)
+	p.next(28)
+
+	party := func(title string, lines ...string) {
+		p.text(pdfMargin, title, 11, true)
+		p.next(15)
+		for _, line := range lines {
+			if strings.TrimSpace(line) == "" {
+				continue
+			}
+			p.text(pdfMargin, line, 10, false)
+			p.next(13)
+		}
+		p.next(10)
+	}
+	s := inv.Seller
+	party("Seller", s.Name, "Tax ID (BIN): "+s.TaxID, s.Address,
+		strings.TrimSpace(s.BankName+" "+s.IBAN), bicLine(s.BIC))
+	b := inv.Buyer
+	buyer := []string{b.Name, b.Email}
+	if b.Company != "" {
+		buyer = append([]string{b.Company}, buyer...)
+	}
+	if b.TaxID != "" {
+		buyer = append(buyer, "Tax ID: "+b.TaxID)
+	}
+	party("Buyer", append(buyer, b.Address)...)
+
+	columns := []float64{340, 400, 470, pdfPageWidth - pdfMargin}
+	p.text(pdfMargin, "Description", 10, true)
+	for i, title := range []string{"Net", "VAT %", "VAT", "Gross"} {
+		p.textRight(columns[i], title, 10, true)
+	}
+	p.next(6)
+	p.rule()
+	p.next(12)
+	for _, line := range inv.Lines {
+		name := line.Name
+		if len(name) > 48 {
+			name = name[:47] + "..."
+		}
+		p.text(pdfMargin, name, 10, false)
+		p.textRight(columns[0], line.Net.String(), 10, false)
+		p.textRight(columns[1], fmt.Sprintf("%g", line.TaxPercent), 10, false)
+		p.textRight(columns[2], line.Tax.String(), 10, false)
+		p.textRight(columns[3], line.Gross.String(), 10, false)
+		p.next(14)
+	}
+	p.rule()
+	p.next(12)
+	p.text(pdfMargin, "Total, "+inv.Currency, 10, true)
+	p.textRight(columns[0], inv.Net.String(), 10, true)
+	p.textRight(columns[2], inv.Tax.String(), 10, true)
+	p.textRight(columns[3], inv.Gross.String(), 10, true)
+	return p.bytes()
+}
+
+func bicLine(bic string) string {
+	if bic == "" {
+		return ""
+	}
+	return "BIC: " + bic
+}
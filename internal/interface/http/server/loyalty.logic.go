@@ -0,0 +1,211 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/CMPNION/Car-Rental-API.git/internal/entity"
+	"gorm.io/gorm"
+)
+
+const (
+	// Points earned per currency unit paid, before the tier multiplier.
+	loyaltyPointsPerUnit = 1
+	// What one point is worth when redeemed against a payment.
+	loyaltyPointValue entity.Money = 1
+	loyaltyPointsTTL               = 365 * 24 * time.Hour
+	// Tiers are decided by the points earned within this window.
+	loyaltyTierWindow = 365 * 24 * time.Hour
+)
+
+var errInsufficientPoints = errors.New("insufficient loyalty points")
+
+// loyaltyTier is a status level; higher tiers earn points faster.
+type loyaltyTier struct {
+	Name       string
+	MinPoints  int
+	Multiplier float64
+}
+
+// loyaltyTiers are ordered from the highest tier down.
+var loyaltyTiers = []loyaltyTier{
+	{Name: entity.LoyaltyTierGold, MinPoints: 5000, Multiplier: 1.5},
+	{Name: entity.LoyaltyTierSilver, MinPoints: 1000, Multiplier: 1.25},
+	{Name: entity.LoyaltyTierBronze, MinPoints: 0, Multiplier: 1},
+}
+
+// loyaltyStatus is the loyalty summary shown on the profile.
+type loyaltyStatus struct {
+	Points           int          `json:"points"`
+	PointsValue      entity.Money `json:"points_value"`
+	Tier             string       `json:"tier"`
+	EarnedInWindow   int          `json:"earned_last_365_days"`
+	NextTier         string       `json:"next_tier,omitempty"`
+	PointsToNextTier int          `json:"points_to_next_tier,omitempty"`
+}
+
+// expireLoyaltyPoints writes off what is left of the user's expired lots.
+// It runs before every balance read, so balances never include expired points.
+func expireLoyaltyPoints(tx *gorm.DB, userID uint, now time.Time) error {
+	var lots []entity.LoyaltyEntry
+	if err := tx.Where("user_id = ? AND remaining > 0 AND expires_at <= ?", userID, now.UTC()).
+		Find(&lots).Error; err != nil {
+		return err
+	}
+	for _, lot := range lots {
+		lotID := lot.ID
+		if err := tx.Create(&entity.LoyaltyEntry{
+			UserID: userID,
+			Type:   entity.LoyaltyEntryExpire,
+			Points: -lot.Remaining,
+			Note:   fmt.Sprintf("expired from entry %d", lotID),
+		}).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&entity.LoyaltyEntry{}).Where("id = ?", lotID).
+			Update("remaining", 0).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func loyaltyBalance(tx *gorm.DB, userID uint, now time.Time) (int, error) {
+	if err := expireLoyaltyPoints(tx, userID, now); err != nil {
+		return 0, err
+	}
+	var points int
+	err := tx.Model(&entity.LoyaltyEntry{}).Where("user_id = ?", userID).
+		Select("COALESCE(SUM(points), 0)").Scan(&points).Error
+	return points, err
+}
+
+func userLoyaltyStatus(tx *gorm.DB, userID uint, now time.Time) (loyaltyStatus, error) {
+	points, err := loyaltyBalance(tx, userID, now)
+	if err != nil {
+		return loyaltyStatus{}, err
+	}
+	tier, earned, err := userLoyaltyTier(tx, userID, now)
+	if err != nil {
+		return loyaltyStatus{}, err
+	}
+
+	status := loyaltyStatus{
+		Points:         points,
+		PointsValue:    entity.Money(points) * loyaltyPointValue,
+		Tier:           tier.Name,
+		EarnedInWindow: earned,
+	}
+	for i := len(loyaltyTiers) - 1; i >= 0; i-- {
+		if loyaltyTiers[i].MinPoints > earned {
+			status.NextTier = loyaltyTiers[i].Name
+			status.PointsToNextTier = loyaltyTiers[i].MinPoints - earned
+			break
+		}
+	}
+	return status, nil
+}
+
+// userLoyaltyTier returns the tier reached by the points earned in the window.
+func userLoyaltyTier(tx *gorm.DB, userID uint, now time.Time) (loyaltyTier, int, error) {
+	var earned int
+	if err := tx.Model(&entity.LoyaltyEntry{}).
+		Where("user_id = ? AND type = ? AND created_at >= ?", userID, entity.LoyaltyEntryEarn, now.Add(-loyaltyTierWindow).UTC()).
+		Select("COALESCE(SUM(points), 0)").Scan(&earned).Error; err != nil {
+		return loyaltyTier{}, 0, err
+	}
+	for _, tier := range loyaltyTiers {
+		if earned >= tier.MinPoints {
+			return tier, earned, nil
+		}
+	}
+	return loyaltyTiers[len(loyaltyTiers)-1], earned, nil
+}
+
+// rentalCashPaid is what the renter paid from their balance, i.e. the price
+// minus the value of the redeemed points and what was refunded already.
+func rentalCashPaid(rental entity.Rental) entity.Money {
+	return rental.TotalPrice - entity.Money(rental.PointsRedeemed)*loyaltyPointValue - rental.Refunded
+}
+
+// redeemLoyaltyPoints spends points on a rental, oldest lots first.
+func redeemLoyaltyPoints(tx *gorm.DB, rental entity.Rental, points int, now time.Time) error {
+	balance, err := loyaltyBalance(tx, rental.UserID, now)
+	if err != nil {
+		return err
+	}
+	if balance < points {
+		return errInsufficientPoints
+	}
+
+	var lots []entity.LoyaltyEntry
+	if err := tx.Where("user_id = ? AND remaining > 0", rental.UserID).
+		Order("expires_at asc").Order("id asc").Find(&lots).Error; err != nil {
+		return err
+	}
+	left := points
+	for _, lot := range lots {
+		if left == 0 {
+			break
+		}
+		used := min(lot.Remaining, left)
+		if err := tx.Model(&entity.LoyaltyEntry{}).Where("id = ?", lot.ID).
+			Update("remaining", lot.Remaining-used).Error; err != nil {
+			return err
+		}
+		left -= used
+	}
+
+	rentalID := rental.ID
+	return tx.Create(&entity.LoyaltyEntry{
+		UserID:   rental.UserID,
+		Type:     entity.LoyaltyEntryRedeem,
+		Points:   -points,
+		RentalID: &rentalID,
+	}).Error
+}
+
+// earnLoyaltyPoints credits the points for a completed rental. Only the part
+// paid from the balance earns points, at the renter's current tier.
+func earnLoyaltyPoints(tx *gorm.DB, rental entity.Rental, now time.Time) error {
+	tier, _, err := userLoyaltyTier(tx, rental.UserID, now)
+	if err != nil {
+		return err
+	}
+	points := int(math.Floor(rentalCashPaid(rental).Float() * loyaltyPointsPerUnit * tier.Multiplier))
+	if points <= 0 {
+		return nil
+	}
+
+	rentalID := rental.ID
+	expiresAt := now.Add(loyaltyPointsTTL).UTC()
+	return tx.Create(&entity.LoyaltyEntry{
+		UserID:    rental.UserID,
+		Type:      entity.LoyaltyEntryEarn,
+		Points:    points,
+		RentalID:  &rentalID,
+		ExpiresAt: &expiresAt,
+		Remaining: points,
+		Note:      tier.Name + " tier",
+	}).Error
+}
+
+// refundLoyaltyPoints gives back the points redeemed on a refunded rental as a
+// new lot with a fresh expiry.
+func refundLoyaltyPoints(tx *gorm.DB, rental entity.Rental, now time.Time) error {
+	if rental.PointsRedeemed <= 0 {
+		return nil
+	}
+	rentalID := rental.ID
+	expiresAt := now.Add(loyaltyPointsTTL).UTC()
+	return tx.Create(&entity.LoyaltyEntry{
+		UserID:    rental.UserID,
+		Type:      entity.LoyaltyEntryRefund,
+		Points:    rental.PointsRedeemed,
+		RentalID:  &rentalID,
+		ExpiresAt: &expiresAt,
+		Remaining: rental.PointsRedeemed,
+	}).Error
+}
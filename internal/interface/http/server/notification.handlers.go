@@ -0,0 +1,102 @@
+package server
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/CMPNION/Car-Rental-API.git/internal/entity"
+	authhttp "github.com/CMPNION/Car-Rental-API.git/internal/interface/http/auth"
+	"gorm.io/gorm"
+)
+
+// notifyUser puts a message in the user's inbox.
+func notifyUser(tx *gorm.DB, userID uint, rentalID *uint, kind, title, body string) error {
+	return tx.Create(&entity.Notification{
+		UserID:   userID,
+		Type:     kind,
+		Title:    title,
+		Body:     body,
+		RentalID: rentalID,
+	}).Error
+}
+
+// userNotificationsHandler handles GET /api/v1/users/me/notifications[?unread=true]
+// (newest first) and POST /api/v1/users/me/notifications/{id}/read.
+func (s *Server) userNotificationsHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := authhttp.UserIDFromContext(r.Context())
+	if !ok {
+		RespondWithError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	rest := strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/v1/users/me/notifications"), "/")
+	if rest == "" {
+		if r.Method != http.MethodGet {
+			RespondWithError(w, http.StatusMethodNotAllowed, "method not allowed")
+			return
+		}
+		s.listNotifications(w, r, userID)
+		return
+	}
+
+	idPart, action, _ := strings.Cut(rest, "/")
+	id, err := strconv.Atoi(idPart)
+	if err != nil || id <= 0 {
+		RespondWithError(w, http.StatusBadRequest, "invalid id")
+		return
+	}
+	if action != "read" {
+		RespondWithError(w, http.StatusNotFound, "unknown action")
+		return
+	}
+	if r.Method != http.MethodPost {
+		RespondWithError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	var notification entity.Notification
+	if err := s.db.Where("id = ? AND user_id = ?", id, userID).First(&notification).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			RespondWithError(w, http.StatusNotFound, "notification not found")
+			return
+		}
+		RespondWithError(w, http.StatusInternalServerError, "database error")
+		return
+	}
+	if notification.ReadAt == nil {
+		now := time.Now().UTC()
+		if err := s.db.Model(&notification).Update("read_at", now).Error; err != nil {
+			RespondWithError(w, http.StatusInternalServerError, "database error")
+			return
+		}
+		notification.ReadAt = &now
+	}
+	RespondWithJSON(w, http.StatusOK, notification)
+}
+
+func (s *Server) listNotifications(w http.ResponseWriter, r *http.Request, userID uint) {
+	page, err := parsePageRequest(r)
+	if err != nil {
+		RespondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	q := s.db.Model(&entity.Notification{}).Where("user_id = ?", userID)
+	if v := r.URL.Query().Get("unread"); v != "" {
+		unread, err := strconv.ParseBool(v)
+		if err != nil {
+			RespondWithError(w, http.StatusBadRequest, "invalid unread")
+			return
+		}
+		if unread {
+			q = q.Where("read_at IS NULL")
+		}
+	}
+	result, err := paginate(q, page, newestFirst, func(n entity.Notification) (any, uint) {
+		return n.ID, n.ID
+	})
+	respondWithPage(w, result, err)
+}
@@ -0,0 +1,208 @@
+package server
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const (
+	defaultPageLimit = 20
+	maxPageLimit     = 200
+)
+
+// Page is the envelope returned by every list endpoint.
+type Page struct {
+	Items      any    `json:"items"`
+	NextCursor string `json:"next_cursor,omitempty"`
+	HasMore    bool   `json:"has_more"`
+	Total      *int64 `json:"total,omitempty"`
+}
+
+// sortKey describes the keyset a list is ordered by. Column is the SQL
+// expression of the sort value and IDColumn the unique tie-breaker.
+type sortKey struct {
+	Name     string
+	Column   string
+	IDColumn string
+	Desc     bool
+	IsTime   bool
+}
+
+// newestFirst orders by primary key descending, i.e. by creation time.
+var newestFirst = sortKey{Name: "newest", Column: "id", IDColumn: "id", Desc: true}
+
+type pageRequest struct {
+	Limit     int
+	WithTotal bool
+	cursor    *pageCursor
+}
+
+// pageCursor is the decoded form of the opaque next_cursor token.
+type pageCursor struct {
+	Sort  string          `json:"s"`
+	Value json.RawMessage `json:"v"`
+	ID    uint            `json:"id"`
+}
+
+// parsePageRequest reads limit, cursor and include_total from the query string.
+func parsePageRequest(r *http.Request) (pageRequest, error) {
+	req := pageRequest{Limit: defaultPageLimit}
+
+	if v := r.URL.Query().Get("limit"); v != "" {
+		lim, err := strconv.Atoi(v)
+		if err != nil || lim <= 0 || lim > maxPageLimit {
+			return req, errors.New("invalid limit")
+		}
+		req.Limit = lim
+	}
+
+	if v := r.URL.Query().Get("include_total"); v != "" {
+		withTotal, err := strconv.ParseBool(v)
+		if err != nil {
+			return req, errors.New("invalid include_total")
+		}
+		req.WithTotal = withTotal
+	}
+
+	if v := r.URL.Query().Get("cursor"); v != "" {
+		raw, err := base64.RawURLEncoding.DecodeString(v)
+		if err != nil {
+			return req, errors.New("invalid cursor")
+		}
+		var c pageCursor
+		if err := json.Unmarshal(raw, &c); err != nil || c.ID == 0 {
+			return req, errors.New("invalid cursor")
+		}
+		req.cursor = &c
+	}
+
+	return req, nil
+}
+
+// respondWithPage writes the error of parsePageRequest/paginate or the page itself.
+func respondWithPage(w http.ResponseWriter, page Page, err error) {
+	if err != nil {
+		var cursorErr cursorError
+		if errors.As(err, &cursorErr) {
+			RespondWithError(w, http.StatusBadRequest, cursorErr.Error())
+			return
+		}
+		RespondWithError(w, http.StatusInternalServerError, "database error")
+		return
+	}
+	RespondWithJSON(w, http.StatusOK, page)
+}
+
+type cursorError struct{ msg string }
+
+func (e cursorError) Error() string { return e.msg }
+
+// paginate applies keyset pagination to q ordered by key and loads one page of T.
+// keyOf returns the sort value and id of an item, used to build next_cursor.
+func paginate[T any](q *gorm.DB, req pageRequest, key sortKey, keyOf func(T) (any, uint)) (Page, error) {
+	var page Page
+
+	if req.WithTotal {
+		var total int64
+		if err := q.Session(&gorm.Session{}).Count(&total).Error; err != nil {
+			return page, err
+		}
+		page.Total = &total
+	}
+
+	dir, cmp := "asc", ">"
+	if key.Desc {
+		dir, cmp = "desc", "<"
+	}
+
+	if req.cursor != nil {
+		if req.cursor.Sort != key.Name {
+			return page, cursorError{"cursor does not match sort"}
+		}
+		value, err := decodeCursorValue(req.cursor.Value, key.IsTime)
+		if err != nil {
+			return page, cursorError{"invalid cursor"}
+		}
+		if key.Column == key.IDColumn {
+			q = q.Where(fmt.Sprintf("%s %s ?", key.IDColumn, cmp), req.cursor.ID)
+		} else {
+			q = q.Where(fmt.Sprintf("((%s %s ?) OR (%s = ? AND %s %s ?))", key.Column, cmp, key.Column, key.IDColumn, cmp),
+				value, value, req.cursor.ID)
+		}
+	}
+
+	q = q.Order(key.Column + " " + dir)
+	if key.Column != key.IDColumn {
+		q = q.Order(key.IDColumn + " " + dir)
+	}
+
+	items := make([]T, 0, req.Limit+1)
+	if err := q.Limit(req.Limit + 1).Find(&items).Error; err != nil {
+		return page, err
+	}
+
+	if len(items) > req.Limit {
+		items = items[:req.Limit]
+		page.HasMore = true
+
+		value, id := keyOf(items[len(items)-1])
+		encoded, err := encodeCursor(key, value, id)
+		if err != nil {
+			return page, err
+		}
+		page.NextCursor = encoded
+	}
+	page.Items = items
+
+	return page, nil
+}
+
+func encodeCursor(key sortKey, value any, id uint) (string, error) {
+	if t, ok := value.(time.Time); ok {
+		// Keep the offset: timestamps compare as text in SQLite, so the
+		// cursor has to be bound in the same zone the value was stored in.
+		value = t.Format(time.RFC3339Nano)
+	}
+	rawValue, err := json.Marshal(value)
+	if err != nil {
+		return "", err
+	}
+	raw, err := json.Marshal(pageCursor{Sort: key.Name, Value: rawValue, ID: id})
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(raw), nil
+}
+
+func decodeCursorValue(raw json.RawMessage, isTime bool) (any, error) {
+	if isTime {
+		var s string
+		if err := json.Unmarshal(raw, &s); err != nil {
+			return nil, err
+		}
+		return time.Parse(time.RFC3339Nano, s)
+	}
+	// Integers are bound back as integers, so they compare exactly with
+	// integer columns such as money in minor units.
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.UseNumber()
+	var v any
+	if err := dec.Decode(&v); err != nil {
+		return nil, err
+	}
+	if n, ok := v.(json.Number); ok {
+		if i, err := n.Int64(); err == nil {
+			return i, nil
+		}
+		return n.Float64()
+	}
+	return v, nil
+}
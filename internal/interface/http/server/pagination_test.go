@@ -0,0 +1,62 @@
+package server
+
+import (
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+)
+
+func TestCursorRoundTrip(t *testing.T) {
+	stored := time.Date(2026, 3, 2, 10, 30, 0, 123456789, time.FixedZone("UTC+5", 5*60*60))
+
+	tests := []struct {
+		name  string
+		key   sortKey
+		value any
+		want  any
+	}{
+		{"minor units", sortKey{Name: "price_per_hour"}, int64(1999), int64(1999)},
+		{"large integer", sortKey{Name: "price_per_hour"}, int64(1<<53 + 1), int64(1<<53 + 1)},
+		{"float", sortKey{Name: "rating"}, 4.75, 4.75},
+		{"string", sortKey{Name: "mark"}, "Kia", "Kia"},
+		{"time keeps its zone", sortKey{Name: "created_at", IsTime: true}, stored, stored},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			encoded, err := encodeCursor(tt.key, tt.value, 42)
+			if err != nil {
+				t.Fatal(err)
+			}
+			req, err := parsePageRequest(httptest.NewRequest("GET", "/?"+url.Values{"cursor": {encoded}}.Encode(), nil))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if req.cursor.Sort != tt.key.Name || req.cursor.ID != 42 {
+				t.Errorf("cursor = %s/%d, want %s/42", req.cursor.Sort, req.cursor.ID, tt.key.Name)
+			}
+			got, err := decodeCursorValue(req.cursor.Value, tt.key.IsTime)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if want, ok := tt.want.(time.Time); ok {
+				// The offset matters: timestamps compare as text.
+				if gotTime, ok := got.(time.Time); !ok || gotTime.Format(time.RFC3339Nano) != want.Format(time.RFC3339Nano) {
+					t.Errorf("value = %v, want %v", got, want)
+				}
+				return
+			}
+			if got != tt.want {
+				t.Errorf("value = %v (%T), want %v (%T)", got, got, tt.want, tt.want)
+			}
+		})
+	}
+}
+
+func TestParsePageRequestRejectsBadCursors(t *testing.T) {
+	for _, cursor := range []string{"not base64!", "bm90IGpzb24", "eyJzIjoibmV3ZXN0In0"} {
+		if _, err := parsePageRequest(httptest.NewRequest("GET", "/?"+url.Values{"cursor": {cursor}}.Encode(), nil)); err == nil {
+			t.Errorf("cursor %q was accepted", cursor)
+		}
+	}
+}
@@ -0,0 +1,310 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/CMPNION/Car-Rental-API.git/internal/entity"
+	authhttp "github.com/CMPNION/Car-Rental-API.git/internal/interface/http/auth"
+	"gorm.io/gorm"
+)
+
+const (
+	maxPricingMultiplier      = 10
+	maxLateReturnGraceMinutes = 24 * 60
+)
+
+// pricingRuleSetRequest is the payload of POST /api/v1/admin/pricing/rules.
+type pricingRuleSetRequest struct {
+	EffectiveFrom *time.Time          `json:"effective_from"`
+	Note          string              `json:"note"`
+	Rules         entity.PricingRules `json:"rules"`
+}
+
+// pricingRulesHandler handles /api/v1/admin/pricing/rules[/active|/{version}] (admin).
+// Versions are immutable; a version that is not in effect yet may be deleted.
+func (s *Server) pricingRulesHandler(w http.ResponseWriter, r *http.Request) {
+	rest := strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/v1/admin/pricing/rules"), "/")
+
+	if rest == "" {
+		switch r.Method {
+		case http.MethodGet:
+			s.listPricingRules(w, r)
+		case http.MethodPost:
+			s.createPricingRules(w, r)
+		default:
+			RespondWithError(w, http.StatusMethodNotAllowed, "method not allowed")
+		}
+		return
+	}
+
+	if rest == "active" {
+		if r.Method != http.MethodGet {
+			RespondWithError(w, http.StatusMethodNotAllowed, "method not allowed")
+			return
+		}
+		set, err := activePricingRules(s.db, time.Now())
+		if err != nil {
+			if errors.Is(err, errNoPricingRules) {
+				RespondWithError(w, http.StatusNotFound, "no pricing rules in effect")
+				return
+			}
+			RespondWithError(w, http.StatusInternalServerError, "database error")
+			return
+		}
+		RespondWithJSON(w, http.StatusOK, set)
+		return
+	}
+
+	version, err := strconv.Atoi(rest)
+	if err != nil || version <= 0 {
+		RespondWithError(w, http.StatusBadRequest, "invalid version")
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		var set entity.PricingRuleSet
+		if err := s.db.Where("version = ?", version).First(&set).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				RespondWithError(w, http.StatusNotFound, "pricing rules version not found")
+				return
+			}
+			RespondWithError(w, http.StatusInternalServerError, "database error")
+			return
+		}
+		RespondWithJSON(w, http.StatusOK, set)
+	case http.MethodDelete:
+		res := s.db.Unscoped().Where("version = ? AND effective_from > ?", version, time.Now().UTC()).
+			Delete(&entity.PricingRuleSet{})
+		if res.Error != nil {
+			RespondWithError(w, http.StatusInternalServerError, "database error")
+			return
+		}
+		if res.RowsAffected == 0 {
+			RespondWithError(w, http.StatusConflict, "only versions that are not in effect yet can be deleted")
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		RespondWithError(w, http.StatusMethodNotAllowed, "method not allowed")
+	}
+}
+
+func (s *Server) listPricingRules(w http.ResponseWriter, r *http.Request) {
+	page, err := parsePageRequest(r)
+	if err != nil {
+		RespondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	q := s.db.Model(&entity.PricingRuleSet{})
+	result, err := paginate(q, page, newestFirst, func(set entity.PricingRuleSet) (any, uint) {
+		return set.ID, set.ID
+	})
+	respondWithPage(w, result, err)
+}
+
+func (s *Server) createPricingRules(w http.ResponseWriter, r *http.Request) {
+	var req pricingRuleSetRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		RespondWithError(w, http.StatusBadRequest, "invalid JSON")
+		return
+	}
+
+	now := time.Now().UTC()
+	set := entity.PricingRuleSet{
+		EffectiveFrom: now,
+		Note:          strings.TrimSpace(req.Note),
+		Rules:         req.Rules,
+	}
+	if req.EffectiveFrom != nil {
+		if req.EffectiveFrom.Before(now.Add(-time.Minute)) {
+			RespondWithError(w, http.StatusBadRequest, "effective_from cannot be in the past")
+			return
+		}
+		set.EffectiveFrom = req.EffectiveFrom.UTC()
+	}
+	if userID, ok := authhttp.UserIDFromContext(r.Context()); ok {
+		set.CreatedBy = &userID
+	}
+
+	var msg string
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if msg = validatePricingRules(tx, set.Rules); msg != "" {
+			return nil
+		}
+		var last uint
+		if err := tx.Unscoped().Model(&entity.PricingRuleSet{}).
+			Select("COALESCE(MAX(version), 0)").Scan(&last).Error; err != nil {
+			return err
+		}
+		set.Version = last + 1
+		return tx.Create(&set).Error
+	})
+	if err != nil {
+		RespondWithError(w, http.StatusInternalServerError, "database error")
+		return
+	}
+	if msg != "" {
+		RespondWithError(w, http.StatusBadRequest, msg)
+		return
+	}
+	RespondWithJSON(w, http.StatusCreated, set)
+}
+
+// validatePricingRules checks a rule document, including that overrides point
+// at existing categories and cars.
+func validatePricingRules(db *gorm.DB, rules entity.PricingRules) string {
+	validMultiplier := func(m float64) bool {
+		return m == 0 || (m > 0 && m <= maxPricingMultiplier)
+	}
+
+	if rules.TimeZone != "" {
+		if _, err := time.LoadLocation(rules.TimeZone); err != nil {
+			return "unknown time_zone"
+		}
+	}
+	if msg := validatePricingTiers("tiers", rules.Tiers); msg != "" {
+		return msg
+	}
+	if !validMultiplier(rules.WeekendMultiplier) {
+		return "weekend_multiplier must be within 0..10"
+	}
+
+	for i, h := range rules.Holidays {
+		if _, err := time.Parse(time.DateOnly, h.Date); err != nil {
+			return fmt.Sprintf("holidays[%d].date must be YYYY-MM-DD", i)
+		}
+		if h.Multiplier <= 0 || h.Multiplier > maxPricingMultiplier {
+			return fmt.Sprintf("holidays[%d].multiplier must be within 0..10", i)
+		}
+	}
+	for i, season := range rules.Seasons {
+		if _, err := parseMonthDay(season.From); err != nil {
+			return fmt.Sprintf("seasons[%d].from must be MM-DD", i)
+		}
+		if _, err := parseMonthDay(season.To); err != nil {
+			return fmt.Sprintf("seasons[%d].to must be MM-DD", i)
+		}
+		if season.Multiplier <= 0 || season.Multiplier > maxPricingMultiplier {
+			return fmt.Sprintf("seasons[%d].multiplier must be within 0..10", i)
+		}
+	}
+	for i, m := range rules.RatingModifiers {
+		if m.Above != nil && m.Below != nil && *m.Above >= *m.Below {
+			return fmt.Sprintf("rating_modifiers[%d]: above must be less than below", i)
+		}
+		if m.Multiplier <= 0 || m.Multiplier > maxPricingMultiplier {
+			return fmt.Sprintf("rating_modifiers[%d].multiplier must be within 0..10", i)
+		}
+	}
+
+	if d := rules.Dynamic; d != nil {
+		switch {
+		case d.TargetUtilization < 0 || d.TargetUtilization > 1:
+			return "dynamic.target_utilization must be within 0..1"
+		case d.Sensitivity < 0 || d.Sensitivity > maxPricingMultiplier:
+			return "dynamic.sensitivity must be within 0..10"
+		case d.Floor <= 0 || d.Floor > 1:
+			return "dynamic.floor must be within 0..1"
+		case d.Ceiling < 1 || d.Ceiling > maxPricingMultiplier:
+			return "dynamic.ceiling must be within 1..10"
+		}
+		for i, code := range d.Categories {
+			if _, err := findCarCategory(db, code); err != nil {
+				return fmt.Sprintf("dynamic.categories[%d]: unknown category %q", i, code)
+			}
+		}
+	}
+
+	if l := rules.LateReturn; l != nil {
+		switch {
+		case l.GraceMinutes < 0 || l.GraceMinutes > maxLateReturnGraceMinutes:
+			return "late_return.grace_minutes must be within 0..1440"
+		case l.HourlyMultiplier < 0 || l.HourlyMultiplier > maxPricingMultiplier:
+			return "late_return.hourly_multiplier must be within 0..10"
+		case l.Flat < 0:
+			return "late_return.flat must be >= 0"
+		case l.Cap < 0:
+			return "late_return.cap must be >= 0"
+		}
+	}
+
+	if e := rules.EarlyReturn; e != nil {
+		switch {
+		case e.Refund != entity.EarlyReturnRefundNone && e.Refund != entity.EarlyReturnRefundFull && e.Refund != entity.EarlyReturnRefundPartial:
+			return "early_return.refund must be none, full or partial"
+		case e.MinUnusedHours < 0:
+			return "early_return.min_unused_hours must be >= 0"
+		case e.Refund == entity.EarlyReturnRefundPartial && (e.Percent <= 0 || e.Percent > 100):
+			return "early_return.percent must be within 0..100"
+		}
+	}
+
+	extras := map[string]bool{}
+	for i, extra := range rules.Extras {
+		switch {
+		case !categoryCodePattern.MatchString(extra.Code):
+			return fmt.Sprintf("extras[%d].code must be 1-32 characters of a-z, 0-9, '_' or '-'", i)
+		case extras[extra.Code]:
+			return fmt.Sprintf("extras[%d]: duplicate code", i)
+		case strings.TrimSpace(extra.Name) == "":
+			return fmt.Sprintf("extras[%d].name is required", i)
+		case extra.Fee < 0:
+			return fmt.Sprintf("extras[%d].fee must be >= 0", i)
+		}
+		extras[extra.Code] = true
+	}
+
+	for i, o := range rules.Overrides {
+		switch {
+		case (o.Category == "") == (o.CarID == 0):
+			return fmt.Sprintf("overrides[%d] must set exactly one of category or car_id", i)
+		case o.HourlyRate != nil && *o.HourlyRate <= 0:
+			return fmt.Sprintf("overrides[%d].hourly_rate must be > 0", i)
+		case o.Multiplier != nil && (*o.Multiplier <= 0 || *o.Multiplier > maxPricingMultiplier):
+			return fmt.Sprintf("overrides[%d].multiplier must be within 0..10", i)
+		case o.WeekendMultiplier != nil && !validMultiplier(*o.WeekendMultiplier):
+			return fmt.Sprintf("overrides[%d].weekend_multiplier must be within 0..10", i)
+		}
+		if msg := validatePricingTiers(fmt.Sprintf("overrides[%d].tiers", i), o.Tiers); msg != "" {
+			return msg
+		}
+
+		if o.Category != "" {
+			if _, err := findCarCategory(db, o.Category); err != nil {
+				return fmt.Sprintf("overrides[%d]: unknown category %q", i, o.Category)
+			}
+			continue
+		}
+		var cars int64
+		if err := db.Model(&entity.Car{}).Where("id = ?", o.CarID).Count(&cars).Error; err != nil || cars == 0 {
+			return fmt.Sprintf("overrides[%d]: car %d not found", i, o.CarID)
+		}
+	}
+	return ""
+}
+
+func validatePricingTiers(field string, tiers []entity.PricingTier) string {
+	seen := map[float64]bool{}
+	for i, tier := range tiers {
+		switch {
+		case strings.TrimSpace(tier.Name) == "":
+			return fmt.Sprintf("%s[%d].name is required", field, i)
+		case tier.MinHours <= 0:
+			return fmt.Sprintf("%s[%d].min_hours must be > 0", field, i)
+		case seen[tier.MinHours]:
+			return fmt.Sprintf("%s[%d]: duplicate min_hours", field, i)
+		case tier.Multiplier <= 0 || tier.Multiplier > maxPricingMultiplier:
+			return fmt.Sprintf("%s[%d].multiplier must be within 0..10", field, i)
+		}
+		seen[tier.MinHours] = true
+	}
+	return ""
+}
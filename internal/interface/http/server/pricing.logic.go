@@ -0,0 +1,342 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/CMPNION/Car-Rental-API.git/internal/entity"
+	"gorm.io/gorm"
+)
+
+var (
+	errNoPricingRules = errors.New("no pricing rules in effect")
+	errUnknownOption  = errors.New("unknown rental option")
+)
+
+// effectiveRules are the rules for one car after applying overrides.
+type effectiveRules struct {
+	hourlyRate entity.Money
+	weekend    float64
+	tiers      []entity.PricingTier
+}
+
+// activePricingRules returns the latest rule set in effect at the given time.
+func activePricingRules(db *gorm.DB, at time.Time) (entity.PricingRuleSet, error) {
+	var set entity.PricingRuleSet
+	err := db.Where("effective_from <= ?", at.UTC()).
+		Order("effective_from desc").Order("version desc").
+		First(&set).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return set, errNoPricingRules
+	}
+	return set, err
+}
+
+// rentalPricingRules are the rules that priced the rental, or those in effect
+// at its end for rentals priced before versioning. ok is false without any.
+func rentalPricingRules(db *gorm.DB, rental entity.Rental) (rules entity.PricingRules, ok bool, err error) {
+	var set entity.PricingRuleSet
+	if rental.PricingVersion > 0 {
+		err = db.Unscoped().Where("version = ?", rental.PricingVersion).First(&set).Error
+	} else {
+		set, err = activePricingRules(db, rental.EndDate)
+	}
+	if errors.Is(err, gorm.ErrRecordNotFound) || errors.Is(err, errNoPricingRules) {
+		return rules, false, nil
+	}
+	if err != nil {
+		return rules, false, err
+	}
+	return set.Rules, true, nil
+}
+
+// CalculatePrice is our isolated Pricing Engine. The rental is priced hour by
+// hour so weekend, holiday and seasonal multipliers only apply to the hours
+// they cover; the duration tier and the rating modifier then apply to the
+// whole, and the fees of the picked options are added last. Each line is
+// rounded to a whole minor unit once, see entity.Money.
+func CalculatePrice(set entity.PricingRuleSet, car entity.Car, start, end time.Time, userRating float64, options []string) (entity.PriceBreakdown, error) {
+	rules := set.Rules
+	eff := applyPricingOverrides(rules, car)
+	loc := pricingLocation(rules)
+
+	var base, weekend, holiday, seasonal float64
+	for t := start; t.Before(end); t = t.Add(time.Hour) {
+		next := t.Add(time.Hour)
+		if next.After(end) {
+			next = end
+		}
+		slot := float64(eff.hourlyRate) * next.Sub(t).Hours()
+		base += slot
+
+		local := t.In(loc)
+		if m, ok := holidayMultiplier(rules, local); ok {
+			holiday += slot * (m - 1)
+			slot *= m
+		} else if isWeekend(local) {
+			weekend += slot * (eff.weekend - 1)
+			slot *= eff.weekend
+		}
+		seasonal += slot * (seasonMultiplier(rules, local) - 1)
+	}
+
+	hours := end.Sub(start).Hours()
+	b := entity.PriceBreakdown{
+		PricingVersion: set.Version,
+		HourlyRate:     eff.hourlyRate,
+		Hours:          math.Round(hours*100) / 100,
+		Base:           entity.RoundMoney(base),
+		Surcharges:     []entity.PriceLine{},
+		Discounts:      []entity.PriceLine{},
+		Fees:           []entity.PriceLine{},
+		Taxes:          []entity.PriceLine{},
+	}
+	addPriceLine(&b.Surcharges, "weekend", "Weekend surcharge", entity.RoundMoney(weekend))
+	addPriceLine(&b.Surcharges, "holiday", "Holiday surcharge", entity.RoundMoney(holiday))
+	addPriceLine(&b.Surcharges, "seasonal", "Seasonal adjustment", entity.RoundMoney(seasonal))
+	subtotal := sumPriceLines(b.Base, b.Surcharges)
+
+	if tier, ok := durationTier(eff.tiers, hours); ok && tier.Multiplier != 1 {
+		b.DurationTier = &entity.PriceLine{Code: "duration_tier", Name: tier.Name, Amount: subtotal.Mul(tier.Multiplier - 1)}
+		subtotal += b.DurationTier.Amount
+	}
+	if m := ratingMultiplier(rules, userRating); m != 1 {
+		b.RatingModifier = &entity.PriceLine{Code: "rating", Name: "Rating modifier", Amount: subtotal.Mul(m - 1)}
+	}
+
+	days := entity.Money(math.Ceil(hours / 24))
+	seen := map[string]bool{}
+	for _, code := range options {
+		code = strings.ToLower(strings.TrimSpace(code))
+		if seen[code] {
+			continue
+		}
+		seen[code] = true
+		extra, ok := findPricingExtra(rules, code)
+		if !ok {
+			return b, fmt.Errorf("%w: %q", errUnknownOption, code)
+		}
+		fee := extra.Fee
+		if extra.PerDay {
+			fee *= days
+		}
+		b.Fees = append(b.Fees, entity.PriceLine{Code: extra.Code, Name: extra.Name, Amount: fee})
+	}
+
+	totalPriceBreakdown(&b)
+	return b, nil
+}
+
+// rentalSubtotal is the price of the rental itself: base, surcharges, duration
+// tier, rating modifier and demand adjustment, before discounts, fees and taxes.
+func rentalSubtotal(b entity.PriceBreakdown) entity.Money {
+	total := sumPriceLines(b.Base, b.Surcharges)
+	if b.DurationTier != nil {
+		total += b.DurationTier.Amount
+	}
+	if b.RatingModifier != nil {
+		total += b.RatingModifier.Amount
+	}
+	if b.Demand != nil {
+		total += b.Demand.Amount
+	}
+	return total
+}
+
+// totalPriceBreakdown recomputes Total from the base and all lines.
+func totalPriceBreakdown(b *entity.PriceBreakdown) {
+	total := sumPriceLines(rentalSubtotal(*b), b.Discounts)
+	total = sumPriceLines(total, b.Fees)
+	total = sumPriceLines(total, b.Taxes)
+	b.Total = total
+}
+
+// addPriceLine appends a line unless its amount is zero.
+func addPriceLine(lines *[]entity.PriceLine, code, name string, amount entity.Money) {
+	if amount != 0 {
+		*lines = append(*lines, entity.PriceLine{Code: code, Name: name, Amount: amount})
+	}
+}
+
+func sumPriceLines(total entity.Money, lines []entity.PriceLine) entity.Money {
+	for _, line := range lines {
+		total += line.Amount
+	}
+	return total
+}
+
+func findPricingExtra(rules entity.PricingRules, code string) (entity.PricingExtra, bool) {
+	for _, extra := range rules.Extras {
+		if extra.Code == code {
+			return extra, true
+		}
+	}
+	return entity.PricingExtra{}, false
+}
+
+// quoteRental prices a rental request without writing anything. createRental
+// stores the same breakdown, so a quote and the booking that follows agree.
+// The promo code, if any, is returned so the booking can redeem it.
+func quoteRental(db *gorm.DB, car entity.Car, category entity.CarCategory, user entity.User, req RentalRequest, now time.Time) (entity.PriceBreakdown, *entity.PromoCode, error) {
+	var promo *entity.PromoCode
+	if strings.TrimSpace(req.PromoCode) != "" {
+		p, err := checkPromoCode(db, req.PromoCode, user, car, req, now)
+		if err != nil {
+			return entity.PriceBreakdown{}, nil, err
+		}
+		promo = &p
+	}
+	b, err := priceRental(db, car, category, user, req, promo, 0, now)
+	return b, promo, err
+}
+
+// priceRental prices the request with the rules in effect now and applies an
+// already accepted promo code. Tax is added last, and the total is also shown
+// in the renter's currency at today's rate. excludeRentalID keeps a rental
+// being repriced out of its own demand.
+func priceRental(db *gorm.DB, car entity.Car, category entity.CarCategory, user entity.User, req RentalRequest, promo *entity.PromoCode, excludeRentalID uint, now time.Time) (entity.PriceBreakdown, error) {
+	rules, err := activePricingRules(db, now)
+	if err != nil {
+		return entity.PriceBreakdown{}, err
+	}
+	b, err := CalculatePrice(rules, car, req.StartDate, req.EndDate, user.Rating, req.Options)
+	if err != nil {
+		return b, err
+	}
+	b.Deposit = category.DefaultDeposit
+	if err := applyDemandPricing(db, &b, rules.Rules, car, req.StartDate, req.EndDate, excludeRentalID); err != nil {
+		return b, err
+	}
+	if promo != nil {
+		applyPromoDiscount(&b, *promo)
+	}
+	if err := applyTaxes(db, &b, car, now); err != nil {
+		return b, err
+	}
+	if err := applyCurrency(db, &b, user, now); err != nil {
+		return b, err
+	}
+	return b, nil
+}
+
+// applyPricingOverrides resolves the category override and then the car override.
+func applyPricingOverrides(rules entity.PricingRules, car entity.Car) effectiveRules {
+	eff := effectiveRules{
+		hourlyRate: car.PricePerHour,
+		weekend:    multiplierOrOne(rules.WeekendMultiplier),
+		tiers:      rules.Tiers,
+	}
+	multiplier := 1.0
+
+	apply := func(o entity.PricingOverride) {
+		if o.HourlyRate != nil {
+			eff.hourlyRate = *o.HourlyRate
+		}
+		if o.Multiplier != nil {
+			multiplier = *o.Multiplier
+		}
+		if o.WeekendMultiplier != nil {
+			eff.weekend = multiplierOrOne(*o.WeekendMultiplier)
+		}
+		if o.Tiers != nil {
+			eff.tiers = o.Tiers
+		}
+	}
+	for _, o := range rules.Overrides {
+		if o.Category != "" && o.Category == car.Category {
+			apply(o)
+		}
+	}
+	for _, o := range rules.Overrides {
+		if o.CarID != 0 && o.CarID == car.ID {
+			apply(o)
+		}
+	}
+
+	eff.hourlyRate = eff.hourlyRate.Mul(multiplier)
+	return eff
+}
+
+func pricingLocation(rules entity.PricingRules) *time.Location {
+	if rules.TimeZone == "" {
+		return time.UTC
+	}
+	loc, err := time.LoadLocation(rules.TimeZone)
+	if err != nil {
+		return time.UTC
+	}
+	return loc
+}
+
+func isWeekend(t time.Time) bool {
+	return t.Weekday() == time.Saturday || t.Weekday() == time.Sunday
+}
+
+func holidayMultiplier(rules entity.PricingRules, t time.Time) (float64, bool) {
+	date := t.Format(time.DateOnly)
+	for _, h := range rules.Holidays {
+		if h.Date == date {
+			return multiplierOrOne(h.Multiplier), true
+		}
+	}
+	return 1, false
+}
+
+func seasonMultiplier(rules entity.PricingRules, t time.Time) float64 {
+	day := int(t.Month())*100 + t.Day()
+	m := 1.0
+	for _, season := range rules.Seasons {
+		from, errFrom := parseMonthDay(season.From)
+		to, errTo := parseMonthDay(season.To)
+		if errFrom != nil || errTo != nil {
+			continue
+		}
+		in := from <= day && day <= to
+		if from > to {
+			in = day >= from || day <= to
+		}
+		if in {
+			m *= multiplierOrOne(season.Multiplier)
+		}
+	}
+	return m
+}
+
+// parseMonthDay turns "MM-DD" into MM*100+DD.
+func parseMonthDay(v string) (int, error) {
+	t, err := time.Parse("01-02", v)
+	if err != nil {
+		return 0, fmt.Errorf("invalid month-day %q", v)
+	}
+	return int(t.Month())*100 + t.Day(), nil
+}
+
+func durationTier(tiers []entity.PricingTier, hours float64) (entity.PricingTier, bool) {
+	var best entity.PricingTier
+	found := false
+	for _, tier := range tiers {
+		if hours >= tier.MinHours && (!found || tier.MinHours > best.MinHours) {
+			best, found = tier, true
+		}
+	}
+	return best, found
+}
+
+func ratingMultiplier(rules entity.PricingRules, rating float64) float64 {
+	for _, m := range rules.RatingModifiers {
+		if (m.Above == nil || rating > *m.Above) && (m.Below == nil || rating < *m.Below) {
+			return multiplierOrOne(m.Multiplier)
+		}
+	}
+	return 1
+}
+
+func multiplierOrOne(m float64) float64 {
+	if m == 0 {
+		return 1
+	}
+	return m
+}
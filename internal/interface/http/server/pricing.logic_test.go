@@ -0,0 +1,173 @@
+package server
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/CMPNION/Car-Rental-API.git/internal/entity"
+)
+
+func TestCalculatePrice(t *testing.T) {
+	// 2 March 2026 is a Monday, 7 March a Saturday.
+	monday := time.Date(2026, 3, 2, 10, 0, 0, 0, time.UTC)
+	friday := time.Date(2026, 3, 6, 22, 0, 0, 0, time.UTC)
+	saturday := time.Date(2026, 3, 7, 10, 0, 0, 0, time.UTC)
+	car := entity.Car{Category: entity.CarCategoryBusiness, PricePerHour: 1000}
+	car.ID = 7
+	below3 := 3.0
+	categoryRate := entity.Money(2000)
+	categoryMultiplier, carMultiplier := 1.5, 2.0
+
+	tests := []struct {
+		name       string
+		rules      entity.PricingRules
+		start, end time.Time
+		rating     float64
+		want       entity.Money
+		wantLines  map[string]entity.Money
+	}{
+		{name: "hourly", start: monday, end: monday.Add(3 * time.Hour), want: 3000},
+		{name: "part of an hour", start: monday, end: monday.Add(90 * time.Minute), want: 1500},
+		{
+			name:  "weekend hours only",
+			rules: entity.PricingRules{WeekendMultiplier: 1.5},
+			start: friday, end: friday.Add(4 * time.Hour),
+			want: 5000, wantLines: map[string]entity.Money{"weekend": 1000},
+		},
+		{
+			name: "holiday replaces weekend",
+			rules: entity.PricingRules{WeekendMultiplier: 1.5,
+				Holidays: []entity.PricingHoliday{{Date: "2026-03-07", Multiplier: 2}}},
+			start: saturday, end: saturday.Add(2 * time.Hour),
+			want: 4000, wantLines: map[string]entity.Money{"holiday": 2000, "weekend": 0},
+		},
+		{
+			name:  "season",
+			rules: entity.PricingRules{Seasons: []entity.PricingSeason{{Name: "spring", From: "03-01", To: "03-31", Multiplier: 1.1}}},
+			start: monday, end: monday.Add(2 * time.Hour),
+			want: 2200, wantLines: map[string]entity.Money{"seasonal": 200},
+		},
+		{
+			name:  "season wrapping the new year",
+			rules: entity.PricingRules{Seasons: []entity.PricingSeason{{Name: "winter", From: "12-01", To: "03-15", Multiplier: 1.2}}},
+			start: monday, end: monday.Add(time.Hour),
+			want: 1200, wantLines: map[string]entity.Money{"seasonal": 200},
+		},
+		{
+			name: "largest matching duration tier",
+			rules: entity.PricingRules{Tiers: []entity.PricingTier{
+				{Name: "daily", MinHours: 24, Multiplier: 0.8}, {Name: "weekly", MinHours: 168, Multiplier: 0.6}}},
+			start: monday, end: monday.Add(24 * time.Hour),
+			want: 19200, wantLines: map[string]entity.Money{"duration_tier": -4800},
+		},
+		{
+			name:  "rating modifier",
+			rules: entity.PricingRules{RatingModifiers: []entity.RatingModifier{{Below: &below3, Multiplier: 1.2}}},
+			start: monday, end: monday.Add(time.Hour), rating: 2,
+			want: 1200, wantLines: map[string]entity.Money{"rating": 200},
+		},
+		{
+			name:  "rating modifier not matching",
+			rules: entity.PricingRules{RatingModifiers: []entity.RatingModifier{{Below: &below3, Multiplier: 1.2}}},
+			start: monday, end: monday.Add(time.Hour), rating: 4.5,
+			want: 1000,
+		},
+		{
+			// The car multiplier replaces the category one, the category rate stays.
+			name: "car override on top of category override",
+			rules: entity.PricingRules{Overrides: []entity.PricingOverride{
+				{CarID: 7, Multiplier: &carMultiplier},
+				{Category: entity.CarCategoryBusiness, HourlyRate: &categoryRate, Multiplier: &categoryMultiplier}}},
+			start: monday, end: monday.Add(time.Hour),
+			want: 4000,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b, err := CalculatePrice(entity.PricingRuleSet{Rules: tt.rules}, car, tt.start, tt.end, tt.rating, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if b.Total != tt.want {
+				t.Errorf("total = %s, want %s", b.Total, tt.want)
+			}
+			for code, want := range tt.wantLines {
+				if got := priceLineAmount(b, code); got != want {
+					t.Errorf("%s = %s, want %s", code, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestCalculatePriceOptions(t *testing.T) {
+	start := time.Date(2026, 3, 2, 10, 0, 0, 0, time.UTC)
+	car := entity.Car{Category: entity.CarCategoryEconomy, PricePerHour: 1000}
+	rules := entity.PricingRules{Extras: []entity.PricingExtra{
+		{Code: "child_seat", Name: "Child seat", Fee: 500, PerDay: true},
+		{Code: "cleaning", Name: "Cleaning", Fee: 300},
+	}}
+	set := entity.PricingRuleSet{Rules: rules}
+
+	// 25 hours start a second day, an option picked twice is charged once.
+	b, err := CalculatePrice(set, car, start, start.Add(25*time.Hour), 0, []string{"child_seat", " Cleaning", "child_seat"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := priceLineAmount(b, "child_seat"); got != 1000 {
+		t.Errorf("child_seat = %s, want 10.00", got)
+	}
+	if got := priceLineAmount(b, "cleaning"); got != 300 {
+		t.Errorf("cleaning = %s, want 3.00", got)
+	}
+	if b.Total != 25000+1000+300 {
+		t.Errorf("total = %s, want 263.00", b.Total)
+	}
+
+	if _, err := CalculatePrice(set, car, start, start.Add(time.Hour), 0, []string{"gps"}); !errors.Is(err, errUnknownOption) {
+		t.Errorf("unknown option: err = %v, want errUnknownOption", err)
+	}
+}
+
+func TestValidateRentalWindow(t *testing.T) {
+	now := time.Date(2026, 3, 2, 10, 0, 0, 0, time.UTC)
+	start := now.Add(time.Hour)
+
+	tests := []struct {
+		name       string
+		start, end time.Time
+		wantOK     bool
+	}{
+		{"one hour", start, start.Add(time.Hour), true},
+		{"in the past", now.Add(-time.Minute), start, false},
+		{"under an hour", start, start.Add(59 * time.Minute), false},
+		{"90 days", start, start.Add(maxRentalDuration), true},
+		{"over 90 days", start, start.Add(maxRentalDuration + time.Minute), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			msg := validateRentalWindow(RentalRequest{StartDate: tt.start, EndDate: tt.end}, now)
+			if (msg == "") != tt.wantOK {
+				t.Errorf("validateRentalWindow = %q, want ok = %v", msg, tt.wantOK)
+			}
+		})
+	}
+}
+
+// priceLineAmount finds a surcharge, tier, rating or fee line by its code.
+func priceLineAmount(b entity.PriceBreakdown, code string) entity.Money {
+	lines := append(append([]entity.PriceLine{}, b.Surcharges...), b.Fees...)
+	if b.DurationTier != nil {
+		lines = append(lines, *b.DurationTier)
+	}
+	if b.RatingModifier != nil {
+		lines = append(lines, *b.RatingModifier)
+	}
+	for _, line := range lines {
+		if line.Code == code {
+			return line.Amount
+		}
+	}
+	return 0
+}
@@ -0,0 +1,189 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/CMPNION/Car-Rental-API.git/internal/entity"
+	"github.com/CMPNION/Car-Rental-API.git/internal/usecase/rentalstate"
+	"gorm.io/gorm"
+)
+
+// maxSimulatedRentals bounds one simulation run.
+const maxSimulatedRentals = 1000
+
+// pricingSimulationRequest is the payload of POST /api/v1/admin/pricing/simulate.
+// Rules, when given, are simulated as a draft; otherwise Version, or the
+// version in effect now.
+type pricingSimulationRequest struct {
+	From     time.Time            `json:"from"`
+	To       time.Time            `json:"to"`
+	Category string               `json:"category"`
+	Version  uint                 `json:"version"`
+	Rules    *entity.PricingRules `json:"rules"`
+}
+
+type pricingSimulationRow struct {
+	RentalID       uint                  `json:"rental_id"`
+	CarID          uint                  `json:"car_id"`
+	Category       string                `json:"category"`
+	StartDate      time.Time             `json:"start_date"`
+	EndDate        time.Time             `json:"end_date"`
+	ActualPrice    entity.Money          `json:"actual_price"`
+	SimulatedPrice entity.Money          `json:"simulated_price"`
+	Difference     entity.Money          `json:"difference"`
+	Demand         *entity.DemandPricing `json:"demand,omitempty"`
+}
+
+type pricingSimulation struct {
+	PricingVersion   uint                   `json:"pricing_version,omitempty"`
+	Rentals          int                    `json:"rentals"`
+	Truncated        bool                   `json:"truncated"`
+	ActualRevenue    entity.Money           `json:"actual_revenue"`
+	SimulatedRevenue entity.Money           `json:"simulated_revenue"`
+	Difference       entity.Money           `json:"difference"`
+	Rows             []pricingSimulationRow `json:"rows"`
+}
+
+// simulatePricingHandler handles POST /api/v1/admin/pricing/simulate (admin).
+// It re-prices the rentals that started in [from, to) with the given rules and
+// compares the result with what was charged, tax included. Utilization is
+// measured against all bookings known now, promo codes are not replayed and
+// the renter's current rating is used.
+func (s *Server) simulatePricingHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		RespondWithError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	var req pricingSimulationRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		RespondWithError(w, http.StatusBadRequest, "invalid JSON")
+		return
+	}
+	if req.From.IsZero() || req.To.IsZero() || !req.To.After(req.From) {
+		RespondWithError(w, http.StatusBadRequest, "from and to are required and to must be after from")
+		return
+	}
+	req.Category = strings.ToLower(strings.TrimSpace(req.Category))
+
+	var set entity.PricingRuleSet
+	switch {
+	case req.Rules != nil:
+		if msg := validatePricingRules(s.db, *req.Rules); msg != "" {
+			RespondWithError(w, http.StatusBadRequest, msg)
+			return
+		}
+		set.Rules = *req.Rules
+	case req.Version != 0:
+		if err := s.db.Where("version = ?", req.Version).First(&set).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				RespondWithError(w, http.StatusNotFound, "pricing rules version not found")
+				return
+			}
+			RespondWithError(w, http.StatusInternalServerError, "database error")
+			return
+		}
+	default:
+		var err error
+		if set, err = activePricingRules(s.db, time.Now()); err != nil {
+			if errors.Is(err, errNoPricingRules) {
+				RespondWithError(w, http.StatusNotFound, "no pricing rules in effect")
+				return
+			}
+			RespondWithError(w, http.StatusInternalServerError, "database error")
+			return
+		}
+	}
+
+	q := s.db.Model(&entity.Rental{}).
+		Joins("JOIN cars ON cars.id = rentals.car_id").
+		Where("rentals.status NOT IN ? AND rentals.start_date >= ? AND rentals.start_date < ?",
+			rentalstate.Released, req.From.UTC(), req.To.UTC())
+	if req.Category != "" {
+		q = q.Where("cars.category = ?", req.Category)
+	}
+	var rentals []entity.Rental
+	if err := q.Order("rentals.start_date asc").Limit(maxSimulatedRentals + 1).Find(&rentals).Error; err != nil {
+		RespondWithError(w, http.StatusInternalServerError, "database error")
+		return
+	}
+
+	result := pricingSimulation{PricingVersion: set.Version, Rows: []pricingSimulationRow{}}
+	if len(rentals) > maxSimulatedRentals {
+		rentals = rentals[:maxSimulatedRentals]
+		result.Truncated = true
+	}
+	cars := map[uint]entity.Car{}
+	ratings := map[uint]float64{}
+	for _, rental := range rentals {
+		car, ok := cars[rental.CarID]
+		if !ok {
+			if err := s.db.Unscoped().First(&car, rental.CarID).Error; err != nil {
+				RespondWithError(w, http.StatusInternalServerError, "database error")
+				return
+			}
+			cars[rental.CarID] = car
+		}
+		rating, ok := ratings[rental.UserID]
+		if !ok {
+			var user entity.User
+			if err := s.db.Select("id", "rating").First(&user, rental.UserID).Error; err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+				RespondWithError(w, http.StatusInternalServerError, "database error")
+				return
+			}
+			rating = user.Rating
+			ratings[rental.UserID] = rating
+		}
+
+		b, err := CalculatePrice(set, car, rental.StartDate, rental.EndDate, rating, simulatedOptions(set.Rules, rental))
+		if err != nil {
+			RespondWithError(w, http.StatusInternalServerError, "pricing error")
+			return
+		}
+		if err := applyDemandPricing(s.db, &b, set.Rules, car, rental.StartDate, rental.EndDate, rental.ID); err != nil {
+			RespondWithError(w, http.StatusInternalServerError, "database error")
+			return
+		}
+		if err := applyTaxes(s.db, &b, car, rental.CreatedAt); err != nil {
+			RespondWithError(w, http.StatusInternalServerError, "database error")
+			return
+		}
+
+		result.Rows = append(result.Rows, pricingSimulationRow{
+			RentalID:       rental.ID,
+			CarID:          car.ID,
+			Category:       car.Category,
+			StartDate:      rental.StartDate,
+			EndDate:        rental.EndDate,
+			ActualPrice:    rental.TotalPrice,
+			SimulatedPrice: b.Total,
+			Difference:     b.Total - rental.TotalPrice,
+			Demand:         b.Demand,
+		})
+		result.ActualRevenue += rental.TotalPrice
+		result.SimulatedRevenue += b.Total
+	}
+	result.Rentals = len(result.Rows)
+	result.Difference = result.SimulatedRevenue - result.ActualRevenue
+
+	RespondWithJSON(w, http.StatusOK, result)
+}
+
+// simulatedOptions replays the options a rental was booked with, skipping
+// those the simulated rules no longer offer.
+func simulatedOptions(rules entity.PricingRules, rental entity.Rental) []string {
+	if rental.PriceBreakdown == nil {
+		return nil
+	}
+	var options []string
+	for _, fee := range rental.PriceBreakdown.Fees {
+		if _, ok := findPricingExtra(rules, fee.Code); ok {
+			options = append(options, fee.Code)
+		}
+	}
+	return options
+}
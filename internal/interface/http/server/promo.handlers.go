@@ -0,0 +1,291 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/CMPNION/Car-Rental-API.git/internal/entity"
+	"gorm.io/gorm"
+)
+
+var errPromoCodeExists = errors.New("promo code already exists")
+
+var promoCodePattern = regexp.MustCompile(`^[A-Z0-9][A-Z0-9_-]{2,31}$`)
+
+// promoCodeRequest is the payload of POST/PUT /api/v1/admin/promo-codes.
+type promoCodeRequest struct {
+	Code            *string       `json:"code"`
+	Description     *string       `json:"description"`
+	DiscountType    *string       `json:"discount_type"`
+	DiscountValue   *entity.Money `json:"discount_value"`
+	DiscountPercent *int          `json:"discount_percent"`
+	ValidFrom       *time.Time    `json:"valid_from"`
+	ValidUntil      *time.Time    `json:"valid_until"`
+	MaxUses         *int          `json:"max_uses"`
+	MaxUsesPerUser  *int          `json:"max_uses_per_user"`
+	MinHours        *float64      `json:"min_hours"`
+	Categories      *[]string     `json:"categories"`
+	FirstRentalOnly *bool         `json:"first_rental_only"`
+	Active          *bool         `json:"active"`
+}
+
+// promoCodesHandler handles /api/v1/admin/promo-codes[/{id}[/redemptions]] (admin)
+func (s *Server) promoCodesHandler(w http.ResponseWriter, r *http.Request) {
+	rest := strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/v1/admin/promo-codes"), "/")
+
+	if rest == "" {
+		switch r.Method {
+		case http.MethodGet:
+			s.listPromoCodes(w, r)
+		case http.MethodPost:
+			s.createPromoCode(w, r)
+		default:
+			RespondWithError(w, http.StatusMethodNotAllowed, "method not allowed")
+		}
+		return
+	}
+
+	parts := strings.Split(rest, "/")
+	id, err := strconv.Atoi(parts[0])
+	if err != nil || id <= 0 || len(parts) > 2 {
+		RespondWithError(w, http.StatusBadRequest, "invalid id")
+		return
+	}
+
+	if len(parts) == 2 {
+		if parts[1] != "redemptions" {
+			RespondWithError(w, http.StatusNotFound, "not found")
+			return
+		}
+		if r.Method != http.MethodGet {
+			RespondWithError(w, http.StatusMethodNotAllowed, "method not allowed")
+			return
+		}
+		s.listPromoRedemptions(w, r, uint(id))
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		var promo entity.PromoCode
+		if err := s.db.First(&promo, id).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				RespondWithError(w, http.StatusNotFound, "promo code not found")
+				return
+			}
+			RespondWithError(w, http.StatusInternalServerError, "database error")
+			return
+		}
+		RespondWithJSON(w, http.StatusOK, promo)
+	case http.MethodPut:
+		s.updatePromoCode(w, r, uint(id))
+	case http.MethodDelete:
+		res := s.db.Delete(&entity.PromoCode{}, id)
+		if res.Error != nil {
+			RespondWithError(w, http.StatusInternalServerError, "database error")
+			return
+		}
+		if res.RowsAffected == 0 {
+			RespondWithError(w, http.StatusNotFound, "promo code not found")
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		RespondWithError(w, http.StatusMethodNotAllowed, "method not allowed")
+	}
+}
+
+func (s *Server) listPromoCodes(w http.ResponseWriter, r *http.Request) {
+	page, err := parsePageRequest(r)
+	if err != nil {
+		RespondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	q := s.db.Model(&entity.PromoCode{})
+	if v := r.URL.Query().Get("active"); v != "" {
+		active, err := strconv.ParseBool(v)
+		if err != nil {
+			RespondWithError(w, http.StatusBadRequest, "invalid active")
+			return
+		}
+		q = q.Where("active = ?", active)
+	}
+	result, err := paginate(q, page, newestFirst, func(promo entity.PromoCode) (any, uint) {
+		return promo.ID, promo.ID
+	})
+	respondWithPage(w, result, err)
+}
+
+func (s *Server) listPromoRedemptions(w http.ResponseWriter, r *http.Request, promoID uint) {
+	page, err := parsePageRequest(r)
+	if err != nil {
+		RespondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	q := s.db.Model(&entity.PromoRedemption{}).Where("promo_code_id = ?", promoID)
+	result, err := paginate(q, page, newestFirst, func(redemption entity.PromoRedemption) (any, uint) {
+		return redemption.ID, redemption.ID
+	})
+	respondWithPage(w, result, err)
+}
+
+func (s *Server) createPromoCode(w http.ResponseWriter, r *http.Request) {
+	var req promoCodeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		RespondWithError(w, http.StatusBadRequest, "invalid JSON")
+		return
+	}
+	if req.Code == nil || req.DiscountType == nil || (req.DiscountValue == nil && req.DiscountPercent == nil) {
+		RespondWithError(w, http.StatusBadRequest, "code, discount_type and discount_value or discount_percent are required")
+		return
+	}
+
+	promo := entity.PromoCode{Code: normalizePromoCode(*req.Code), Categories: []string{}, Active: true}
+	if !promoCodePattern.MatchString(promo.Code) {
+		RespondWithError(w, http.StatusBadRequest, "code must be 3-32 characters of A-Z, 0-9, '_' or '-'")
+		return
+	}
+
+	var msg string
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if msg = applyPromoCode(tx, &promo, req); msg != "" {
+			return nil
+		}
+		var existing int64
+		if err := tx.Unscoped().Model(&entity.PromoCode{}).
+			Where("code = ?", promo.Code).Count(&existing).Error; err != nil {
+			return err
+		}
+		if existing > 0 {
+			return errPromoCodeExists
+		}
+		return tx.Create(&promo).Error
+	})
+	if err != nil {
+		if errors.Is(err, errPromoCodeExists) {
+			RespondWithError(w, http.StatusConflict, "promo code already exists")
+			return
+		}
+		RespondWithError(w, http.StatusInternalServerError, "database error")
+		return
+	}
+	if msg != "" {
+		RespondWithError(w, http.StatusBadRequest, msg)
+		return
+	}
+	RespondWithJSON(w, http.StatusCreated, promo)
+}
+
+// updatePromoCode changes everything but the code itself and its usage count.
+func (s *Server) updatePromoCode(w http.ResponseWriter, r *http.Request, id uint) {
+	var req promoCodeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		RespondWithError(w, http.StatusBadRequest, "invalid JSON")
+		return
+	}
+
+	var promo entity.PromoCode
+	var msg string
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.First(&promo, id).Error; err != nil {
+			return err
+		}
+		if req.Code != nil && normalizePromoCode(*req.Code) != promo.Code {
+			msg = "code cannot be changed"
+			return nil
+		}
+		if msg = applyPromoCode(tx, &promo, req); msg != "" {
+			return nil
+		}
+		return tx.Save(&promo).Error
+	})
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			RespondWithError(w, http.StatusNotFound, "promo code not found")
+			return
+		}
+		RespondWithError(w, http.StatusInternalServerError, "database error")
+		return
+	}
+	if msg != "" {
+		RespondWithError(w, http.StatusBadRequest, msg)
+		return
+	}
+	RespondWithJSON(w, http.StatusOK, promo)
+}
+
+// applyPromoCode copies the provided fields onto promo and validates the result.
+func applyPromoCode(db *gorm.DB, promo *entity.PromoCode, req promoCodeRequest) string {
+	if req.Description != nil {
+		promo.Description = strings.TrimSpace(*req.Description)
+	}
+	if req.DiscountType != nil {
+		promo.DiscountType = strings.ToLower(strings.TrimSpace(*req.DiscountType))
+	}
+	if req.DiscountValue != nil {
+		promo.DiscountValue = *req.DiscountValue
+	}
+	if req.DiscountPercent != nil {
+		promo.DiscountPercent = *req.DiscountPercent
+	}
+	if req.ValidFrom != nil {
+		v := req.ValidFrom.UTC()
+		promo.ValidFrom = &v
+	}
+	if req.ValidUntil != nil {
+		v := req.ValidUntil.UTC()
+		promo.ValidUntil = &v
+	}
+	if req.MaxUses != nil {
+		promo.MaxUses = *req.MaxUses
+	}
+	if req.MaxUsesPerUser != nil {
+		promo.MaxUsesPerUser = *req.MaxUsesPerUser
+	}
+	if req.MinHours != nil {
+		promo.MinHours = *req.MinHours
+	}
+	if req.Categories != nil {
+		promo.Categories = []string{}
+		for _, code := range *req.Categories {
+			code = strings.ToLower(strings.TrimSpace(code))
+			if _, err := findCarCategory(db, code); err != nil {
+				return "unknown category " + strconv.Quote(code)
+			}
+			promo.Categories = append(promo.Categories, code)
+		}
+	}
+	if req.FirstRentalOnly != nil {
+		promo.FirstRentalOnly = *req.FirstRentalOnly
+	}
+	if req.Active != nil {
+		promo.Active = *req.Active
+	}
+
+	switch {
+	case promo.DiscountType != entity.PromoDiscountPercent && promo.DiscountType != entity.PromoDiscountFixed:
+		return "discount_type must be percent or fixed"
+	case promo.DiscountType == entity.PromoDiscountPercent && promo.DiscountValue != 0:
+		return "a percent discount takes discount_percent, not discount_value"
+	case promo.DiscountType == entity.PromoDiscountPercent && (promo.DiscountPercent <= 0 || promo.DiscountPercent > promoPercentScale):
+		return "discount_percent must be between 1 and 10000 basis points"
+	case promo.DiscountType == entity.PromoDiscountFixed && promo.DiscountPercent != 0:
+		return "a fixed discount takes discount_value, not discount_percent"
+	case promo.DiscountType == entity.PromoDiscountFixed && promo.DiscountValue <= 0:
+		return "discount_value must be > 0"
+	case promo.ValidFrom != nil && promo.ValidUntil != nil && !promo.ValidUntil.After(*promo.ValidFrom):
+		return "valid_until must be after valid_from"
+	case promo.MaxUses < 0 || promo.MaxUsesPerUser < 0:
+		return "usage limits must be >= 0"
+	case promo.MinHours < 0:
+		return "min_hours must be >= 0"
+	}
+	return ""
+}
@@ -0,0 +1,100 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/CMPNION/Car-Rental-API.git/internal/entity"
+	"github.com/CMPNION/Car-Rental-API.git/internal/infra/database"
+)
+
+func TestCreatePromoCodeDiscountFields(t *testing.T) {
+	s := newTestServer(t)
+
+	tests := []struct {
+		name, body string
+		want       int
+	}{
+		{"percent", `{"code":"PCT10","discount_type":"percent","discount_percent":1000}`, http.StatusCreated},
+		{"fixed", `{"code":"FIX500","discount_type":"fixed","discount_value":500}`, http.StatusCreated},
+		{"percent with both", `{"code":"PCTBOTH","discount_type":"percent","discount_percent":1000,"discount_value":10}`, http.StatusBadRequest},
+		{"percent as value", `{"code":"PCTVAL","discount_type":"percent","discount_value":10}`, http.StatusBadRequest},
+		{"percent over 100", `{"code":"PCTBIG","discount_type":"percent","discount_percent":10001}`, http.StatusBadRequest},
+		{"fixed with both", `{"code":"FIXBOTH","discount_type":"fixed","discount_value":500,"discount_percent":1000}`, http.StatusBadRequest},
+		{"fixed as percent", `{"code":"FIXPCT","discount_type":"fixed","discount_percent":1000}`, http.StatusBadRequest},
+		{"no discount", `{"code":"NONE","discount_type":"fixed"}`, http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := asAdmin(t, s, httptest.NewRequest(http.MethodPost, "/api/v1/admin/promo-codes", strings.NewReader(tt.body)))
+			rec := httptest.NewRecorder()
+			s.router.ServeHTTP(rec, req)
+			if rec.Code != tt.want {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.want, rec.Body)
+			}
+		})
+	}
+
+	var promo entity.PromoCode
+	if err := s.db.Where("code = ?", "PCT10").First(&promo).Error; err != nil {
+		t.Fatal(err)
+	}
+	if promo.DiscountPercent != 1000 || promo.DiscountValue != 0 {
+		t.Fatalf("PCT10 stored percent %d, value %v; want 1000 and 0", promo.DiscountPercent, promo.DiscountValue)
+	}
+}
+
+func TestApplyPromoDiscount(t *testing.T) {
+	tests := []struct {
+		name  string
+		promo entity.PromoCode
+		want  entity.Money
+	}{
+		{"percent", entity.PromoCode{DiscountType: entity.PromoDiscountPercent, DiscountPercent: 1000}, -1000},
+		{"fractional percent", entity.PromoCode{DiscountType: entity.PromoDiscountPercent, DiscountPercent: 1250}, -1250},
+		{"fixed", entity.PromoCode{DiscountType: entity.PromoDiscountFixed, DiscountValue: 3000}, -3000},
+		{"fixed above the price", entity.PromoCode{DiscountType: entity.PromoDiscountFixed, DiscountValue: 50000}, -10000},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := entity.PriceBreakdown{Base: 10000, Fees: []entity.PriceLine{{Code: "fee", Amount: 500}}}
+			applyPromoDiscount(&b, tt.promo)
+			if len(b.Discounts) != 1 || b.Discounts[0].Amount != tt.want {
+				t.Fatalf("discounts = %+v, want one line of %v", b.Discounts, tt.want)
+			}
+			if want := 10000 + 500 + tt.want; b.Total != want {
+				t.Fatalf("total = %v, want %v", b.Total, want)
+			}
+		})
+	}
+}
+
+func TestPercentPromoMovesToDiscountPercent(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.db")
+	db := database.InitDB(path)
+
+	// A percent promo saved before discount_percent existed kept its
+	// hundredths of a percent in discount_value.
+	if err := db.Exec(`INSERT INTO promo_codes (code, discount_type, discount_value, discount_percent, categories, active)
+		VALUES ('OLD10', 'percent', 1000, 0, '[]', true), ('OLD500', 'fixed', 500, 0, '[]', true)`).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	db = database.InitDB(path)
+	var promos []entity.PromoCode
+	if err := db.Order("code").Find(&promos).Error; err != nil {
+		t.Fatal(err)
+	}
+	if len(promos) != 2 {
+		t.Fatalf("got %d promos, want 2", len(promos))
+	}
+	if p := promos[0]; p.Code != "OLD10" || p.DiscountPercent != 1000 || p.DiscountValue != 0 {
+		t.Fatalf("percent promo = %s %d %v, want OLD10 1000 0", p.Code, p.DiscountPercent, p.DiscountValue)
+	}
+	if p := promos[1]; p.Code != "OLD500" || p.DiscountPercent != 0 || p.DiscountValue != 500 {
+		t.Fatalf("fixed promo = %s %d %v, want OLD500 0 500", p.Code, p.DiscountPercent, p.DiscountValue)
+	}
+}
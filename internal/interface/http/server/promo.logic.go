@@ -0,0 +1,145 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/CMPNION/Car-Rental-API.git/internal/entity"
+	"github.com/CMPNION/Car-Rental-API.git/internal/usecase/rentalstate"
+	"gorm.io/gorm"
+)
+
+var errPromoRejected = errors.New("promo code rejected")
+
+func normalizePromoCode(code string) string {
+	return strings.ToUpper(strings.TrimSpace(code))
+}
+
+// checkPromoCode loads a promo code and checks that it applies to this booking.
+// The returned error wraps errPromoRejected with a reason the renter can act on.
+func checkPromoCode(db *gorm.DB, code string, user entity.User, car entity.Car, req RentalRequest, now time.Time) (entity.PromoCode, error) {
+	var promo entity.PromoCode
+	if err := db.Where("code = ?", normalizePromoCode(code)).First(&promo).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return promo, fmt.Errorf("%w: unknown code", errPromoRejected)
+		}
+		return promo, err
+	}
+
+	reject := func(reason string) (entity.PromoCode, error) {
+		return promo, fmt.Errorf("%w: %s", errPromoRejected, reason)
+	}
+	switch {
+	case !promo.Active:
+		return reject("code is not active")
+	case promo.ValidFrom != nil && now.Before(*promo.ValidFrom):
+		return reject("code is not valid yet")
+	case promo.ValidUntil != nil && !now.Before(*promo.ValidUntil):
+		return reject("code has expired")
+	case promo.MaxUses > 0 && promo.UsedCount >= promo.MaxUses:
+		return reject("code has been fully redeemed")
+	case req.EndDate.Sub(req.StartDate).Hours() < promo.MinHours:
+		return reject(fmt.Sprintf("code requires a rental of at least %g hours", promo.MinHours))
+	case len(promo.Categories) > 0 && !slices.Contains(promo.Categories, car.Category):
+		return reject("code does not apply to this car category")
+	}
+
+	if promo.MaxUsesPerUser > 0 {
+		var used int64
+		if err := db.Model(&entity.PromoRedemption{}).
+			Where("promo_code_id = ? AND user_id = ?", promo.ID, user.ID).
+			Count(&used).Error; err != nil {
+			return promo, err
+		}
+		if used >= int64(promo.MaxUsesPerUser) {
+			return reject("you have already used this code")
+		}
+	}
+	if promo.FirstRentalOnly {
+		var rentals int64
+		if err := db.Model(&entity.Rental{}).
+			Where("user_id = ? AND status NOT IN ?", user.ID, rentalstate.Released).
+			Count(&rentals).Error; err != nil {
+			return promo, err
+		}
+		if rentals > 0 {
+			return reject("code is only valid for a first rental")
+		}
+	}
+	return promo, nil
+}
+
+// promoPercentScale is 100% in the basis points of PromoCode.DiscountPercent.
+const promoPercentScale = 10000
+
+// applyPromoDiscount adds the promo line to the breakdown. The discount never
+// exceeds the price of the rental itself, so fees are always paid.
+func applyPromoDiscount(b *entity.PriceBreakdown, promo entity.PromoCode) {
+	subtotal := rentalSubtotal(*b)
+	amount := promo.DiscountValue
+	if promo.DiscountType == entity.PromoDiscountPercent {
+		amount = subtotal.Mul(float64(promo.DiscountPercent) / promoPercentScale)
+	}
+	amount = min(amount, subtotal)
+	if amount > 0 {
+		b.Discounts = append(b.Discounts, entity.PriceLine{Code: "promo", Name: promo.Code, Amount: -amount})
+	}
+	totalPriceBreakdown(b)
+}
+
+// redeemPromoCode counts one use of promo for the rental. It runs inside the
+// booking transaction; the conditional increment keeps the global limit even
+// when two bookings race for the last use.
+func redeemPromoCode(tx *gorm.DB, promo entity.PromoCode, rental entity.Rental) error {
+	res := tx.Model(&entity.PromoCode{}).
+		Where("id = ? AND (max_uses = 0 OR used_count < max_uses)", promo.ID).
+		Update("used_count", gorm.Expr("used_count + 1"))
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return fmt.Errorf("%w: code has been fully redeemed", errPromoRejected)
+	}
+
+	var amount entity.Money
+	if rental.PriceBreakdown != nil {
+		amount = promoDiscount(*rental.PriceBreakdown)
+	}
+	return tx.Create(&entity.PromoRedemption{
+		PromoCodeID: promo.ID,
+		UserID:      rental.UserID,
+		RentalID:    rental.ID,
+		Amount:      amount,
+	}).Error
+}
+
+// releasePromoRedemption gives the use of a promo code back when its rental
+// is cancelled before it started.
+func releasePromoRedemption(tx *gorm.DB, rentalID uint) error {
+	var redemption entity.PromoRedemption
+	if err := tx.Where("rental_id = ?", rentalID).First(&redemption).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil
+		}
+		return err
+	}
+	if err := tx.Model(&entity.PromoCode{}).
+		Where("id = ? AND used_count > 0", redemption.PromoCodeID).
+		Update("used_count", gorm.Expr("used_count - 1")).Error; err != nil {
+		return err
+	}
+	return tx.Delete(&redemption).Error
+}
+
+// promoDiscount is the amount of the promo line of a price.
+func promoDiscount(b entity.PriceBreakdown) entity.Money {
+	for _, line := range b.Discounts {
+		if line.Code == "promo" {
+			return -line.Amount
+		}
+	}
+	return 0
+}
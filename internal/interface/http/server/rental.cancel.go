@@ -0,0 +1,77 @@
+package server
+
+import (
+	"errors"
+	"time"
+
+	"github.com/CMPNION/Car-Rental-API.git/internal/entity"
+	"gorm.io/gorm"
+)
+
+// defaultCancellationPolicy applies to categories without a policy of their
+// own: a full refund 48 hours or more before the start, half of it later.
+var defaultCancellationPolicy = []entity.CancellationTier{
+	{MinHoursBefore: 48, RefundPercent: 100},
+	{MinHoursBefore: 0, RefundPercent: 50},
+}
+
+// cancellationRefund is what a cancellation paid back.
+type cancellationRefund struct {
+	Amount     entity.Money
+	Percent    float64
+	Overridden bool
+}
+
+// cancellationRefundPercent is the refund of the tier with the largest
+// MinHoursBefore that hoursBefore reaches; nothing when none does.
+func cancellationRefundPercent(policy []entity.CancellationTier, hoursBefore float64) float64 {
+	if len(policy) == 0 {
+		policy = defaultCancellationPolicy
+	}
+	best, percent := -1.0, 0.0
+	for _, tier := range policy {
+		if hoursBefore >= tier.MinHoursBefore && tier.MinHoursBefore > best {
+			best, percent = tier.MinHoursBefore, tier.RefundPercent
+		}
+	}
+	return percent
+}
+
+// refundCancellation pays back the share of a paid rental that the policy of
+// its car's category allows, or override percent of it. Redeemed loyalty
+// points only come back with a full refund; the deposit is released by the
+// cancel transition.
+func refundCancellation(tx *gorm.DB, rental *entity.Rental, override *float64, now time.Time) (cancellationRefund, error) {
+	var refund cancellationRefund
+	if override != nil {
+		refund.Percent, refund.Overridden = *override, true
+	} else {
+		var car entity.Car
+		if err := tx.Unscoped().First(&car, rental.CarID).Error; err != nil {
+			return refund, err
+		}
+		category, err := findCarCategory(tx, car.Category)
+		if err != nil && !errors.Is(err, errUnknownCategory) {
+			return refund, err
+		}
+		refund.Percent = cancellationRefundPercent(category.CancellationPolicy, rental.StartDate.Sub(now).Hours())
+	}
+
+	refund.Amount = rentalCashPaid(*rental).Mul(refund.Percent / 100)
+	if refund.Amount > 0 {
+		if err := refundRental(tx, *rental, refund.Amount); err != nil {
+			return refund, err
+		}
+		rental.Refunded += refund.Amount
+		if err := tx.Model(&entity.Rental{}).Where("id = ?", rental.ID).
+			Update("refunded", gorm.Expr("refunded + ?", refund.Amount)).Error; err != nil {
+			return refund, err
+		}
+	}
+	if refund.Percent == 100 {
+		if err := refundLoyaltyPoints(tx, *rental, now); err != nil {
+			return refund, err
+		}
+	}
+	return refund, nil
+}
@@ -0,0 +1,40 @@
+package server
+
+import (
+	"testing"
+
+	"github.com/CMPNION/Car-Rental-API.git/internal/entity"
+)
+
+func TestCancellationRefundPercent(t *testing.T) {
+	tiered := []entity.CancellationTier{
+		{MinHoursBefore: 0, RefundPercent: 0},
+		{MinHoursBefore: 72, RefundPercent: 100},
+		{MinHoursBefore: 24, RefundPercent: 50},
+	}
+	fromDay := []entity.CancellationTier{{MinHoursBefore: 24, RefundPercent: 80}}
+
+	tests := []struct {
+		name        string
+		policy      []entity.CancellationTier
+		hoursBefore float64
+		want        float64
+	}{
+		{"default, early", nil, 48, 100},
+		{"default, late", nil, 47.9, 50},
+		{"default, at the start", nil, 0, 50},
+		{"tiers in any order, top", tiered, 100, 100},
+		{"tiers in any order, at a boundary", tiered, 72, 100},
+		{"tiers in any order, middle", tiered, 30, 50},
+		{"tiers in any order, last", tiered, 23.5, 0},
+		{"no tier reached", fromDay, 12, 0},
+		{"single tier reached", fromDay, 24, 80},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := cancellationRefundPercent(tt.policy, tt.hoursBefore); got != tt.want {
+				t.Errorf("got %v%%, want %v%%", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,115 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"github.com/CMPNION/Car-Rental-API.git/internal/entity"
+	"github.com/CMPNION/Car-Rental-API.git/internal/usecase/rentalstate"
+	"gorm.io/gorm"
+)
+
+const (
+	// Defaults of RENTAL_PAYMENT_WINDOW and RENTAL_PAYMENT_CUTOFF.
+	defaultPaymentWindow = 30 * time.Minute
+	defaultPaymentCutoff = 15 * time.Minute
+	rentalExpiryTick     = time.Minute
+)
+
+var errPaymentWindowClosed = errors.New("payment window closed")
+
+// rentalExpiryConfig is how long a pending rental waits for its payment: at
+// most PaymentWindow after booking and until PaymentCutoff before pickup.
+type rentalExpiryConfig struct {
+	PaymentWindow time.Duration
+	PaymentCutoff time.Duration
+}
+
+// loadRentalExpiryConfig reads RENTAL_PAYMENT_WINDOW and RENTAL_PAYMENT_CUTOFF
+// (Go durations such as "45m"), falling back to the defaults.
+func loadRentalExpiryConfig() rentalExpiryConfig {
+	return rentalExpiryConfig{
+		PaymentWindow: envDuration("RENTAL_PAYMENT_WINDOW", defaultPaymentWindow),
+		PaymentCutoff: envDuration("RENTAL_PAYMENT_CUTOFF", defaultPaymentCutoff),
+	}
+}
+
+func envDuration(name string, fallback time.Duration) time.Duration {
+	v := os.Getenv(name)
+	if v == "" {
+		return fallback
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil || d < 0 {
+		log.Printf("%s=%q is not a valid duration, using %s", name, v, fallback)
+		return fallback
+	}
+	return d
+}
+
+// payBy is when a pending rental expires unless it is paid.
+func (c rentalExpiryConfig) payBy(rental entity.Rental) time.Time {
+	payBy := rental.CreatedAt.Add(c.PaymentWindow)
+	if cutoff := rental.StartDate.Add(-c.PaymentCutoff); cutoff.Before(payBy) {
+		payBy = cutoff
+	}
+	return payBy.UTC()
+}
+
+// runRentalExpiry periodically expires pending rentals whose payment window has closed.
+func (s *Server) runRentalExpiry() {
+	ticker := time.NewTicker(rentalExpiryTick)
+	defer ticker.Stop()
+
+	for {
+		if n, err := s.expireUnpaidRentals(time.Now()); err != nil {
+			log.Printf("rental expiry failed: %v", err)
+		} else if n > 0 {
+			log.Printf("expired %d unpaid rental(s)", n)
+		}
+		<-ticker.C
+	}
+}
+
+// expireUnpaidRentals expires every pending rental that is past its pay-by
+// time, each in its own transaction. A rental paid or cancelled by a request
+// in the meantime is left alone: the transition only applies to a rental
+// that is still pending. Both bounds are in UTC, the zone created_at and
+// start_date are stored in, as SQLite compares them as text.
+func (s *Server) expireUnpaidRentals(now time.Time) (int, error) {
+	cfg := s.rentalExpiry
+	var ids []uint
+	if err := s.db.Model(&entity.Rental{}).
+		Where("status = ? AND (created_at <= ? OR start_date <= ?)", entity.RentalStatusPending,
+			now.Add(-cfg.PaymentWindow).UTC(), now.Add(cfg.PaymentCutoff).UTC()).
+		Order("id asc").
+		Pluck("id", &ids).Error; err != nil {
+		return 0, err
+	}
+
+	expired := 0
+	for _, id := range ids {
+		err := s.db.Transaction(func(tx *gorm.DB) error {
+			var rental entity.Rental
+			if err := tx.First(&rental, id).Error; err != nil {
+				return err
+			}
+			reason := fmt.Sprintf("not paid within %s of booking", cfg.PaymentWindow)
+			if rental.CreatedAt.Add(cfg.PaymentWindow).After(now) {
+				reason = fmt.Sprintf("not paid %s before pickup", cfg.PaymentCutoff)
+			}
+			return s.rentals.Fire(tx, &rental, rentalstate.EventExpire, nil, reason)
+		})
+		if errors.Is(err, rentalstate.ErrInvalidTransition) || errors.Is(err, gorm.ErrRecordNotFound) {
+			continue
+		}
+		if err != nil {
+			return expired, err
+		}
+		expired++
+	}
+	return expired, nil
+}
@@ -0,0 +1,133 @@
+package server
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/CMPNION/Car-Rental-API.git/internal/entity"
+	"github.com/CMPNION/Car-Rental-API.git/internal/infra/database"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+func TestExpireUnpaidRentals(t *testing.T) {
+	// A zone east of UTC makes a bound in the wrong zone compare wrongly as text.
+	local := time.Local
+	time.Local = time.FixedZone("UTC+5", 5*60*60)
+	t.Cleanup(func() { time.Local = local })
+
+	s := newTestServer(t)
+	now := time.Now().UTC()
+
+	user := entity.User{FirstName: "U", LastName: "S", Email: "u@example.com", PasswordHash: "x",
+		Role: entity.UserRoleClient}
+	if err := s.db.Create(&user).Error; err != nil {
+		t.Fatal(err)
+	}
+	car := entity.Car{Mark: "Kia", CarModel: "Rio", Category: entity.CarCategoryEconomy,
+		Status: entity.CarStatusAvailable, PricePerHour: 1000}
+	if err := s.db.Create(&car).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	pending := func(bookedAgo, startsIn time.Duration) entity.Rental {
+		start := now.Add(startsIn)
+		r := entity.Rental{UserID: user.ID, CarID: car.ID, StartDate: start, EndDate: start.Add(4 * time.Hour),
+			TotalPrice: 4000, Status: entity.RentalStatusPending}
+		if bookedAgo > 0 {
+			r.CreatedAt = now.Add(-bookedAgo)
+		}
+		if err := s.db.Create(&r).Error; err != nil {
+			t.Fatal(err)
+		}
+		return r
+	}
+	waiting := pending(0, 2*time.Hour)
+	unpaid := pending(s.rentalExpiry.PaymentWindow+time.Minute, 2*time.Hour)
+	nearPickup := pending(0, s.rentalExpiry.PaymentCutoff-time.Minute)
+
+	n, err := s.expireUnpaidRentals(now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 2 {
+		t.Fatalf("expired %d rentals, want 2", n)
+	}
+
+	for _, tt := range []struct {
+		name   string
+		rental entity.Rental
+		want   string
+	}{
+		{"within the payment window", waiting, entity.RentalStatusPending},
+		{"past the payment window", unpaid, entity.RentalStatusExpired},
+		{"past the payment cutoff", nearPickup, entity.RentalStatusExpired},
+	} {
+		var got entity.Rental
+		if err := s.db.First(&got, tt.rental.ID).Error; err != nil {
+			t.Fatal(err)
+		}
+		if got.Status != tt.want {
+			t.Errorf("%s: status = %s, want %s", tt.name, got.Status, tt.want)
+		}
+	}
+}
+
+func TestExpireUnpaidRentalsBookedBeforeUTC(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.db")
+	db := database.InitDB(path).Session(&gorm.Session{Logger: logger.Discard})
+	now := time.Now().UTC()
+	zone := time.FixedZone("UTC+5", 5*60*60)
+
+	birthDate := time.Date(1990, 5, 1, 0, 0, 0, 0, zone)
+	user := entity.User{FirstName: "U", LastName: "S", Email: "u@example.com", PasswordHash: "x",
+		Role: entity.UserRoleClient, BirthDate: &birthDate}
+	if err := db.Create(&user).Error; err != nil {
+		t.Fatal(err)
+	}
+	car := entity.Car{Mark: "Kia", CarModel: "Rio", Category: entity.CarCategoryEconomy,
+		Status: entity.CarStatusAvailable, PricePerHour: 1000}
+	if err := db.Create(&car).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	// Before NowFunc switched to UTC, created_at was written in local time,
+	// which as text sorts after the UTC bound of the payment window.
+	start := now.Add(2 * time.Hour)
+	unpaid := entity.Rental{UserID: user.ID, CarID: car.ID, StartDate: start, EndDate: start.Add(4 * time.Hour),
+		TotalPrice: 4000, Status: entity.RentalStatusPending}
+	if err := db.Create(&unpaid).Error; err != nil {
+		t.Fatal(err)
+	}
+	bookedAt := now.Add(-time.Hour).In(zone)
+	if err := db.Exec("UPDATE rentals SET created_at = ? WHERE id = ?", bookedAt, unpaid.ID).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Exec("PRAGMA user_version = 0").Error; err != nil {
+		t.Fatal(err)
+	}
+
+	s := GetNewServer(":0", database.InitDB(path).Session(&gorm.Session{Logger: logger.Discard}))
+
+	var stored struct{ CreatedAt, BirthDate string }
+	if err := s.db.Raw(`SELECT CAST(r.created_at AS TEXT) AS created_at, CAST(u.birth_date AS TEXT) AS birth_date
+		FROM rentals r JOIN users u ON u.id = r.user_id WHERE r.id = ?`, unpaid.ID).Scan(&stored).Error; err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasSuffix(stored.CreatedAt, "+00:00") {
+		t.Errorf("created_at = %s, want it in UTC", stored.CreatedAt)
+	}
+	if !strings.HasPrefix(stored.BirthDate, "1990-05-01") {
+		t.Errorf("birth_date = %s, want the calendar date kept", stored.BirthDate)
+	}
+
+	n, err := s.expireUnpaidRentals(now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 1 {
+		t.Fatalf("expired %d rentals, want 1", n)
+	}
+}
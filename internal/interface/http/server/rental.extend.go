@@ -0,0 +1,199 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/CMPNION/Car-Rental-API.git/internal/entity"
+	authhttp "github.com/CMPNION/Car-Rental-API.git/internal/interface/http/auth"
+	"github.com/CMPNION/Car-Rental-API.git/internal/usecase/rentalstate"
+	"gorm.io/gorm"
+)
+
+var errNotExtendable = errors.New("rental cannot be extended")
+
+// extendableStatuses are the statuses of rentals whose end can still move.
+var extendableStatuses = []string{entity.RentalStatusPending, entity.RentalStatusConfirmed, entity.RentalStatusActive}
+
+// extendRentalRequest is the payload of POST /api/v1/rentals/{id}/extend.
+type extendRentalRequest struct {
+	EndDate time.Time `json:"end_date"`
+}
+
+// extendRental handles POST /api/v1/rentals/{id}/extend: it moves the end of
+// the rental if the car is free until the new end, prices the extra hours
+// with the current rules and settles them, all in one transaction.
+func (s *Server) extendRental(w http.ResponseWriter, r *http.Request, rentalID uint) {
+	userID, ok := authhttp.UserIDFromContext(r.Context())
+	if !ok {
+		RespondWithError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	var req extendRentalRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		RespondWithError(w, http.StatusBadRequest, "invalid JSON")
+		return
+	}
+	now := time.Now().UTC()
+	if !req.EndDate.After(now) {
+		RespondWithError(w, http.StatusBadRequest, "end_date must be in the future")
+		return
+	}
+
+	var rental entity.Rental
+	var extension entity.RentalExtension
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.First(&rental, rentalID).Error; err != nil {
+			return err
+		}
+		if getRoleFromContext(r) != entity.UserRoleAdmin && rental.UserID != userID {
+			return errors.New("forbidden")
+		}
+		if !slices.Contains(extendableStatuses, rental.Status) {
+			return errNotExtendable
+		}
+		if req.EndDate.Sub(rental.EndDate) < time.Hour {
+			return errors.New("too short")
+		}
+		if req.EndDate.Sub(rental.StartDate) > maxRentalDuration {
+			return errors.New("too long")
+		}
+
+		var car entity.Car
+		if err := tx.First(&car, rental.CarID).Error; err != nil {
+			return err
+		}
+		var user entity.User
+		if err := tx.First(&user, rental.UserID).Error; err != nil {
+			return err
+		}
+		category, err := findCarCategory(tx, car.Category)
+		if err != nil {
+			return err
+		}
+
+		available, err := checkAvailabilityWithDB(tx, rental.CarID, rental.EndDate, req.EndDate, rental.ID)
+		if err != nil {
+			return err
+		}
+		if !available {
+			return errors.New("car already booked")
+		}
+		if err := checkCarDocuments(tx, rental.CarID, req.EndDate.UTC()); err != nil {
+			return err
+		}
+
+		rules, err := activePricingRules(tx, now)
+		if err != nil {
+			return err
+		}
+		price, err := priceRental(tx, car, category, user, RentalRequest{
+			CarID:     rental.CarID,
+			StartDate: rental.EndDate,
+			EndDate:   req.EndDate,
+			Options:   perDayOptions(rules.Rules, rental.PriceBreakdown),
+		}, nil, rental.ID, now)
+		if err != nil {
+			return err
+		}
+		price.Deposit = 0
+
+		extension = entity.RentalExtension{
+			RentalID:       rental.ID,
+			PreviousEnd:    rental.EndDate.UTC(),
+			NewEnd:         req.EndDate.UTC(),
+			Amount:         price.Total,
+			PriceBreakdown: &price,
+			Settlement:     entity.RentalExtensionDue,
+			CreatedBy:      requestActor(r),
+		}
+
+		// A paid rental pays the extension now; an unpaid one owes it with the rest.
+		if slices.Contains(rentalstate.Paid, rental.Status) && price.Total > 0 {
+			res := tx.Model(&entity.User{}).
+				Where("id = ? AND balance >= ?", rental.UserID, price.Total).
+				Update("balance", gorm.Expr("balance - ?", price.Total))
+			if res.Error != nil {
+				return res.Error
+			}
+			if res.RowsAffected == 0 {
+				return errInsufficientBalance
+			}
+			transaction := rentalTransaction(rental, entity.TransactionTypePayment, price.Total)
+			if err := tx.Create(&transaction).Error; err != nil {
+				return err
+			}
+			extension.Settlement = entity.RentalExtensionCharged
+			extension.TransactionID = &transaction.ID
+		}
+
+		res := tx.Model(&entity.Rental{}).Where("id = ? AND status = ?", rental.ID, rental.Status).
+			Updates(map[string]any{
+				"end_date":          req.EndDate.UTC(),
+				"total_price":       gorm.Expr("total_price + ?", price.Total),
+				"net_price":         gorm.Expr("net_price + ?", price.Net),
+				"tax_amount":        gorm.Expr("tax_amount + ?", price.Tax),
+				"total_in_currency": gorm.Expr("total_in_currency + ?", fromBaseCurrency(price.Total, rentalExchangeRate(rental))),
+			})
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return errNotExtendable
+		}
+		if err := tx.Create(&extension).Error; err != nil {
+			return err
+		}
+		return tx.First(&rental, rental.ID).Error
+	})
+
+	if err != nil {
+		switch {
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			RespondWithError(w, http.StatusNotFound, "rental not found")
+		case err.Error() == "forbidden":
+			RespondWithError(w, http.StatusForbidden, "forbidden")
+		case errors.Is(err, errNotExtendable):
+			RespondWithError(w, http.StatusBadRequest, "only pending, confirmed or active rentals can be extended")
+		case err.Error() == "too short":
+			RespondWithError(w, http.StatusBadRequest, "end_date must be at least 1 hour after the current end")
+		case err.Error() == "too long":
+			RespondWithError(w, http.StatusBadRequest, "maximum rental duration is 90 days")
+		case err.Error() == "car already booked":
+			RespondWithError(w, http.StatusConflict, "car already booked for the extra time")
+		case errors.Is(err, errCarDocumentsExpired):
+			RespondWithError(w, http.StatusBadRequest, "car documents expire before the new end")
+		case errors.Is(err, errNoPricingRules):
+			RespondWithError(w, http.StatusServiceUnavailable, "pricing is not configured")
+		case errors.Is(err, errInsufficientBalance):
+			RespondWithError(w, http.StatusBadRequest, "insufficient balance for the extension")
+		default:
+			RespondWithError(w, http.StatusInternalServerError, "could not extend rental")
+		}
+		return
+	}
+
+	RespondWithJSON(w, http.StatusOK, map[string]any{
+		"rental_id":   rental.ID,
+		"status":      rental.Status,
+		"end_date":    rental.EndDate,
+		"total_price": rental.TotalPrice,
+		"extension":   extension,
+	})
+}
+
+// perDayOptions are the booked extras that are charged per day and are still
+// offered, so the extra days pay for them too; one-off extras are not charged again.
+func perDayOptions(rules entity.PricingRules, booked *entity.PriceBreakdown) []string {
+	var options []string
+	for _, code := range bookedOptions(rules, booked) {
+		if extra, _ := findPricingExtra(rules, code); extra.PerDay {
+			options = append(options, code)
+		}
+	}
+	return options
+}
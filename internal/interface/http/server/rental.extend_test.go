@@ -0,0 +1,169 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/CMPNION/Car-Rental-API.git/internal/entity"
+)
+
+// newRenter creates a client old enough for every category.
+func newRenter(t *testing.T, s *Server, balance entity.Money) entity.User {
+	t.Helper()
+	birthDate := time.Date(1990, 5, 17, 0, 0, 0, 0, time.UTC)
+	user := entity.User{FirstName: "U", LastName: "S", Email: "u@example.com", PasswordHash: "x",
+		Role: entity.UserRoleClient, Balance: balance, BirthDate: &birthDate}
+	if err := s.db.Create(&user).Error; err != nil {
+		t.Fatal(err)
+	}
+	return user
+}
+
+// bookRental books car for user through POST /api/v1/rentals, paying for it when paid is set.
+func bookRental(t *testing.T, s *Server, user entity.User, car entity.Car, start, end time.Time, paid bool) entity.Rental {
+	t.Helper()
+	body, _ := json.Marshal(RentalRequest{CarID: car.ID, StartDate: start, EndDate: end})
+	rec := httptest.NewRecorder()
+	s.createRental(rec, withUser(httptest.NewRequest(http.MethodPost, "/api/v1/rentals", strings.NewReader(string(body))),
+		user.ID, entity.UserRoleClient))
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("book: status %d: %s", rec.Code, rec.Body)
+	}
+	var resp struct {
+		Data struct {
+			RentalID uint `json:"rental_id"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+
+	if paid {
+		rec := rentalAction(s, user, resp.Data.RentalID, "pay", "")
+		if rec.Code != http.StatusOK {
+			t.Fatalf("pay: status %d: %s", rec.Code, rec.Body)
+		}
+	}
+	var rental entity.Rental
+	if err := s.db.First(&rental, resp.Data.RentalID).Error; err != nil {
+		t.Fatal(err)
+	}
+	return rental
+}
+
+// rentalAction posts body to /api/v1/rentals/{id}/{action} as user.
+func rentalAction(s *Server, user entity.User, rentalID uint, action, body string) *httptest.ResponseRecorder {
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, fmt.Sprintf("/api/v1/rentals/%d/%s", rentalID, action), strings.NewReader(body))
+	s.rentalActionHandler(rec, withUser(req, user.ID, entity.UserRoleClient))
+	return rec
+}
+
+func TestExtendRentalSettlement(t *testing.T) {
+	tests := []struct {
+		name           string
+		paid           bool
+		balance        entity.Money
+		wantCode       int
+		wantSettlement string
+	}{
+		{"paid rental pays the extension now", true, 100000, http.StatusOK, entity.RentalExtensionCharged},
+		{"unpaid rental owes it with the rest", false, 0, http.StatusOK, entity.RentalExtensionDue},
+		{"paid rental without the balance for it", true, 0, http.StatusBadRequest, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestServer(t)
+			car := entity.Car{Mark: "Kia", CarModel: "Rio", Category: entity.CarCategoryEconomy,
+				Status: entity.CarStatusAvailable, PricePerHour: 1000}
+			if err := s.db.Create(&car).Error; err != nil {
+				t.Fatal(err)
+			}
+			user := newRenter(t, s, 1000000)
+			start := time.Now().UTC().Add(48 * time.Hour).Truncate(time.Hour)
+			rental := bookRental(t, s, user, car, start, start.Add(4*time.Hour), tt.paid)
+			// Leave the balance the case wants once the booking is paid.
+			if err := s.db.Model(&user).Update("balance", tt.balance).Error; err != nil {
+				t.Fatal(err)
+			}
+
+			newEnd := rental.EndDate.Add(3 * time.Hour)
+			rec := rentalAction(s, user, rental.ID, "extend", fmt.Sprintf(`{"end_date":%q}`, newEnd.Format(time.RFC3339)))
+			if rec.Code != tt.wantCode {
+				t.Fatalf("extend: status %d, want %d: %s", rec.Code, tt.wantCode, rec.Body)
+			}
+
+			var extended entity.Rental
+			if err := s.db.First(&extended, rental.ID).Error; err != nil {
+				t.Fatal(err)
+			}
+			if err := s.db.First(&user, user.ID).Error; err != nil {
+				t.Fatal(err)
+			}
+			if tt.wantCode != http.StatusOK {
+				if !extended.EndDate.Equal(rental.EndDate) || extended.TotalPrice != rental.TotalPrice || user.Balance != tt.balance {
+					t.Errorf("failed extension changed the rental or the balance")
+				}
+				return
+			}
+
+			var resp struct {
+				Data struct {
+					Extension entity.RentalExtension `json:"extension"`
+				} `json:"data"`
+			}
+			if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+				t.Fatal(err)
+			}
+			extension := resp.Data.Extension
+			if extension.Amount <= 0 || extension.Settlement != tt.wantSettlement {
+				t.Fatalf("extension amount %s settled %q, want a positive amount settled %q",
+					extension.Amount, extension.Settlement, tt.wantSettlement)
+			}
+			if !extended.EndDate.Equal(newEnd) || extended.TotalPrice != rental.TotalPrice+extension.Amount {
+				t.Errorf("rental ends %s at %s, want %s at %s", extended.EndDate, extended.TotalPrice,
+					newEnd, rental.TotalPrice+extension.Amount)
+			}
+
+			if !tt.paid {
+				if user.Balance != tt.balance || extension.TransactionID != nil {
+					t.Errorf("unpaid extension took %s from the balance", tt.balance-user.Balance)
+				}
+				// Paying the booking now pays the extension too.
+				if err := s.db.Model(&user).Update("balance", 1000000).Error; err != nil {
+					t.Fatal(err)
+				}
+				if rec := rentalAction(s, user, rental.ID, "pay", ""); rec.Code != http.StatusOK {
+					t.Fatalf("pay: status %d: %s", rec.Code, rec.Body)
+				}
+				var payment entity.Transaction
+				if err := s.db.Where("rental_id = ? AND type = ?", rental.ID, entity.TransactionTypePayment).
+					First(&payment).Error; err != nil {
+					t.Fatal(err)
+				}
+				if payment.Amount != extended.TotalPrice {
+					t.Errorf("paid %s, want the extended total %s", payment.Amount, extended.TotalPrice)
+				}
+				return
+			}
+			if user.Balance != tt.balance-extension.Amount {
+				t.Errorf("balance %s, want %s less the extension %s", user.Balance, tt.balance, extension.Amount)
+			}
+			var payment entity.Transaction
+			if extension.TransactionID == nil {
+				t.Fatal("charged extension has no transaction")
+			}
+			if err := s.db.First(&payment, *extension.TransactionID).Error; err != nil {
+				t.Fatal(err)
+			}
+			if payment.Type != entity.TransactionTypePayment || payment.Amount != extension.Amount {
+				t.Errorf("transaction %s of %s, want a payment of %s", payment.Type, payment.Amount, extension.Amount)
+			}
+		})
+	}
+}
@@ -3,13 +3,17 @@ package server
 import (
 	"encoding/json"
 	"errors"
+	"fmt"
+	"io"
 	"net/http"
+	"slices"
 	"strconv"
 	"strings"
 	"time"
 
 	"github.com/CMPNION/Car-Rental-API.git/internal/entity"
 	authhttp "github.com/CMPNION/Car-Rental-API.git/internal/interface/http/auth"
+	"github.com/CMPNION/Car-Rental-API.git/internal/usecase/rentalstate"
 	"gorm.io/gorm"
 )
 
@@ -18,6 +22,9 @@ type RentalRequest struct {
 	CarID     uint      `json:"car_id"`
 	StartDate time.Time `json:"start_date"`
 	EndDate   time.Time `json:"end_date"`
+	// Options are codes of PricingExtra add-ons, e.g. "child_seat".
+	Options   []string `json:"options,omitempty"`
+	PromoCode string   `json:"promo_code,omitempty"`
 }
 
 // rentalsHandler handles GET/POST /api/v1/rentals
@@ -32,26 +39,56 @@ func (s *Server) rentalsHandler(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
-// rentalActionHandler handles POST /api/v1/rentals/{id}/pay|finish|cancel
+// rentalActionHandler handles POST /api/v1/rentals/{id}/pay|finish|cancel|extend|modify|invoice, GET /api/v1/rentals/{id}/track|history
+// and GET|POST /api/v1/rentals/{id}/charges
 func (s *Server) rentalActionHandler(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodPost {
-		RespondWithError(w, http.StatusMethodNotAllowed, "method not allowed")
-		return
-	}
-
 	id, action, err := parseRentalAction(r.URL.Path)
 	if err != nil {
 		RespondWithError(w, http.StatusBadRequest, "invalid rental path")
 		return
 	}
 
+	if action == "track" {
+		if r.Method != http.MethodGet {
+			RespondWithError(w, http.StatusMethodNotAllowed, "method not allowed")
+			return
+		}
+		s.rentalTrack(w, r, id)
+		return
+	}
+	if action == "history" {
+		if r.Method != http.MethodGet {
+			RespondWithError(w, http.StatusMethodNotAllowed, "method not allowed")
+			return
+		}
+		s.rentalHistory(w, r, id)
+		return
+	}
+	if action == "charges" {
+		s.rentalChargesHandler(w, r, id)
+		return
+	}
+
+	if r.Method != http.MethodPost {
+		RespondWithError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
 	switch action {
 	case "pay":
 		s.payRental(w, r, id)
+	case "pickup":
+		s.pickupRental(w, r, id)
 	case "finish":
 		s.finishRental(w, r, id)
 	case "cancel":
 		s.cancelRental(w, r, id)
+	case "extend":
+		s.extendRental(w, r, id)
+	case "modify":
+		s.modifyRental(w, r, id)
+	case "invoice":
+		s.issueInvoice(w, r, id)
 	default:
 		RespondWithError(w, http.StatusNotFound, "unknown action")
 	}
@@ -71,12 +108,8 @@ func (s *Server) createRental(w http.ResponseWriter, r *http.Request) {
 	}
 
 	now := time.Now().UTC()
-	if req.StartDate.Before(now) {
-		RespondWithError(w, http.StatusBadRequest, "pickup date cannot be in the past")
-		return
-	}
-	if req.EndDate.Sub(req.StartDate) < time.Hour {
-		RespondWithError(w, http.StatusBadRequest, "minimum rental duration is 1 hour")
+	if msg := validateRentalWindow(req, now); msg != "" {
+		RespondWithError(w, http.StatusBadRequest, msg)
 		return
 	}
 
@@ -94,8 +127,27 @@ func (s *Server) createRental(w http.ResponseWriter, r *http.Request) {
 		if err := tx.First(&user, userID).Error; err != nil {
 			return err
 		}
+		owed, err := outstandingCharges(tx, userID)
+		if err != nil {
+			return err
+		}
+		if owed > 0 {
+			return fmt.Errorf("%w of %s", errOutstandingCharges, owed)
+		}
+
+		category, err := findCarCategory(tx, car.Category)
+		if err != nil {
+			return err
+		}
+		if err := checkDriverAge(category, user, req.StartDate); err != nil {
+			return err
+		}
+
+		if err := checkCarDocuments(tx, req.CarID, req.EndDate.UTC()); err != nil {
+			return err
+		}
 
-		available, err := checkAvailabilityWithDB(tx, req.CarID, req.StartDate, req.EndDate)
+		available, err := checkAvailabilityWithDB(tx, req.CarID, req.StartDate, req.EndDate, 0)
 		if err != nil {
 			return err
 		}
@@ -103,19 +155,43 @@ func (s *Server) createRental(w http.ResponseWriter, r *http.Request) {
 			return errors.New("car already booked")
 		}
 
-		finalPrice := CalculatePrice(car.PricePerHour, req.StartDate, req.EndDate, user.Rating)
+		price, promo, err := quoteRental(tx, car, category, user, req, now)
+		if err != nil {
+			return err
+		}
+		if !now.Before(req.StartDate.Add(-s.rentalExpiry.PaymentCutoff)) {
+			return errPaymentWindowClosed
+		}
 		created = entity.Rental{
-			UserID:     userID,
-			CarID:      req.CarID,
-			StartDate:  req.StartDate.UTC(),
-			EndDate:    req.EndDate.UTC(),
-			TotalPrice: finalPrice,
-			Status:     entity.RentalStatusPending,
+			UserID:          userID,
+			CarID:           req.CarID,
+			StartDate:       req.StartDate.UTC(),
+			EndDate:         req.EndDate.UTC(),
+			TotalPrice:      price.Total,
+			Status:          entity.RentalStatusPending,
+			PricingVersion:  price.PricingVersion,
+			PriceBreakdown:  &price,
+			Currency:        price.Currency,
+			ExchangeRate:    price.ExchangeRate,
+			TotalInCurrency: price.TotalInCurrency,
+			NetPrice:        price.Net,
+			TaxAmount:       price.Tax,
 		}
 
 		if err := tx.Create(&created).Error; err != nil {
 			return err
 		}
+		if err := s.rentals.Created(tx, &created, &userID); err != nil {
+			return err
+		}
+		if promo != nil {
+			if err := redeemPromoCode(tx, *promo, created); err != nil {
+				return err
+			}
+		}
+		if err := recordRentalEvent(tx, r, created, entity.CarEventRentalCreated); err != nil {
+			return err
+		}
 
 		if err := tx.Model(&entity.Car{}).Where("id = ?", req.CarID).
 			Update("status", entity.CarStatusBooked).Error; err != nil {
@@ -133,6 +209,21 @@ func (s *Server) createRental(w http.ResponseWriter, r *http.Request) {
 			RespondWithError(w, http.StatusBadRequest, "car already booked for these dates")
 		case err.Error() == "car not available":
 			RespondWithError(w, http.StatusBadRequest, "car not available")
+		case errors.Is(err, errOutstandingCharges):
+			RespondWithError(w, http.StatusPaymentRequired, "top up the balance to pay your "+err.Error()+" before booking")
+		case errors.Is(err, errBirthDateNeeded):
+			RespondWithError(w, http.StatusBadRequest, "set birth_date in your profile to rent this category")
+		case errors.Is(err, errDriverTooYoung):
+			RespondWithError(w, http.StatusForbidden, "driver is below the minimum age for this category")
+		case errors.Is(err, errCarDocumentsExpired):
+			RespondWithError(w, http.StatusBadRequest, "car documents expire before the end of the rental")
+		case errors.Is(err, errNoPricingRules):
+			RespondWithError(w, http.StatusServiceUnavailable, "pricing is not configured")
+		case errors.Is(err, errUnknownOption), errors.Is(err, errPromoRejected):
+			RespondWithError(w, http.StatusBadRequest, err.Error())
+		case errors.Is(err, errPaymentWindowClosed):
+			RespondWithError(w, http.StatusBadRequest,
+				fmt.Sprintf("pickup must be more than %s away to leave time for payment", s.rentalExpiry.PaymentCutoff))
 		default:
 			RespondWithError(w, http.StatusInternalServerError, "could not create rental")
 		}
@@ -140,13 +231,100 @@ func (s *Server) createRental(w http.ResponseWriter, r *http.Request) {
 	}
 
 	RespondWithJSON(w, http.StatusCreated, map[string]any{
-		"rental_id":   created.ID,
-		"total_price": created.TotalPrice,
-		"status":      created.Status,
-		"message":     "Rental created. Please proceed to payment.",
+		"rental_id":         created.ID,
+		"total_price":       created.TotalPrice,
+		"currency":          created.Currency,
+		"total_in_currency": created.TotalInCurrency,
+		"pricing_version":   created.PricingVersion,
+		"price_breakdown":   created.PriceBreakdown,
+		"status":            created.Status,
+		"pay_by":            s.rentalExpiry.payBy(created),
+		"message":           "Rental created. Please proceed to payment.",
 	})
 }
 
+// payRentalRequest is the optional body of POST /api/v1/rentals/{id}/pay.
+type payRentalRequest struct {
+	// Points are loyalty points to redeem; the rest is paid from the balance.
+	Points int `json:"points"`
+}
+
+// quoteRentalHandler handles POST /api/v1/rentals/quote: it prices a rental
+// exactly like createRental would, without booking anything.
+func (s *Server) quoteRentalHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		RespondWithError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	userID, ok := authhttp.UserIDFromContext(r.Context())
+	if !ok {
+		RespondWithError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	var req RentalRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		RespondWithError(w, http.StatusBadRequest, "invalid JSON")
+		return
+	}
+	now := time.Now().UTC()
+	if msg := validateRentalWindow(req, now); msg != "" {
+		RespondWithError(w, http.StatusBadRequest, msg)
+		return
+	}
+
+	var car entity.Car
+	var user entity.User
+	if err := s.db.First(&car, req.CarID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			RespondWithError(w, http.StatusNotFound, "car not found")
+			return
+		}
+		RespondWithError(w, http.StatusInternalServerError, "database error")
+		return
+	}
+	if err := s.db.First(&user, userID).Error; err != nil {
+		RespondWithError(w, http.StatusInternalServerError, "database error")
+		return
+	}
+
+	category, err := findCarCategory(s.db, car.Category)
+	var quote entity.PriceBreakdown
+	if err == nil {
+		quote, _, err = quoteRental(s.db, car, category, user, req, now)
+	}
+	if err != nil {
+		switch {
+		case errors.Is(err, errNoPricingRules):
+			RespondWithError(w, http.StatusServiceUnavailable, "pricing is not configured")
+		case errors.Is(err, errUnknownOption), errors.Is(err, errPromoRejected):
+			RespondWithError(w, http.StatusBadRequest, err.Error())
+		default:
+			RespondWithError(w, http.StatusInternalServerError, "could not price rental")
+		}
+		return
+	}
+	RespondWithJSON(w, http.StatusOK, quote)
+}
+
+// maxRentalDuration caps the length of a rental, extensions included, so
+// CalculatePrice, which walks the rental hour by hour, stays bounded.
+const maxRentalDuration = 90 * 24 * time.Hour
+
+// validateRentalWindow checks the requested dates of a new rental.
+func validateRentalWindow(req RentalRequest, now time.Time) string {
+	if req.StartDate.Before(now) {
+		return "pickup date cannot be in the past"
+	}
+	if req.EndDate.Sub(req.StartDate) < time.Hour {
+		return "minimum rental duration is 1 hour"
+	}
+	if req.EndDate.Sub(req.StartDate) > maxRentalDuration {
+		return "maximum rental duration is 90 days"
+	}
+	return ""
+}
+
 func (s *Server) listRentals(w http.ResponseWriter, r *http.Request) {
 	userID, ok := authhttp.UserIDFromContext(r.Context())
 	if !ok {
@@ -167,13 +345,16 @@ func (s *Server) listRentals(w http.ResponseWriter, r *http.Request) {
 		q = q.Where("user_id = ?", id)
 	}
 
-	var rentals []entity.Rental
-	if err := q.Find(&rentals).Error; err != nil {
-		RespondWithError(w, http.StatusInternalServerError, "database error")
+	page, err := parsePageRequest(r)
+	if err != nil {
+		RespondWithError(w, http.StatusBadRequest, err.Error())
 		return
 	}
 
-	RespondWithJSON(w, http.StatusOK, rentals)
+	result, err := paginate(q, page, newestFirst, func(rental entity.Rental) (any, uint) {
+		return rental.ID, rental.ID
+	})
+	respondWithPage(w, result, err)
 }
 
 func (s *Server) payRental(w http.ResponseWriter, r *http.Request, rentalID uint) {
@@ -185,6 +366,18 @@ func (s *Server) payRental(w http.ResponseWriter, r *http.Request, rentalID uint
 
 	role := getRoleFromContext(r)
 
+	var req payRentalRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		RespondWithError(w, http.StatusBadRequest, "invalid JSON")
+		return
+	}
+	if req.Points < 0 {
+		RespondWithError(w, http.StatusBadRequest, "points must be >= 0")
+		return
+	}
+
+	var transaction entity.Transaction
+	var deposit entity.Money
 	err := s.db.Transaction(func(tx *gorm.DB) error {
 		var rental entity.Rental
 		if err := tx.First(&rental, rentalID).Error; err != nil {
@@ -194,38 +387,45 @@ func (s *Server) payRental(w http.ResponseWriter, r *http.Request, rentalID uint
 		if role != entity.UserRoleAdmin && rental.UserID != userID {
 			return errors.New("forbidden")
 		}
-		if rental.Status != entity.RentalStatusPending {
-			return errors.New("invalid status")
+		if !s.rentals.Can(rental.Status, rentalstate.EventPay) {
+			return rentalstate.ErrInvalidTransition
 		}
 
+		if entity.Money(req.Points)*loyaltyPointValue > rental.TotalPrice {
+			return errors.New("too many points")
+		}
+		rental.PointsRedeemed = req.Points
+		paid := rentalCashPaid(rental)
+
 		res := tx.Model(&entity.User{}).
-			Where("id = ? AND balance >= ?", rental.UserID, rental.TotalPrice).
-			Update("balance", gorm.Expr("balance - ?", rental.TotalPrice))
+			Where("id = ? AND balance >= ?", rental.UserID, paid).
+			Update("balance", gorm.Expr("balance - ?", paid))
 		if res.Error != nil {
 			return res.Error
 		}
 		if res.RowsAffected == 0 {
-			return errors.New("insufficient balance")
+			return errInsufficientBalance
+		}
+		if req.Points > 0 {
+			if err := redeemLoyaltyPoints(tx, rental, req.Points, time.Now()); err != nil {
+				return err
+			}
 		}
 
-		if err := tx.Model(&entity.Rental{}).
-			Where("id = ? AND status = ?", rentalID, entity.RentalStatusPending).
-			Update("status", entity.RentalStatusActive).Error; err != nil {
+		if err := tx.Model(&entity.Rental{}).Where("id = ?", rentalID).
+			Update("points_redeemed", req.Points).Error; err != nil {
 			return err
 		}
 
-			transaction := entity.Transaction{
-				UserID:   rental.UserID,
-				RentalID: &rentalID,
-				Type:     "payment",
-				Amount:   rental.TotalPrice,
-				Status:   entity.TransactionStatusSuccess,
-			}
-			if err := tx.Create(&transaction).Error; err != nil {
-				return err
-			}
-
-		return nil
+		transaction = rentalTransaction(rental, entity.TransactionTypePayment, paid)
+		if err := tx.Create(&transaction).Error; err != nil {
+			return err
+		}
+		var err error
+		if deposit, err = holdDeposit(tx, rental); err != nil {
+			return err
+		}
+		return s.rentals.Fire(tx, &rental, rentalstate.EventPay, requestActor(r), "")
 	})
 
 	if err != nil {
@@ -234,17 +434,30 @@ func (s *Server) payRental(w http.ResponseWriter, r *http.Request, rentalID uint
 			RespondWithError(w, http.StatusNotFound, "rental not found")
 		case err.Error() == "forbidden":
 			RespondWithError(w, http.StatusForbidden, "forbidden")
-		case err.Error() == "invalid status":
+		case errors.Is(err, rentalstate.ErrInvalidTransition):
 			RespondWithError(w, http.StatusBadRequest, "rental is not pending")
-		case err.Error() == "insufficient balance":
-			RespondWithError(w, http.StatusBadRequest, "insufficient balance")
+		case errors.Is(err, errPaymentWindowClosed):
+			RespondWithError(w, http.StatusConflict, "the payment window of this booking has closed")
+		case errors.Is(err, errInsufficientBalance):
+			RespondWithError(w, http.StatusBadRequest, "insufficient balance for the price and the deposit")
+		case err.Error() == "too many points":
+			RespondWithError(w, http.StatusBadRequest, "points are worth more than the rental price")
+		case errors.Is(err, errInsufficientPoints):
+			RespondWithError(w, http.StatusBadRequest, "insufficient loyalty points")
 		default:
 			RespondWithError(w, http.StatusInternalServerError, "payment failed")
 		}
 		return
 	}
 
-	RespondWithJSON(w, http.StatusOK, map[string]any{"message": "payment successful"})
+	RespondWithJSON(w, http.StatusOK, map[string]any{
+		"message":          "payment successful",
+		"paid":             transaction.Amount,
+		"currency":         transaction.Currency,
+		"paid_in_currency": transaction.CurrencyAmount,
+		"points_redeemed":  req.Points,
+		"deposit_held":     deposit,
+	})
 }
 
 func (s *Server) finishRental(w http.ResponseWriter, r *http.Request, rentalID uint) {
@@ -256,6 +469,9 @@ func (s *Server) finishRental(w http.ResponseWriter, r *http.Request, rentalID u
 
 	role := getRoleFromContext(r)
 
+	var refunded entity.Money
+	var releaseAt *time.Time
+	var lateFee *entity.RentalCharge
 	err := s.db.Transaction(func(tx *gorm.DB) error {
 		var rental entity.Rental
 		if err := tx.First(&rental, rentalID).Error; err != nil {
@@ -264,18 +480,24 @@ func (s *Server) finishRental(w http.ResponseWriter, r *http.Request, rentalID u
 		if role != entity.UserRoleAdmin && rental.UserID != userID {
 			return errors.New("forbidden")
 		}
-		if rental.Status != entity.RentalStatusActive {
-			return errors.New("invalid status")
-		}
-
-		if err := tx.Model(&entity.Rental{}).Where("id = ?", rentalID).
-			Update("status", entity.RentalStatusCompleted).Error; err != nil {
+		refundedBefore := rental.Refunded
+		if err := s.rentals.Fire(tx, &rental, rentalstate.EventFinish, requestActor(r), ""); err != nil {
 			return err
 		}
-		if err := tx.Model(&entity.Car{}).Where("id = ?", rental.CarID).
-			Update("status", entity.CarStatusAvailable).Error; err != nil {
+		refunded = rental.Refunded - refundedBefore
+
+		// The late fee, if any, has been captured from the deposit; the rest
+		// stays held until the inspection window ends.
+		var charge entity.RentalCharge
+		err := tx.Where("rental_id = ? AND type = ? AND created_by IS NULL", rental.ID, entity.RentalChargeLateFee).
+			Last(&charge).Error
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
 			return err
 		}
+		if err == nil {
+			lateFee = &charge
+		}
+		releaseAt = s.depositReleaseAt(rental)
 		return nil
 	})
 
@@ -285,7 +507,7 @@ func (s *Server) finishRental(w http.ResponseWriter, r *http.Request, rentalID u
 			RespondWithError(w, http.StatusNotFound, "rental not found")
 		case err.Error() == "forbidden":
 			RespondWithError(w, http.StatusForbidden, "forbidden")
-		case err.Error() == "invalid status":
+		case errors.Is(err, rentalstate.ErrInvalidTransition):
 			RespondWithError(w, http.StatusBadRequest, "rental is not active")
 		default:
 			RespondWithError(w, http.StatusInternalServerError, "could not finish rental")
@@ -293,7 +515,19 @@ func (s *Server) finishRental(w http.ResponseWriter, r *http.Request, rentalID u
 		return
 	}
 
-	RespondWithJSON(w, http.StatusOK, map[string]any{"message": "rental completed"})
+	RespondWithJSON(w, http.StatusOK, map[string]any{
+		"message":            "rental completed",
+		"deposit_release_at": releaseAt,
+		"late_fee":           lateFee,
+		"refunded":           refunded,
+	})
+}
+
+// cancelRentalRequest is the optional body of POST /api/v1/rentals/{id}/cancel.
+type cancelRentalRequest struct {
+	Reason string `json:"reason"`
+	// RefundPercent lets an admin override the category's cancellation policy; a reason is required.
+	RefundPercent *float64 `json:"refund_percent"`
 }
 
 func (s *Server) cancelRental(w http.ResponseWriter, r *http.Request, rentalID uint) {
@@ -305,6 +539,27 @@ func (s *Server) cancelRental(w http.ResponseWriter, r *http.Request, rentalID u
 
 	role := getRoleFromContext(r)
 
+	var req cancelRentalRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		RespondWithError(w, http.StatusBadRequest, "invalid JSON")
+		return
+	}
+	reason := strings.TrimSpace(req.Reason)
+	if req.RefundPercent != nil {
+		switch {
+		case role != entity.UserRoleAdmin:
+			RespondWithError(w, http.StatusForbidden, "only admins can override the refund")
+			return
+		case reason == "":
+			RespondWithError(w, http.StatusBadRequest, "reason is required to override the refund")
+			return
+		case *req.RefundPercent < 0 || *req.RefundPercent > 100:
+			RespondWithError(w, http.StatusBadRequest, "refund_percent must be within 0..100")
+			return
+		}
+	}
+
+	var refund cancellationRefund
 	err := s.db.Transaction(func(tx *gorm.DB) error {
 		var rental entity.Rental
 		if err := tx.First(&rental, rentalID).Error; err != nil {
@@ -313,22 +568,22 @@ func (s *Server) cancelRental(w http.ResponseWriter, r *http.Request, rentalID u
 		if role != entity.UserRoleAdmin && rental.UserID != userID {
 			return errors.New("forbidden")
 		}
-		if rental.Status != entity.RentalStatusPending {
-			return errors.New("invalid status")
-		}
-		if time.Now().UTC().After(rental.StartDate) {
-			return errors.New("too late")
+		if !s.rentals.Can(rental.Status, rentalstate.EventCancel) {
+			return rentalstate.ErrInvalidTransition
 		}
 
-		if err := tx.Model(&entity.Rental{}).Where("id = ?", rentalID).
-			Update("status", entity.RentalStatusCancelled).Error; err != nil {
-			return err
-		}
-		if err := tx.Model(&entity.Car{}).Where("id = ?", rental.CarID).
-			Update("status", entity.CarStatusAvailable).Error; err != nil {
-			return err
+		// A paid rental is refunded under its category's policy; the guard
+		// of the transition below rolls it back after the start.
+		if slices.Contains(rentalstate.Paid, rental.Status) {
+			var err error
+			if refund, err = refundCancellation(tx, &rental, req.RefundPercent, time.Now()); err != nil {
+				return err
+			}
+			if refund.Overridden {
+				reason = fmt.Sprintf("refund overridden to %g%%: %s", refund.Percent, reason)
+			}
 		}
-		return nil
+		return s.rentals.Fire(tx, &rental, rentalstate.EventCancel, requestActor(r), reason)
 	})
 
 	if err != nil {
@@ -337,9 +592,9 @@ func (s *Server) cancelRental(w http.ResponseWriter, r *http.Request, rentalID u
 			RespondWithError(w, http.StatusNotFound, "rental not found")
 		case err.Error() == "forbidden":
 			RespondWithError(w, http.StatusForbidden, "forbidden")
-		case err.Error() == "invalid status":
-			RespondWithError(w, http.StatusBadRequest, "rental is not pending")
-		case err.Error() == "too late":
+		case errors.Is(err, rentalstate.ErrInvalidTransition):
+			RespondWithError(w, http.StatusBadRequest, "rental cannot be cancelled")
+		case errors.Is(err, rentalstate.ErrRentalStarted):
 			RespondWithError(w, http.StatusBadRequest, "cannot cancel after start")
 		default:
 			RespondWithError(w, http.StatusInternalServerError, "could not cancel rental")
@@ -347,7 +602,12 @@ func (s *Server) cancelRental(w http.ResponseWriter, r *http.Request, rentalID u
 		return
 	}
 
-	RespondWithJSON(w, http.StatusOK, map[string]any{"message": "rental cancelled"})
+	RespondWithJSON(w, http.StatusOK, map[string]any{
+		"message":         "rental cancelled",
+		"refunded":        refund.Amount,
+		"refund_percent":  refund.Percent,
+		"refund_override": refund.Overridden,
+	})
 }
 
 func parseRentalAction(path string) (uint, string, error) {
@@ -1,40 +1,26 @@
 package server
 
 import (
-	"math"
 	"time"
 
 	"github.com/CMPNION/Car-Rental-API.git/internal/entity"
+	"github.com/CMPNION/Car-Rental-API.git/internal/usecase/rentalstate"
 	"gorm.io/gorm"
 )
 
-// CalculatePrice is our isolated Pricing Engine
-func CalculatePrice(basePricePerHour float64, start, end time.Time, userRating float64) float64 {
-	durationHours := end.Sub(start).Hours()
-	baseTotal := basePricePerHour * durationHours
-
-	modifier := 1.0
-	// 10% discount for high-rated users (rating > 4.5)
-	if userRating > 4.5 {
-		modifier = 0.90
-	} else if userRating < 2.0 && userRating > 0 {
-		// 20% surcharge for risky drivers
-		modifier = 1.20
-	}
-
-	return math.Round(baseTotal*modifier*100) / 100
-}
-
 // CheckAvailability is an isolated check for overbooking
 func (s *Server) CheckAvailability(carID uint, start, end time.Time) (bool, error) {
-	return checkAvailabilityWithDB(s.db, carID, start, end)
+	return checkAvailabilityWithDB(s.db, carID, start, end, 0)
 }
 
-func checkAvailabilityWithDB(db *gorm.DB, carID uint, start, end time.Time) (bool, error) {
+// checkAvailabilityWithDB reports whether no open booking of the car overlaps
+// [start, end); excludeRentalID leaves out a rental being moved. A completed
+// rental no longer holds the car, even if it was returned before its end.
+func checkAvailabilityWithDB(db *gorm.DB, carID uint, start, end time.Time, excludeRentalID uint) (bool, error) {
 	var existingRental entity.Rental
 	// Formula for interval intersection
-	err := db.Where("car_id = ? AND status != ? AND start_date < ? AND end_date > ?",
-		carID, entity.RentalStatusCancelled, end, start).First(&existingRental).Error
+	err := db.Where("car_id = ? AND id <> ? AND status IN ? AND start_date < ? AND end_date > ?",
+		carID, excludeRentalID, rentalstate.Open, end, start).First(&existingRental).Error
 
 	if err == nil {
 		return false, nil // Match found, car is occupied
@@ -44,3 +30,22 @@ func checkAvailabilityWithDB(db *gorm.DB, carID uint, start, end time.Time) (boo
 	}
 	return false, err // Database error
 }
+
+// refundRental credits amount back to the renter's balance and records a refund transaction.
+func refundRental(tx *gorm.DB, rental entity.Rental, amount entity.Money) error {
+	if amount <= 0 {
+		return nil
+	}
+
+	res := tx.Model(&entity.User{}).Where("id = ?", rental.UserID).
+		Update("balance", gorm.Expr("balance + ?", amount))
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+
+	transaction := rentalTransaction(rental, entity.TransactionTypeRefund, amount)
+	return tx.Create(&transaction).Error
+}
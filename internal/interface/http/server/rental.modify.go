@@ -0,0 +1,347 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/CMPNION/Car-Rental-API.git/internal/entity"
+	authhttp "github.com/CMPNION/Car-Rental-API.git/internal/interface/http/auth"
+	"github.com/CMPNION/Car-Rental-API.git/internal/usecase/rentalstate"
+	"gorm.io/gorm"
+)
+
+var errNotModifiable = errors.New("rental cannot be modified")
+
+// modifiableStatuses are the statuses of rentals whose car and dates can still change.
+var modifiableStatuses = []string{entity.RentalStatusPending, entity.RentalStatusConfirmed}
+
+// modifyRentalRequest is the payload of POST /api/v1/rentals/{id}/modify.
+// Fields left out keep their current value.
+type modifyRentalRequest struct {
+	CarID     uint       `json:"car_id"`
+	StartDate *time.Time `json:"start_date"`
+	EndDate   *time.Time `json:"end_date"`
+	Reason    string     `json:"reason"`
+}
+
+// modifyRental handles POST /api/v1/rentals/{id}/modify: it moves a rental
+// that has not started to other dates and/or another car in one transaction.
+// The new slot is checked like a booking, leaving the rental itself out, the
+// rental is repriced with the rules in effect now and the difference is
+// charged or refunded if it was paid.
+func (s *Server) modifyRental(w http.ResponseWriter, r *http.Request, rentalID uint) {
+	userID, ok := authhttp.UserIDFromContext(r.Context())
+	if !ok {
+		RespondWithError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	var req modifyRentalRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		RespondWithError(w, http.StatusBadRequest, "invalid JSON")
+		return
+	}
+	now := time.Now().UTC()
+
+	var rental entity.Rental
+	var modification entity.RentalModification
+	var msg string
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.First(&rental, rentalID).Error; err != nil {
+			return err
+		}
+		if getRoleFromContext(r) != entity.UserRoleAdmin && rental.UserID != userID {
+			return errors.New("forbidden")
+		}
+		if !slices.Contains(modifiableStatuses, rental.Status) {
+			return errNotModifiable
+		}
+		if !now.Before(rental.StartDate) {
+			return rentalstate.ErrRentalStarted
+		}
+
+		target := RentalRequest{CarID: rental.CarID, StartDate: rental.StartDate, EndDate: rental.EndDate}
+		if req.CarID != 0 {
+			target.CarID = req.CarID
+		}
+		if req.StartDate != nil {
+			target.StartDate = *req.StartDate
+		}
+		if req.EndDate != nil {
+			target.EndDate = *req.EndDate
+		}
+		if target.CarID == rental.CarID && target.StartDate.Equal(rental.StartDate) && target.EndDate.Equal(rental.EndDate) {
+			msg = "nothing to change"
+			return nil
+		}
+		if msg = validateRentalWindow(target, now); msg != "" {
+			return nil
+		}
+		if rental.Status == entity.RentalStatusPending && !now.Before(target.StartDate.Add(-s.rentalExpiry.PaymentCutoff)) {
+			return errPaymentWindowClosed
+		}
+
+		var car entity.Car
+		if err := tx.First(&car, target.CarID).Error; err != nil {
+			return err
+		}
+		if target.CarID != rental.CarID && car.Status != entity.CarStatusAvailable {
+			return errors.New("car not available")
+		}
+		var user entity.User
+		if err := tx.First(&user, rental.UserID).Error; err != nil {
+			return err
+		}
+		category, err := findCarCategory(tx, car.Category)
+		if err != nil {
+			return err
+		}
+		if err := checkDriverAge(category, user, target.StartDate); err != nil {
+			return err
+		}
+		if err := checkCarDocuments(tx, target.CarID, target.EndDate.UTC()); err != nil {
+			return err
+		}
+		available, err := checkAvailabilityWithDB(tx, target.CarID, target.StartDate, target.EndDate, rental.ID)
+		if err != nil {
+			return err
+		}
+		if !available {
+			return errors.New("car already booked")
+		}
+
+		// The booked extras that are still offered and the promo code the
+		// rental redeemed carry over to the new price.
+		rules, err := activePricingRules(tx, now)
+		if err != nil {
+			return err
+		}
+		target.Options = bookedOptions(rules.Rules, rental.PriceBreakdown)
+		promo, err := rentalPromoCode(tx, rental.ID)
+		if err != nil {
+			return err
+		}
+		price, err := priceRental(tx, car, category, user, target, promo, rental.ID, now)
+		if err != nil {
+			return err
+		}
+		if rental.Currency != "" {
+			price.Currency = rental.Currency
+			price.ExchangeRate = rentalExchangeRate(rental)
+			price.TotalInCurrency = fromBaseCurrency(price.Total, price.ExchangeRate)
+		}
+
+		modification = entity.RentalModification{
+			RentalID:      rental.ID,
+			PreviousCarID: rental.CarID,
+			CarID:         target.CarID,
+			PreviousStart: rental.StartDate.UTC(),
+			PreviousEnd:   rental.EndDate.UTC(),
+			StartDate:     target.StartDate.UTC(),
+			EndDate:       target.EndDate.UTC(),
+			PreviousTotal: rental.TotalPrice,
+			Total:         price.Total,
+			Difference:    price.Total - rental.TotalPrice,
+			PreviousPrice: rental.PriceBreakdown,
+			Reason:        strings.TrimSpace(req.Reason),
+			CreatedBy:     requestActor(r),
+		}
+
+		previous := rental
+		rental.CarID = target.CarID
+		rental.StartDate = target.StartDate.UTC()
+		rental.EndDate = target.EndDate.UTC()
+		rental.TotalPrice = price.Total
+		rental.NetPrice = price.Net
+		rental.TaxAmount = price.Tax
+		rental.TotalInCurrency = price.TotalInCurrency
+		rental.PricingVersion = price.PricingVersion
+		rental.PriceBreakdown = &price
+
+		if slices.Contains(rentalstate.Paid, rental.Status) {
+			if err := settleModification(tx, previous, rental, modification.Difference); err != nil {
+				return err
+			}
+		}
+
+		res := tx.Model(&entity.Rental{}).Where("id = ? AND status = ?", rental.ID, rental.Status).
+			Select("car_id", "start_date", "end_date", "total_price", "net_price", "tax_amount",
+				"total_in_currency", "pricing_version", "price_breakdown").
+			Updates(&rental)
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return errNotModifiable
+		}
+		if promo != nil {
+			if err := tx.Model(&entity.PromoRedemption{}).Where("rental_id = ?", rental.ID).
+				Update("amount", promoDiscount(price)).Error; err != nil {
+				return err
+			}
+		}
+		if err := tx.Create(&modification).Error; err != nil {
+			return err
+		}
+		return moveRentalCar(tx, r, previous, rental)
+	})
+
+	if err != nil {
+		switch {
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			RespondWithError(w, http.StatusNotFound, "rental or car not found")
+		case err.Error() == "forbidden":
+			RespondWithError(w, http.StatusForbidden, "forbidden")
+		case errors.Is(err, errNotModifiable):
+			RespondWithError(w, http.StatusBadRequest, "only pending or confirmed rentals can be modified")
+		case errors.Is(err, rentalstate.ErrRentalStarted):
+			RespondWithError(w, http.StatusBadRequest, "cannot modify after start")
+		case err.Error() == "car not available":
+			RespondWithError(w, http.StatusBadRequest, "car not available")
+		case err.Error() == "car already booked":
+			RespondWithError(w, http.StatusConflict, "car already booked for these dates")
+		case errors.Is(err, errBirthDateNeeded):
+			RespondWithError(w, http.StatusBadRequest, "set birth_date in your profile to rent this category")
+		case errors.Is(err, errDriverTooYoung):
+			RespondWithError(w, http.StatusForbidden, "driver is below the minimum age for this category")
+		case errors.Is(err, errCarDocumentsExpired):
+			RespondWithError(w, http.StatusBadRequest, "car documents expire before the end of the rental")
+		case errors.Is(err, errNoPricingRules):
+			RespondWithError(w, http.StatusServiceUnavailable, "pricing is not configured")
+		case errors.Is(err, errPaymentWindowClosed):
+			RespondWithError(w, http.StatusBadRequest,
+				fmt.Sprintf("pickup must be more than %s away to leave time for payment", s.rentalExpiry.PaymentCutoff))
+		case err.Error() == "too many points":
+			RespondWithError(w, http.StatusBadRequest, "the redeemed points are worth more than the new price")
+		case errors.Is(err, errInsufficientBalance):
+			RespondWithError(w, http.StatusBadRequest, "insufficient balance for the price difference and the deposit")
+		default:
+			RespondWithError(w, http.StatusInternalServerError, "could not modify rental")
+		}
+		return
+	}
+	if msg != "" {
+		RespondWithError(w, http.StatusBadRequest, msg)
+		return
+	}
+
+	resp := map[string]any{
+		"rental_id":       rental.ID,
+		"status":          rental.Status,
+		"car_id":          rental.CarID,
+		"start_date":      rental.StartDate,
+		"end_date":        rental.EndDate,
+		"total_price":     rental.TotalPrice,
+		"difference":      modification.Difference,
+		"price_breakdown": rental.PriceBreakdown,
+		"modification":    modification,
+	}
+	if rental.Status == entity.RentalStatusPending {
+		resp["pay_by"] = s.rentalExpiry.payBy(rental)
+	}
+	RespondWithJSON(w, http.StatusOK, resp)
+}
+
+// settleModification charges or refunds the price difference of a paid rental
+// and swaps the held deposit when the new car asks for another one.
+func settleModification(tx *gorm.DB, previous, rental entity.Rental, difference entity.Money) error {
+	if entity.Money(rental.PointsRedeemed)*loyaltyPointValue > rental.TotalPrice {
+		return errors.New("too many points")
+	}
+	switch {
+	case difference > 0:
+		res := tx.Model(&entity.User{}).
+			Where("id = ? AND balance >= ?", rental.UserID, difference).
+			Update("balance", gorm.Expr("balance - ?", difference))
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return errInsufficientBalance
+		}
+		transaction := rentalTransaction(rental, entity.TransactionTypePayment, difference)
+		if err := tx.Create(&transaction).Error; err != nil {
+			return err
+		}
+	case difference < 0:
+		if err := refundRental(tx, rental, -difference); err != nil {
+			return err
+		}
+	}
+
+	if previous.DepositHeld <= 0 {
+		return nil
+	}
+	deposit, err := rentalDeposit(tx, rental)
+	if err != nil || deposit == previous.DepositHeld {
+		return err
+	}
+	if _, err := releaseDeposit(tx, previous); err != nil {
+		return err
+	}
+	_, err = holdDeposit(tx, rental)
+	return err
+}
+
+// moveRentalCar records the change on the car's history and, when the rental
+// moved to another car, books the new car and frees the old one.
+func moveRentalCar(tx *gorm.DB, r *http.Request, previous, rental entity.Rental) error {
+	if previous.CarID == rental.CarID {
+		return recordRentalEvent(tx, r, rental, entity.CarEventRentalModified)
+	}
+
+	rentalID := rental.ID
+	if err := recordCarEvent(tx, r, &entity.CarEvent{
+		CarID:    previous.CarID,
+		Type:     entity.CarEventRentalCancelled,
+		RentalID: &rentalID,
+		Note:     fmt.Sprintf("moved to car #%d", rental.CarID),
+	}); err != nil {
+		return err
+	}
+	if err := recordRentalEvent(tx, r, rental, entity.CarEventRentalModified); err != nil {
+		return err
+	}
+	if err := tx.Model(&entity.Car{}).Where("id = ?", previous.CarID).
+		Update("status", entity.CarStatusAvailable).Error; err != nil {
+		return err
+	}
+	return tx.Model(&entity.Car{}).Where("id = ?", rental.CarID).
+		Update("status", entity.CarStatusBooked).Error
+}
+
+// bookedOptions are the extras of the booked price that are still offered.
+func bookedOptions(rules entity.PricingRules, booked *entity.PriceBreakdown) []string {
+	if booked == nil {
+		return nil
+	}
+	var options []string
+	for _, fee := range booked.Fees {
+		if extra, ok := findPricingExtra(rules, strings.ToLower(fee.Code)); ok {
+			options = append(options, extra.Code)
+		}
+	}
+	return options
+}
+
+// rentalPromoCode is the promo code the rental redeemed, nil for none.
+func rentalPromoCode(tx *gorm.DB, rentalID uint) (*entity.PromoCode, error) {
+	var redemption entity.PromoRedemption
+	err := tx.Where("rental_id = ?", rentalID).First(&redemption).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var promo entity.PromoCode
+	if err := tx.Unscoped().First(&promo, redemption.PromoCodeID).Error; err != nil {
+		return nil, err
+	}
+	return &promo, nil
+}
@@ -0,0 +1,137 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/CMPNION/Car-Rental-API.git/internal/entity"
+)
+
+func TestModifyRentalSettlement(t *testing.T) {
+	const balance entity.Money = 1000000
+	tests := []struct {
+		name     string
+		paid     bool
+		hours    time.Duration
+		luxury   bool
+		wantSign int
+	}{
+		{"paid rental charges a longer one", true, 6, false, 1},
+		{"paid rental refunds a shorter one", true, 2, false, -1},
+		{"unpaid rental only changes the price", false, 6, false, 1},
+		{"paid rental swaps the deposit for another car", true, 4, true, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestServer(t)
+			economy := entity.Car{Mark: "Kia", CarModel: "Rio", Category: entity.CarCategoryEconomy,
+				Status: entity.CarStatusAvailable, PricePerHour: 1000}
+			luxury := entity.Car{Mark: "BMW", CarModel: "X5", Category: entity.CarCategoryLuxury,
+				Status: entity.CarStatusAvailable, PricePerHour: 5000}
+			for _, v := range []any{&economy, &luxury} {
+				if err := s.db.Create(v).Error; err != nil {
+					t.Fatal(err)
+				}
+			}
+			user := newRenter(t, s, balance)
+			start := time.Now().UTC().Add(48 * time.Hour).Truncate(time.Hour)
+			rental := bookRental(t, s, user, economy, start, start.Add(4*time.Hour), tt.paid)
+			if err := s.db.Model(&user).Update("balance", balance).Error; err != nil {
+				t.Fatal(err)
+			}
+
+			body := map[string]any{"end_date": start.Add(tt.hours * time.Hour)}
+			if tt.luxury {
+				body["car_id"] = luxury.ID
+			}
+			payload, _ := json.Marshal(body)
+			seen := lastTransactionID(t, s)
+			rec := rentalAction(s, user, rental.ID, "modify", string(payload))
+			if rec.Code != http.StatusOK {
+				t.Fatalf("modify: status %d: %s", rec.Code, rec.Body)
+			}
+			var resp struct {
+				Data struct {
+					Modification entity.RentalModification `json:"modification"`
+				} `json:"data"`
+			}
+			if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+				t.Fatal(err)
+			}
+			difference := resp.Data.Modification.Difference
+			if (tt.wantSign > 0 && difference <= 0) || (tt.wantSign < 0 && difference >= 0) {
+				t.Fatalf("difference %s, want its sign to be %d", difference, tt.wantSign)
+			}
+
+			var modified entity.Rental
+			if err := s.db.First(&modified, rental.ID).Error; err != nil {
+				t.Fatal(err)
+			}
+			if err := s.db.First(&user, user.ID).Error; err != nil {
+				t.Fatal(err)
+			}
+			if modified.TotalPrice != rental.TotalPrice+difference {
+				t.Errorf("total %s, want %s changed by %s", modified.TotalPrice, rental.TotalPrice, difference)
+			}
+
+			var transactions []entity.Transaction
+			if err := s.db.Where("rental_id = ? AND id > ?", rental.ID, seen).
+				Order("id").Find(&transactions).Error; err != nil {
+				t.Fatal(err)
+			}
+			if !tt.paid {
+				if user.Balance != balance || len(transactions) != 0 {
+					t.Errorf("unpaid modification: balance %s and %d transactions, want %s and none",
+						user.Balance, len(transactions), balance)
+				}
+				return
+			}
+
+			wantBalance := balance - difference
+			wantTypes := []string{entity.TransactionTypePayment}
+			if difference < 0 {
+				wantTypes = []string{entity.TransactionTypeRefund}
+			}
+			if tt.luxury {
+				// The economy deposit goes back and the luxury one is held instead.
+				wantBalance += rental.DepositHeld - modified.PriceBreakdown.Deposit
+				wantTypes = append(wantTypes, entity.TransactionTypeDepositRelease, entity.TransactionTypeDepositHold)
+				if modified.DepositHeld != modified.PriceBreakdown.Deposit || modified.DepositHeld == rental.DepositHeld {
+					t.Errorf("deposit held %s, want the luxury deposit %s instead of %s",
+						modified.DepositHeld, modified.PriceBreakdown.Deposit, rental.DepositHeld)
+				}
+			}
+			if user.Balance != wantBalance || user.HeldBalance != modified.DepositHeld {
+				t.Errorf("balance %s, held %s, want %s and %s", user.Balance, user.HeldBalance, wantBalance, modified.DepositHeld)
+			}
+			if got := transactionTypes(transactions); fmt.Sprint(got) != fmt.Sprint(wantTypes) {
+				t.Fatalf("transactions %v, want %v", got, wantTypes)
+			}
+			if transactions[0].Amount != max(difference, -difference) {
+				t.Errorf("%s of %s, want %s", transactions[0].Type, transactions[0].Amount, max(difference, -difference))
+			}
+		})
+	}
+}
+
+// lastTransactionID is the id of the latest transaction, so a test can tell
+// which transactions came after it.
+func lastTransactionID(t *testing.T, s *Server) uint {
+	t.Helper()
+	var id uint
+	if err := s.db.Model(&entity.Transaction{}).Select("COALESCE(MAX(id), 0)").Scan(&id).Error; err != nil {
+		t.Fatal(err)
+	}
+	return id
+}
+
+func transactionTypes(transactions []entity.Transaction) []string {
+	types := make([]string, 0, len(transactions))
+	for _, tx := range transactions {
+		types = append(types, tx.Type)
+	}
+	return types
+}
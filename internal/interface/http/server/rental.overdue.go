@@ -0,0 +1,168 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"time"
+
+	"github.com/CMPNION/Car-Rental-API.git/internal/entity"
+	"github.com/CMPNION/Car-Rental-API.git/internal/usecase/rentalstate"
+	"gorm.io/gorm"
+)
+
+const rentalOverdueTick = time.Minute
+
+// defaultLateReturn applies when the rental's pricing rules set no late_return.
+var defaultLateReturn = entity.LateReturnPolicy{GraceMinutes: 30, HourlyMultiplier: 1.5}
+
+// runOverdueDetection periodically marks active rentals past their end as overdue.
+func (s *Server) runOverdueDetection() {
+	ticker := time.NewTicker(rentalOverdueTick)
+	defer ticker.Stop()
+
+	for {
+		if n, err := s.markOverdueRentals(time.Now()); err != nil {
+			log.Printf("overdue detection failed: %v", err)
+		} else if n > 0 {
+			log.Printf("marked %d rental(s) overdue", n)
+		}
+		<-ticker.C
+	}
+}
+
+// markOverdueRentals moves every active rental whose end has passed to
+// overdue, each in its own transaction. A rental finished by a request in the
+// meantime is left alone.
+func (s *Server) markOverdueRentals(now time.Time) (int, error) {
+	var ids []uint
+	if err := s.db.Model(&entity.Rental{}).
+		Where("status = ? AND end_date <= ?", entity.RentalStatusActive, now.UTC()).
+		Order("id asc").
+		Pluck("id", &ids).Error; err != nil {
+		return 0, err
+	}
+
+	marked := 0
+	for _, id := range ids {
+		err := s.db.Transaction(func(tx *gorm.DB) error {
+			var rental entity.Rental
+			if err := tx.First(&rental, id).Error; err != nil {
+				return err
+			}
+			reason := "not returned by " + rental.EndDate.UTC().Format(time.RFC3339)
+			return s.rentals.Fire(tx, &rental, rentalstate.EventOverdue, nil, reason)
+		})
+		if errors.Is(err, rentalstate.ErrInvalidTransition) || errors.Is(err, gorm.ErrRecordNotFound) {
+			continue
+		}
+		if err != nil {
+			return marked, err
+		}
+		marked++
+	}
+	return marked, nil
+}
+
+// notifyOverdue alerts the admins and the renter of the car's next booking,
+// whose pickup may be delayed.
+func notifyOverdue(tx *gorm.DB, c rentalstate.Change) error {
+	rental := c.Rental
+	rentalID := rental.ID
+	end := rental.EndDate.UTC().Format("2006-01-02 15:04 MST")
+
+	var admins []uint
+	if err := tx.Model(&entity.User{}).Where("role = ?", entity.UserRoleAdmin).
+		Pluck("id", &admins).Error; err != nil {
+		return err
+	}
+	for _, adminID := range admins {
+		if err := notifyUser(tx, adminID, &rentalID, entity.NotificationRentalOverdue,
+			fmt.Sprintf("Rental #%d is overdue", rentalID),
+			fmt.Sprintf("Car #%d was due back at %s and has not been returned.", rental.CarID, end)); err != nil {
+			return err
+		}
+	}
+
+	var next entity.Rental
+	err := tx.Where("car_id = ? AND id <> ? AND status IN ? AND start_date >= ?",
+		rental.CarID, rental.ID, []string{entity.RentalStatusPending, entity.RentalStatusConfirmed}, rental.StartDate).
+		Order("start_date asc").
+		First(&next).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	nextID := next.ID
+	return notifyUser(tx, next.UserID, &nextID, entity.NotificationRentalDelayed,
+		fmt.Sprintf("Booking #%d may be delayed", nextID),
+		fmt.Sprintf("The car booked for %s has not been returned by the previous renter yet. We will contact you before pickup.",
+			next.StartDate.UTC().Format("2006-01-02 15:04 MST")))
+}
+
+// rentalLateReturnPolicy is the late_return of the rules that priced the
+// rental; defaultLateReturn when they set none.
+func rentalLateReturnPolicy(tx *gorm.DB, rental entity.Rental) (entity.LateReturnPolicy, error) {
+	rules, ok, err := rentalPricingRules(tx, rental)
+	if err != nil {
+		return entity.LateReturnPolicy{}, err
+	}
+	if !ok || rules.LateReturn == nil {
+		return defaultLateReturn, nil
+	}
+	return *rules.LateReturn, nil
+}
+
+// lateReturnFee is what returning the car at returnedAt costs on top of the
+// rental, and how many started hours late that is. Within the grace period
+// the return is free; past it every started hour since the end counts.
+func lateReturnFee(policy entity.LateReturnPolicy, hourlyRate entity.Money, end, returnedAt time.Time) (entity.Money, int) {
+	late := returnedAt.Sub(end)
+	if late <= time.Duration(policy.GraceMinutes)*time.Minute {
+		return 0, 0
+	}
+	hours := int(math.Ceil(late.Hours()))
+	fee := policy.Flat + hourlyRate.Mul(policy.HourlyMultiplier*float64(hours))
+	if policy.Cap > 0 && fee > policy.Cap {
+		fee = policy.Cap
+	}
+	return fee, hours
+}
+
+// chargeLateReturn raises a late_fee charge for a rental finished after its
+// end. It runs before the deposit is released so the fee is captured from it.
+func chargeLateReturn(tx *gorm.DB, c rentalstate.Change) error {
+	rental := *c.Rental
+	if !c.At.After(rental.EndDate) {
+		return nil
+	}
+	policy, err := rentalLateReturnPolicy(tx, rental)
+	if err != nil {
+		return err
+	}
+
+	hourlyRate := entity.Money(0)
+	if rental.PriceBreakdown != nil {
+		hourlyRate = rental.PriceBreakdown.HourlyRate
+	} else {
+		var car entity.Car
+		if err := tx.Unscoped().First(&car, rental.CarID).Error; err != nil {
+			return err
+		}
+		hourlyRate = car.PricePerHour
+	}
+
+	fee, hours := lateReturnFee(policy, hourlyRate, rental.EndDate, c.At)
+	if fee <= 0 {
+		return nil
+	}
+	return raiseRentalCharge(tx, rental, &entity.RentalCharge{
+		Type:   entity.RentalChargeLateFee,
+		Amount: fee,
+		Note: fmt.Sprintf("returned %s, %d started hour(s) after the end at %s",
+			c.At.Format(time.RFC3339), hours, rental.EndDate.UTC().Format(time.RFC3339)),
+	})
+}
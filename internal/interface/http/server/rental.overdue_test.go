@@ -0,0 +1,39 @@
+package server
+
+import (
+	"testing"
+	"time"
+
+	"github.com/CMPNION/Car-Rental-API.git/internal/entity"
+)
+
+func TestLateReturnFee(t *testing.T) {
+	end := time.Date(2026, 3, 2, 18, 0, 0, 0, time.UTC)
+	hourly := entity.LateReturnPolicy{GraceMinutes: 15, HourlyMultiplier: 1.5}
+
+	tests := []struct {
+		name      string
+		policy    entity.LateReturnPolicy
+		returned  time.Time
+		wantFee   entity.Money
+		wantHours int
+	}{
+		{"on time", hourly, end, 0, 0},
+		{"early", hourly, end.Add(-time.Hour), 0, 0},
+		{"within grace", hourly, end.Add(15 * time.Minute), 0, 0},
+		{"past grace counts from the end", hourly, end.Add(16 * time.Minute), 1500, 1},
+		{"started hours round up", hourly, end.Add(2*time.Hour + time.Second), 4500, 3},
+		{"no grace", entity.LateReturnPolicy{HourlyMultiplier: 1}, end.Add(time.Minute), 1000, 1},
+		{"flat fee", entity.LateReturnPolicy{HourlyMultiplier: 1, Flat: 500}, end.Add(2 * time.Hour), 2500, 2},
+		{"capped", entity.LateReturnPolicy{HourlyMultiplier: 2, Cap: 5000}, end.Add(5 * time.Hour), 5000, 5},
+		{"below the cap", entity.LateReturnPolicy{HourlyMultiplier: 2, Cap: 5000}, end.Add(2 * time.Hour), 4000, 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fee, hours := lateReturnFee(tt.policy, 1000, end, tt.returned)
+			if fee != tt.wantFee || hours != tt.wantHours {
+				t.Errorf("got %s for %d hours, want %s for %d hours", fee, hours, tt.wantFee, tt.wantHours)
+			}
+		})
+	}
+}
@@ -0,0 +1,234 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"time"
+
+	"github.com/CMPNION/Car-Rental-API.git/internal/entity"
+	authhttp "github.com/CMPNION/Car-Rental-API.git/internal/interface/http/auth"
+	"github.com/CMPNION/Car-Rental-API.git/internal/usecase/rentalstate"
+	"gorm.io/gorm"
+)
+
+const (
+	// Defaults of RENTAL_PICKUP_EARLY and RENTAL_NO_SHOW_AFTER.
+	defaultPickupEarly = 30 * time.Minute
+	defaultNoShowAfter = 2 * time.Hour
+	rentalNoShowTick   = time.Minute
+)
+
+var (
+	errPickupTooEarly = errors.New("pickup too early")
+	errPickupTooLate  = errors.New("pickup too late")
+)
+
+// rentalPickupConfig is when the car of a confirmed rental can be picked up:
+// from Early before its start until NoShowAfter past it, after which the
+// rental is a no-show.
+type rentalPickupConfig struct {
+	Early       time.Duration
+	NoShowAfter time.Duration
+}
+
+// loadRentalPickupConfig reads RENTAL_PICKUP_EARLY and RENTAL_NO_SHOW_AFTER
+// (Go durations such as "1h"), falling back to the defaults.
+func loadRentalPickupConfig() rentalPickupConfig {
+	return rentalPickupConfig{
+		Early:       envDuration("RENTAL_PICKUP_EARLY", defaultPickupEarly),
+		NoShowAfter: envDuration("RENTAL_NO_SHOW_AFTER", defaultNoShowAfter),
+	}
+}
+
+// noShowAt is when a confirmed rental that was not picked up becomes a
+// no-show; never later than its end.
+func (c rentalPickupConfig) noShowAt(rental entity.Rental) time.Time {
+	at := rental.StartDate.Add(c.NoShowAfter)
+	if rental.EndDate.Before(at) {
+		at = rental.EndDate
+	}
+	return at.UTC()
+}
+
+// pickupGuard refuses to hand over the car outside the pickup window.
+func (c rentalPickupConfig) pickupGuard(_ *gorm.DB, ch rentalstate.Change) error {
+	if ch.At.Before(ch.Rental.StartDate.Add(-c.Early)) {
+		return errPickupTooEarly
+	}
+	if !ch.At.Before(c.noShowAt(*ch.Rental)) {
+		return errPickupTooLate
+	}
+	return nil
+}
+
+// markPickedUp records when the car was handed over; the rental is active,
+// and its usage is billed, from then on.
+func markPickedUp(tx *gorm.DB, c rentalstate.Change) error {
+	at := c.At
+	c.Rental.PickedUpAt = &at
+	return tx.Model(&entity.Rental{}).Where("id = ?", c.Rental.ID).Update("picked_up_at", at).Error
+}
+
+// latePickupRefund is what is paid back of paid for the booking from start
+// to end when the car is picked up at pickedUpAt, and how many whole hours
+// before the pickup that covers. Billing starts at the pickup, whatever the
+// early_return policy says.
+func latePickupRefund(paid entity.Money, start, end, pickedUpAt time.Time) (entity.Money, int) {
+	booked := end.Sub(start).Hours()
+	late := latePickupHours(start, pickedUpAt)
+	if booked <= 0 || late <= 0 {
+		return 0, 0
+	}
+	return paid.Mul(math.Min(float64(late)/booked, 1)), late
+}
+
+// latePickupHours is how many whole hours of the booking had passed at the pickup.
+func latePickupHours(start, pickedUpAt time.Time) int {
+	if !pickedUpAt.After(start) {
+		return 0
+	}
+	return int(math.Floor(pickedUpAt.Sub(start).Hours()))
+}
+
+// refundLatePickup pays the hours between the booked start and a late pickup
+// back to the balance.
+func refundLatePickup(tx *gorm.DB, c rentalstate.Change) error {
+	rental := c.Rental
+	amount, _ := latePickupRefund(rentalCashPaid(*rental), rental.StartDate, rental.EndDate, c.At)
+	if amount <= 0 {
+		return nil
+	}
+	if err := refundRental(tx, *rental, amount); err != nil {
+		return err
+	}
+	rental.Refunded += amount
+	return tx.Model(&entity.Rental{}).Where("id = ?", rental.ID).
+		Update("refunded", gorm.Expr("refunded + ?", amount)).Error
+}
+
+// rentalUsageStart is when the renter got the car and billing of the used
+// time starts: the pickup, or the booked start for rentals that went active
+// on payment.
+func rentalUsageStart(rental entity.Rental) time.Time {
+	if rental.PickedUpAt != nil {
+		return *rental.PickedUpAt
+	}
+	return rental.StartDate
+}
+
+// pickupRental handles POST /api/v1/rentals/{id}/pickup: the car of a
+// confirmed rental is handed over, either by staff (an admin) or by the
+// renter collecting the keys.
+func (s *Server) pickupRental(w http.ResponseWriter, r *http.Request, rentalID uint) {
+	userID, ok := authhttp.UserIDFromContext(r.Context())
+	if !ok {
+		RespondWithError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	role := getRoleFromContext(r)
+
+	var rental entity.Rental
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.First(&rental, rentalID).Error; err != nil {
+			return err
+		}
+		if role != entity.UserRoleAdmin && rental.UserID != userID {
+			return errors.New("forbidden")
+		}
+		reason := "keys handed over by staff"
+		if role != entity.UserRoleAdmin {
+			reason = "keys collected by the renter"
+		}
+		return s.rentals.Fire(tx, &rental, rentalstate.EventPickup, requestActor(r), reason)
+	})
+
+	if err != nil {
+		switch {
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			RespondWithError(w, http.StatusNotFound, "rental not found")
+		case err.Error() == "forbidden":
+			RespondWithError(w, http.StatusForbidden, "forbidden")
+		case errors.Is(err, rentalstate.ErrInvalidTransition):
+			RespondWithError(w, http.StatusBadRequest, "only confirmed rentals can be picked up")
+		case errors.Is(err, errPickupTooEarly):
+			RespondWithError(w, http.StatusConflict, "it is too early to pick up the car")
+		case errors.Is(err, errPickupTooLate):
+			RespondWithError(w, http.StatusConflict, "the pickup window of this booking has closed")
+		default:
+			RespondWithError(w, http.StatusInternalServerError, "could not pick up rental")
+		}
+		return
+	}
+
+	RespondWithJSON(w, http.StatusOK, map[string]any{
+		"rental_id":    rental.ID,
+		"status":       rental.Status,
+		"picked_up_at": rental.PickedUpAt,
+		"refunded":     rental.Refunded,
+		"end_date":     rental.EndDate,
+	})
+}
+
+// runNoShowDetection periodically marks confirmed rentals that were not picked up as no-shows.
+func (s *Server) runNoShowDetection() {
+	ticker := time.NewTicker(rentalNoShowTick)
+	defer ticker.Stop()
+
+	for {
+		if n, err := s.markNoShowRentals(time.Now()); err != nil {
+			log.Printf("no-show detection failed: %v", err)
+		} else if n > 0 {
+			log.Printf("marked %d rental(s) as no-show", n)
+		}
+		<-ticker.C
+	}
+}
+
+// markNoShowRentals moves every confirmed rental past its pickup window to
+// no_show, each in its own transaction. A rental picked up or cancelled by a
+// request in the meantime is left alone.
+func (s *Server) markNoShowRentals(now time.Time) (int, error) {
+	cfg := s.rentalPickup
+	var ids []uint
+	if err := s.db.Model(&entity.Rental{}).
+		Where("status = ? AND (start_date <= ? OR end_date <= ?)", entity.RentalStatusConfirmed,
+			now.Add(-cfg.NoShowAfter).UTC(), now.UTC()).
+		Order("id asc").
+		Pluck("id", &ids).Error; err != nil {
+		return 0, err
+	}
+
+	marked := 0
+	for _, id := range ids {
+		err := s.db.Transaction(func(tx *gorm.DB) error {
+			var rental entity.Rental
+			if err := tx.First(&rental, id).Error; err != nil {
+				return err
+			}
+			reason := "not picked up by " + cfg.noShowAt(rental).Format(time.RFC3339)
+			return s.rentals.Fire(tx, &rental, rentalstate.EventNoShow, nil, reason)
+		})
+		if errors.Is(err, rentalstate.ErrInvalidTransition) || errors.Is(err, gorm.ErrRecordNotFound) {
+			continue
+		}
+		if err != nil {
+			return marked, err
+		}
+		marked++
+	}
+	return marked, nil
+}
+
+// notifyNoShow tells the renter that the booking was released; the price is
+// kept and the deposit comes back.
+func notifyNoShow(tx *gorm.DB, c rentalstate.Change) error {
+	rentalID := c.Rental.ID
+	return notifyUser(tx, c.Rental.UserID, &rentalID, entity.NotificationRentalNoShow,
+		fmt.Sprintf("Booking #%d was not picked up", rentalID),
+		fmt.Sprintf("The car booked for %s was %s and has been released. The deposit has been returned to your balance.",
+			c.Rental.StartDate.UTC().Format("2006-01-02 15:04 MST"), c.Reason))
+}
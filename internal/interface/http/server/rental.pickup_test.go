@@ -0,0 +1,87 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/CMPNION/Car-Rental-API.git/internal/entity"
+	"github.com/CMPNION/Car-Rental-API.git/internal/usecase/rentalstate"
+	"gorm.io/gorm"
+)
+
+func TestLatePickupRefund(t *testing.T) {
+	start := time.Date(2026, 3, 2, 10, 0, 0, 0, time.UTC)
+	end := start.Add(10 * time.Hour)
+
+	tests := []struct {
+		name       string
+		pickedUp   time.Time
+		wantAmount entity.Money
+		wantHours  int
+	}{
+		{"early", start.Add(-20 * time.Minute), 0, 0},
+		{"on time", start, 0, 0},
+		{"within the first hour", start.Add(59 * time.Minute), 0, 0},
+		{"partial hours round down", start.Add(2*time.Hour + 59*time.Minute), 200, 2},
+		{"after the end", end.Add(time.Hour), 1000, 11},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			amount, hours := latePickupRefund(1000, start, end, tt.pickedUp)
+			if amount != tt.wantAmount || hours != tt.wantHours {
+				t.Errorf("got %s for %d hours, want %s for %d hours", amount, hours, tt.wantAmount, tt.wantHours)
+			}
+		})
+	}
+}
+
+// The seeded pricing rules have no early_return policy: a late pickup is
+// still billed from the pickup, and returning early refunds nothing more.
+func TestPickupBillsFromPickupUnderSeededRules(t *testing.T) {
+	s := newTestServer(t)
+	now := time.Now().UTC()
+
+	user := entity.User{FirstName: "U", LastName: "S", Email: "u@example.com", PasswordHash: "x", Role: entity.UserRoleClient}
+	car := entity.Car{Mark: "Kia", CarModel: "Rio", Category: entity.CarCategoryEconomy,
+		Status: entity.CarStatusBooked, PricePerHour: 100}
+	for _, v := range []any{&user, &car} {
+		if err := s.db.Create(v).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	start := now.Add(-time.Hour - 5*time.Minute)
+	rental := entity.Rental{UserID: user.ID, CarID: car.ID, StartDate: start, EndDate: start.Add(10 * time.Hour),
+		TotalPrice: 1000, Status: entity.RentalStatusConfirmed}
+	if err := s.db.Create(&rental).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/rentals/1/pickup", nil)
+	s.pickupRental(rec, withUser(req, user.ID, entity.UserRoleClient), rental.ID)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("pickup: status %d: %s", rec.Code, rec.Body)
+	}
+
+	if err := s.db.First(&rental, rental.ID).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := s.db.First(&user, user.ID).Error; err != nil {
+		t.Fatal(err)
+	}
+	if rental.Refunded != 100 || user.Balance != 100 {
+		t.Fatalf("refunded %s, balance %s after picking up an hour late, want 1.00 and 1.00", rental.Refunded, user.Balance)
+	}
+
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		return s.rentals.Fire(tx, &rental, rentalstate.EventFinish, nil, "")
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rental.Refunded != 100 {
+		t.Errorf("refunded %s after an early return without early_return, want 1.00", rental.Refunded)
+	}
+}
@@ -0,0 +1,75 @@
+package server
+
+import (
+	"math"
+	"time"
+
+	"github.com/CMPNION/Car-Rental-API.git/internal/entity"
+	"github.com/CMPNION/Car-Rental-API.git/internal/usecase/rentalstate"
+	"gorm.io/gorm"
+)
+
+// markReturned records when the car came back.
+func markReturned(tx *gorm.DB, c rentalstate.Change) error {
+	at := c.At
+	c.Rental.ReturnedAt = &at
+	return tx.Model(&entity.Rental{}).Where("id = ?", c.Rental.ID).Update("returned_at", at).Error
+}
+
+// rentalUsageEnd is when the renter gave the car back: the return, the booked
+// end for rentals completed before returns were recorded, or zero while the
+// car is still out.
+func rentalUsageEnd(rental entity.Rental) time.Time {
+	if rental.ReturnedAt != nil {
+		return *rental.ReturnedAt
+	}
+	if rental.Status == entity.RentalStatusCompleted {
+		return rental.EndDate
+	}
+	return time.Time{}
+}
+
+// earlyReturnRefund is what the policy pays back of paid for the booking from
+// start to end of a car picked up at pickedUpAt and returned at returnedAt,
+// and how many whole hours of it were left unused. The hours before a late
+// pickup were already paid back at the pickup, see latePickupRefund, so paid
+// covers the rest of the booking and so do the unused hours. Nothing is paid
+// back below MinUnusedHours.
+func earlyReturnRefund(policy entity.EarlyReturnPolicy, paid entity.Money, start, end, pickedUpAt, returnedAt time.Time) (entity.Money, int) {
+	billed := end.Sub(start).Hours() - float64(latePickupHours(start, pickedUpAt))
+	unused := math.Floor(billed - returnedAt.Sub(pickedUpAt).Hours())
+	if billed <= 0 || unused <= 0 || unused < policy.MinUnusedHours {
+		return 0, 0
+	}
+	share := math.Min(unused/billed, 1)
+	switch policy.Refund {
+	case entity.EarlyReturnRefundFull:
+	case entity.EarlyReturnRefundPartial:
+		share *= policy.Percent / 100
+	default:
+		return 0, int(unused)
+	}
+	return paid.Mul(share), int(unused)
+}
+
+// refundEarlyReturn pays the unused hours of a rental back to the balance
+// under the early_return policy of the rules that priced it. It runs before loyalty points are earned, so they are earned on what was kept.
+func refundEarlyReturn(tx *gorm.DB, c rentalstate.Change) error {
+	rental := c.Rental
+	rules, ok, err := rentalPricingRules(tx, *rental)
+	if err != nil || !ok || rules.EarlyReturn == nil {
+		return err
+	}
+
+	amount, _ := earlyReturnRefund(*rules.EarlyReturn, rentalCashPaid(*rental),
+		rental.StartDate, rental.EndDate, rentalUsageStart(*rental), c.At)
+	if amount <= 0 {
+		return nil
+	}
+	if err := refundRental(tx, *rental, amount); err != nil {
+		return err
+	}
+	rental.Refunded += amount
+	return tx.Model(&entity.Rental{}).Where("id = ?", rental.ID).
+		Update("refunded", gorm.Expr("refunded + ?", amount)).Error
+}
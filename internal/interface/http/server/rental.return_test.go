@@ -0,0 +1,45 @@
+package server
+
+import (
+	"testing"
+	"time"
+
+	"github.com/CMPNION/Car-Rental-API.git/internal/entity"
+)
+
+func TestEarlyReturnRefund(t *testing.T) {
+	start := time.Date(2026, 3, 2, 10, 0, 0, 0, time.UTC)
+	end := start.Add(10 * time.Hour)
+	full := entity.EarlyReturnPolicy{Refund: entity.EarlyReturnRefundFull}
+	half := entity.EarlyReturnPolicy{Refund: entity.EarlyReturnRefundPartial, Percent: 50}
+
+	tests := []struct {
+		name       string
+		policy     entity.EarlyReturnPolicy
+		pickedUp   time.Time
+		returned   time.Time
+		wantAmount entity.Money
+		wantUnused int
+	}{
+		{"used to the end", full, start, end, 0, 0},
+		{"returned late", full, start, end.Add(time.Hour), 0, 0},
+		{"four hours early", full, start, end.Add(-4 * time.Hour), 400, 4},
+		{"partial hours round down", full, start, end.Add(-4*time.Hour - 59*time.Minute), 400, 4},
+		{"partial refund", half, start, end.Add(-4 * time.Hour), 200, 4},
+		{"no refund policy", entity.EarlyReturnPolicy{Refund: entity.EarlyReturnRefundNone}, start, end.Add(-4 * time.Hour), 0, 4},
+		{"below min_unused_hours", entity.EarlyReturnPolicy{Refund: entity.EarlyReturnRefundFull, MinUnusedHours: 5}, start, end.Add(-4 * time.Hour), 0, 0},
+		// The hours before a late pickup were refunded at the pickup, paid
+		// covers the eight hours billed from then on.
+		{"late pickup", full, start.Add(2*time.Hour + 30*time.Minute), end, 0, 0},
+		{"late pickup and early return", full, start.Add(2 * time.Hour), end.Add(-3 * time.Hour), 375, 3},
+		{"early pickup", full, start.Add(-30 * time.Minute), end.Add(-2 * time.Hour), 100, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			amount, unused := earlyReturnRefund(tt.policy, 1000, start, end, tt.pickedUp, tt.returned)
+			if amount != tt.wantAmount || unused != tt.wantUnused {
+				t.Errorf("got %s for %d hours, want %s for %d hours", amount, unused, tt.wantAmount, tt.wantUnused)
+			}
+		})
+	}
+}
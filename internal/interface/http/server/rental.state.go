@@ -0,0 +1,178 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/CMPNION/Car-Rental-API.git/internal/entity"
+	authhttp "github.com/CMPNION/Car-Rental-API.git/internal/interface/http/auth"
+	"github.com/CMPNION/Car-Rental-API.git/internal/usecase/rentalstate"
+	"gorm.io/gorm"
+)
+
+// newRentalMachine wires the guards and side effects of every rental
+// transition: money, loyalty points, promo uses, the car's status and its
+// history, and notifications.
+func newRentalMachine(expiry rentalExpiryConfig, pickup rentalPickupConfig) *rentalstate.Machine {
+	m := rentalstate.NewMachine()
+
+	m.Guard(rentalstate.EventPay, func(_ *gorm.DB, c rentalstate.Change) error {
+		if !c.At.Before(expiry.payBy(*c.Rental)) {
+			return errPaymentWindowClosed
+		}
+		return nil
+	})
+	m.OnEvent(rentalstate.EventPay, carEventEffect(entity.CarEventRentalPaid))
+
+	m.Guard(rentalstate.EventPickup, pickup.pickupGuard)
+	m.OnEvent(rentalstate.EventPickup, markPickedUp)
+	m.OnEvent(rentalstate.EventPickup, refundLatePickup)
+	m.OnEvent(rentalstate.EventPickup, carEventEffect(entity.CarEventRentalPickedUp))
+
+	m.OnEvent(rentalstate.EventFinish, carEventEffect(entity.CarEventRentalCompleted))
+	m.OnEvent(rentalstate.EventFinish, markReturned)
+	m.OnEvent(rentalstate.EventFinish, chargeLateReturn)
+	m.OnEvent(rentalstate.EventFinish, refundEarlyReturn)
+	// The deposit stays held for the inspection after the return, see runDepositRelease.
+	m.OnEvent(rentalstate.EventFinish, func(tx *gorm.DB, c rentalstate.Change) error {
+		return earnLoyaltyPoints(tx, *c.Rental, c.At)
+	})
+	m.OnEvent(rentalstate.EventFinish, freeCarEffect)
+
+	// The refund of a paid rental depends on the request, see cancelRental;
+	// its deposit always comes back.
+	m.OnEvent(rentalstate.EventCancel, func(tx *gorm.DB, c rentalstate.Change) error {
+		if !slices.Contains(rentalstate.Paid, c.From) {
+			return nil
+		}
+		_, err := releaseDeposit(tx, *c.Rental)
+		return err
+	})
+	for _, event := range []string{rentalstate.EventCancel, rentalstate.EventExpire} {
+		m.OnEvent(event, func(tx *gorm.DB, c rentalstate.Change) error {
+			return releasePromoRedemption(tx, c.Rental.ID)
+		})
+		m.OnEvent(event, carEventEffect(entity.CarEventRentalCancelled))
+		m.OnEvent(event, freeCarEffect)
+	}
+
+	// Voiding gives back everything the renter paid or spent; the caller
+	// decides what happens to the car.
+	m.OnEvent(rentalstate.EventVoid, func(tx *gorm.DB, c rentalstate.Change) error {
+		if slices.Contains(rentalstate.Paid, c.From) {
+			if err := refundRental(tx, *c.Rental, rentalCashPaid(*c.Rental)); err != nil {
+				return err
+			}
+			if err := refundLoyaltyPoints(tx, *c.Rental, c.At); err != nil {
+				return err
+			}
+			if _, err := releaseDeposit(tx, *c.Rental); err != nil {
+				return err
+			}
+		}
+		if c.From == entity.RentalStatusPending || c.From == entity.RentalStatusConfirmed {
+			return releasePromoRedemption(tx, c.Rental.ID)
+		}
+		return nil
+	})
+	m.OnEvent(rentalstate.EventVoid, carEventEffect(entity.CarEventRentalCancelled))
+
+	m.OnEvent(rentalstate.EventExpire, func(tx *gorm.DB, c rentalstate.Change) error {
+		rentalID := c.Rental.ID
+		return notifyUser(tx, c.Rental.UserID, &rentalID, entity.NotificationRentalExpired,
+			fmt.Sprintf("Booking #%d has expired", rentalID),
+			fmt.Sprintf("Your booking for %s was %s and has been released.",
+				c.Rental.StartDate.Format("2006-01-02 15:04 MST"), c.Reason))
+	})
+
+	m.OnEvent(rentalstate.EventOverdue, notifyOverdue)
+
+	// A no-show keeps the price but not the deposit.
+	m.OnEvent(rentalstate.EventNoShow, func(tx *gorm.DB, c rentalstate.Change) error {
+		_, err := releaseDeposit(tx, *c.Rental)
+		return err
+	})
+	m.OnEvent(rentalstate.EventNoShow, notifyNoShow)
+	m.OnEvent(rentalstate.EventNoShow, carEventEffect(entity.CarEventRentalCancelled))
+	m.OnEvent(rentalstate.EventNoShow, freeCarEffect)
+
+	return m
+}
+
+// carEventEffect records the transition on the rented car's history.
+func carEventEffect(eventType string) rentalstate.Hook {
+	return func(tx *gorm.DB, c rentalstate.Change) error {
+		rentalID := c.Rental.ID
+		return recordCarEvent(tx, nil, &entity.CarEvent{
+			CarID:    c.Rental.CarID,
+			Type:     eventType,
+			ActorID:  c.ActorID,
+			RentalID: &rentalID,
+			Note:     c.Rental.StartDate.Format(time.RFC3339) + " - " + c.Rental.EndDate.Format(time.RFC3339),
+		})
+	}
+}
+
+// freeCarEffect makes the rented car available again.
+func freeCarEffect(tx *gorm.DB, c rentalstate.Change) error {
+	return tx.Model(&entity.Car{}).Where("id = ?", c.Rental.CarID).
+		Update("status", entity.CarStatusAvailable).Error
+}
+
+// requestActor is the authenticated user behind a request, nil for none.
+func requestActor(r *http.Request) *uint {
+	if userID, ok := authhttp.UserIDFromContext(r.Context()); ok {
+		return &userID
+	}
+	return nil
+}
+
+// rentalHistory handles GET /api/v1/rentals/{id}/history: the rental's
+// transitions, extensions and modifications in the order they happened.
+func (s *Server) rentalHistory(w http.ResponseWriter, r *http.Request, rentalID uint) {
+	userID, ok := authhttp.UserIDFromContext(r.Context())
+	if !ok {
+		RespondWithError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	var rental entity.Rental
+	if err := s.db.First(&rental, rentalID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			RespondWithError(w, http.StatusNotFound, "rental not found")
+			return
+		}
+		RespondWithError(w, http.StatusInternalServerError, "database error")
+		return
+	}
+	if getRoleFromContext(r) != entity.UserRoleAdmin && rental.UserID != userID {
+		RespondWithError(w, http.StatusForbidden, "forbidden")
+		return
+	}
+
+	var transitions []entity.RentalTransition
+	if err := s.db.Where("rental_id = ?", rentalID).Order("id asc").Find(&transitions).Error; err != nil {
+		RespondWithError(w, http.StatusInternalServerError, "database error")
+		return
+	}
+	var extensions []entity.RentalExtension
+	if err := s.db.Where("rental_id = ?", rentalID).Order("id asc").Find(&extensions).Error; err != nil {
+		RespondWithError(w, http.StatusInternalServerError, "database error")
+		return
+	}
+	var modifications []entity.RentalModification
+	if err := s.db.Where("rental_id = ?", rentalID).Order("id asc").Find(&modifications).Error; err != nil {
+		RespondWithError(w, http.StatusInternalServerError, "database error")
+		return
+	}
+	RespondWithJSON(w, http.StatusOK, map[string]any{
+		"rental_id":     rental.ID,
+		"status":        rental.Status,
+		"transitions":   transitions,
+		"extensions":    extensions,
+		"modifications": modifications,
+	})
+}
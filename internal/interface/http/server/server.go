@@ -9,6 +9,7 @@ import (
 
 	"gorm.io/gorm"
 
+	"github.com/CMPNION/Car-Rental-API.git/internal/infra/database"
 	authhttp "github.com/CMPNION/Car-Rental-API.git/internal/interface/http/auth"
 	hellohttp "github.com/CMPNION/Car-Rental-API.git/internal/interface/http/hello"
 	authuc "github.com/CMPNION/Car-Rental-API.git/internal/usecase/auth"
@@ -22,6 +23,12 @@ func GetNewServer(addr string, db *gorm.DB) *Server {
 		db:     db,
 		addr:   addr,
 	}
+	srv.searchFTS = database.HasCarSearchIndex(db)
+	srv.catalogCache = newResponseCache()
+	srv.rentalExpiry = loadRentalExpiryConfig()
+	srv.rentalPickup = loadRentalPickupConfig()
+	srv.depositInspection = loadDepositInspectionWindow()
+	srv.rentals = newRentalMachine(srv.rentalExpiry, srv.rentalPickup)
 	srv.registerCarRoutes()
 	srv.registerRoutes()
 
@@ -37,6 +44,12 @@ func (s *Server) Start() error {
 		ReadTimeout:  15 * time.Second,
 	}
 
+	go s.runTelemetryRetention()
+	go s.runRentalExpiry()
+	go s.runOverdueDetection()
+	go s.runNoShowDetection()
+	go s.runDepositRelease()
+
 	println("Starting server on", s.addr)
 	return srv.ListenAndServe()
 }
@@ -59,17 +72,40 @@ func (s *Server) registerRoutes() {
 
 	s.router.Handle("/api/v1/rentals", jwtMiddleware(http.HandlerFunc(s.rentalsHandler)))
 	s.router.Handle("/api/v1/rentals/", jwtMiddleware(http.HandlerFunc(s.rentalActionHandler)))
+	s.router.Handle("/api/v1/rentals/quote", jwtMiddleware(http.HandlerFunc(s.quoteRentalHandler)))
 	s.router.Handle("/api/v1/users/balance", jwtMiddleware(http.HandlerFunc(s.userBalanceHandler)))
 	s.router.Handle("/api/v1/users/me", jwtMiddleware(http.HandlerFunc(s.userProfileHandler)))
+	s.router.Handle("/api/v1/users/me/loyalty", jwtMiddleware(http.HandlerFunc(s.userLoyaltyHandler)))
+	s.router.Handle("/api/v1/users/me/notifications", jwtMiddleware(http.HandlerFunc(s.userNotificationsHandler)))
+	s.router.Handle("/api/v1/users/me/notifications/", jwtMiddleware(http.HandlerFunc(s.userNotificationsHandler)))
+	s.router.HandleFunc("/api/v1/exchange-rates", s.exchangeRatesHandler)
 	s.router.Handle("/api/v1/transactions", jwtMiddleware(http.HandlerFunc(s.transactionsHandler)))
+	s.router.Handle("/api/v1/invoices", jwtMiddleware(http.HandlerFunc(s.invoicesHandler)))
+	s.router.Handle("/api/v1/invoices/", jwtMiddleware(http.HandlerFunc(s.invoicesHandler)))
 	s.router.Handle("/api/v1/admin/metrics", jwtMiddleware(http.HandlerFunc(s.adminMetricsHandler)))
+	s.router.HandleFunc("/api/v1/admin/cars/", s.adminOnly(s.adminCarsHandler))
+	s.router.HandleFunc("/api/v1/admin/documents/expiring", s.adminOnly(s.expiringDocumentsHandler))
+	s.router.HandleFunc("/api/v1/admin/categories", s.adminOnly(s.adminCategoriesHandler))
+	s.router.HandleFunc("/api/v1/admin/categories/", s.adminOnly(s.adminCategoriesHandler))
+	s.router.HandleFunc("/api/v1/admin/pricing/rules", s.adminOnly(s.pricingRulesHandler))
+	s.router.HandleFunc("/api/v1/admin/pricing/rules/", s.adminOnly(s.pricingRulesHandler))
+	s.router.HandleFunc("/api/v1/admin/pricing/simulate", s.adminOnly(s.simulatePricingHandler))
+	s.router.HandleFunc("/api/v1/admin/promo-codes", s.adminOnly(s.promoCodesHandler))
+	s.router.HandleFunc("/api/v1/admin/promo-codes/", s.adminOnly(s.promoCodesHandler))
+	s.router.HandleFunc("/api/v1/admin/exchange-rates", s.adminOnly(s.adminExchangeRatesHandler))
+	s.router.HandleFunc("/api/v1/admin/exchange-rates/", s.adminOnly(s.adminExchangeRatesHandler))
+	s.router.HandleFunc("/api/v1/admin/tax-rates", s.adminOnly(s.taxRatesHandler))
+	s.router.HandleFunc("/api/v1/admin/tax-rates/", s.adminOnly(s.taxRatesHandler))
+	s.router.HandleFunc("/api/v1/admin/company", s.adminOnly(s.companyHandler))
+	s.router.HandleFunc("/api/v1/telemetry", s.telemetryIngestHandler)
 }
 
 func (*Server) withCORS(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Access-Control-Allow-Origin", "*")
 		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, PATCH, DELETE, OPTIONS")
-		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
+		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization, If-Match, If-None-Match, If-Modified-Since")
+		w.Header().Set("Access-Control-Expose-Headers", "ETag, Last-Modified")
 
 		if r.Method == http.MethodOptions {
 			w.WriteHeader(http.StatusNoContent)
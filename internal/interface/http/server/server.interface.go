@@ -3,8 +3,11 @@ package server
 import (
 	"net/http"
 	"sync"
+	"time"
 
 	"gorm.io/gorm"
+
+	"github.com/CMPNION/Car-Rental-API.git/internal/usecase/rentalstate"
 )
 
 type Server struct {
@@ -13,4 +16,12 @@ type Server struct {
 	db        *gorm.DB
 	addr      string
 	jwtSecret string
+	searchFTS bool
+
+	catalogCache *responseCache
+	rentals      *rentalstate.Machine
+	rentalExpiry rentalExpiryConfig
+	rentalPickup rentalPickupConfig
+	// depositInspection is how long the deposit stays held after the return.
+	depositInspection time.Duration
 }
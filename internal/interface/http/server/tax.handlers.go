@@ -0,0 +1,180 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/CMPNION/Car-Rental-API.git/internal/entity"
+	authhttp "github.com/CMPNION/Car-Rental-API.git/internal/interface/http/auth"
+	"gorm.io/gorm"
+)
+
+// taxRateRequest is the payload of POST /api/v1/admin/tax-rates.
+type taxRateRequest struct {
+	Jurisdiction  string     `json:"jurisdiction"`
+	Name          string     `json:"name"`
+	Percent       *float64   `json:"percent"`
+	EffectiveFrom *time.Time `json:"effective_from"`
+}
+
+// taxRatesHandler handles /api/v1/admin/tax-rates[/{id}] (admin).
+// Rates are never edited; a rate that is not in effect yet may be deleted.
+func (s *Server) taxRatesHandler(w http.ResponseWriter, r *http.Request) {
+	rest := strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/v1/admin/tax-rates"), "/")
+
+	if rest == "" {
+		switch r.Method {
+		case http.MethodGet:
+			s.listTaxRates(w, r)
+		case http.MethodPost:
+			s.createTaxRate(w, r)
+		default:
+			RespondWithError(w, http.StatusMethodNotAllowed, "method not allowed")
+		}
+		return
+	}
+
+	id, err := strconv.Atoi(rest)
+	if err != nil || id <= 0 {
+		RespondWithError(w, http.StatusBadRequest, "invalid id")
+		return
+	}
+	if r.Method != http.MethodDelete {
+		RespondWithError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	res := s.db.Unscoped().Where("id = ? AND effective_from > ?", id, time.Now().UTC()).
+		Delete(&entity.TaxRate{})
+	if res.Error != nil {
+		RespondWithError(w, http.StatusInternalServerError, "database error")
+		return
+	}
+	if res.RowsAffected == 0 {
+		RespondWithError(w, http.StatusConflict, "only rates that are not in effect yet can be deleted")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) listTaxRates(w http.ResponseWriter, r *http.Request) {
+	page, err := parsePageRequest(r)
+	if err != nil {
+		RespondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	q := s.db.Model(&entity.TaxRate{})
+	if v := r.URL.Query().Get("jurisdiction"); v != "" {
+		q = q.Where("jurisdiction = ?", normalizeJurisdiction(v))
+	}
+	result, err := paginate(q, page, newestFirst, func(rate entity.TaxRate) (any, uint) {
+		return rate.ID, rate.ID
+	})
+	respondWithPage(w, result, err)
+}
+
+func (s *Server) createTaxRate(w http.ResponseWriter, r *http.Request) {
+	var req taxRateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		RespondWithError(w, http.StatusBadRequest, "invalid JSON")
+		return
+	}
+
+	now := time.Now().UTC()
+	rate := entity.TaxRate{
+		Jurisdiction:  normalizeJurisdiction(req.Jurisdiction),
+		Name:          strings.TrimSpace(req.Name),
+		EffectiveFrom: now,
+	}
+	if rate.Name == "" {
+		rate.Name = "VAT"
+	}
+	switch {
+	case !jurisdictionPattern.MatchString(rate.Jurisdiction):
+		RespondWithError(w, http.StatusBadRequest, "jurisdiction must be an ISO 3166 code, e.g. KZ or KZ-ALA")
+		return
+	case req.Percent == nil || *req.Percent < 0 || *req.Percent > 100:
+		RespondWithError(w, http.StatusBadRequest, "percent must be within 0..100")
+		return
+	}
+	rate.Percent = *req.Percent
+	if req.EffectiveFrom != nil {
+		if req.EffectiveFrom.Before(now.Add(-time.Minute)) {
+			RespondWithError(w, http.StatusBadRequest, "effective_from cannot be in the past")
+			return
+		}
+		rate.EffectiveFrom = req.EffectiveFrom.UTC()
+	}
+	if userID, ok := authhttp.UserIDFromContext(r.Context()); ok {
+		rate.CreatedBy = &userID
+	}
+
+	if err := s.db.Create(&rate).Error; err != nil {
+		RespondWithError(w, http.StatusInternalServerError, "database error")
+		return
+	}
+	RespondWithJSON(w, http.StatusCreated, rate)
+}
+
+// companyHandler handles GET/PUT /api/v1/admin/company (admin): the
+// requisites printed on new invoices. Issued invoices keep their copy.
+func (s *Server) companyHandler(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		company, err := companyProfile(s.db)
+		if err != nil {
+			RespondWithError(w, http.StatusInternalServerError, "database error")
+			return
+		}
+		RespondWithJSON(w, http.StatusOK, company)
+	case http.MethodPut:
+		var req entity.CompanyRequisites
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			RespondWithError(w, http.StatusBadRequest, "invalid JSON")
+			return
+		}
+		req = entity.CompanyRequisites{
+			Name:     strings.TrimSpace(req.Name),
+			TaxID:    strings.TrimSpace(req.TaxID),
+			Address:  strings.TrimSpace(req.Address),
+			BankName: strings.TrimSpace(req.BankName),
+			IBAN:     strings.ToUpper(strings.ReplaceAll(req.IBAN, " ", "")),
+			BIC:      strings.ToUpper(strings.TrimSpace(req.BIC)),
+		}
+		if req.Name == "" || req.TaxID == "" || req.Address == "" {
+			RespondWithError(w, http.StatusBadRequest, "name, tax_id and address are required")
+			return
+		}
+
+		var company entity.CompanyProfile
+		err := s.db.Transaction(func(tx *gorm.DB) error {
+			var err error
+			if company, err = companyProfile(tx); err != nil {
+				return err
+			}
+			company.CompanyRequisites = req
+			return tx.Save(&company).Error
+		})
+		if err != nil {
+			RespondWithError(w, http.StatusInternalServerError, "database error")
+			return
+		}
+		RespondWithJSON(w, http.StatusOK, company)
+	default:
+		RespondWithError(w, http.StatusMethodNotAllowed, "method not allowed")
+	}
+}
+
+// companyProfile returns the single company row, empty until an admin fills it in.
+func companyProfile(db *gorm.DB) (entity.CompanyProfile, error) {
+	var company entity.CompanyProfile
+	err := db.Order("id asc").First(&company).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return company, nil
+	}
+	return company, err
+}
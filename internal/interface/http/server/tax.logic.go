@@ -0,0 +1,88 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/CMPNION/Car-Rental-API.git/internal/entity"
+	"gorm.io/gorm"
+)
+
+// jurisdictionPattern accepts ISO 3166-1 countries ("KZ") and ISO 3166-2
+// subdivisions ("KZ-ALA").
+var jurisdictionPattern = regexp.MustCompile(`^[A-Z]{2}(-[A-Z0-9]{1,3})?$`)
+
+// normalizeJurisdiction upper-cases a jurisdiction code; an empty code is the default one.
+func normalizeJurisdiction(code string) string {
+	code = strings.ToUpper(strings.TrimSpace(code))
+	if code == "" {
+		return entity.DefaultJurisdiction
+	}
+	return code
+}
+
+// taxRateFor returns the tax rate of the jurisdiction in effect at the given
+// time. A subdivision without a rate of its own falls back to its country;
+// ok is false when neither has one, and nothing is taxed then.
+func taxRateFor(db *gorm.DB, jurisdiction string, at time.Time) (entity.TaxRate, bool, error) {
+	codes := []string{jurisdiction}
+	if country, _, found := strings.Cut(jurisdiction, "-"); found {
+		codes = append(codes, country)
+	}
+	for _, code := range codes {
+		var rate entity.TaxRate
+		err := db.Where("jurisdiction = ? AND effective_from <= ?", code, at.UTC()).
+			Order("effective_from desc").Order("id desc").
+			First(&rate).Error
+		if err == nil {
+			return rate, true, nil
+		}
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return rate, false, err
+		}
+	}
+	return entity.TaxRate{}, false, nil
+}
+
+// applyTaxes adds tax on top of the net price. The rental itself, after
+// discounts, and every fee are split into net, tax and gross separately, and
+// each tax is rounded once; the tax line is the sum of those.
+func applyTaxes(db *gorm.DB, b *entity.PriceBreakdown, car entity.Car, at time.Time) error {
+	rate, ok, err := taxRateFor(db, normalizeJurisdiction(car.Jurisdiction), at)
+	if err != nil {
+		return err
+	}
+	name, percent := "VAT", 0.0
+	if ok {
+		name, percent = rate.Name, rate.Percent
+	}
+
+	b.TaxSplits = []entity.TaxSplit{}
+	var tax entity.Money
+	split := func(code, name string, net entity.Money) {
+		t := net.Mul(percent / 100)
+		b.TaxSplits = append(b.TaxSplits, entity.TaxSplit{
+			Code:       code,
+			Name:       name,
+			Net:        net,
+			TaxPercent: percent,
+			Tax:        t,
+			Gross:      net + t,
+		})
+		tax += t
+	}
+	split("rental", "Rental", sumPriceLines(rentalSubtotal(*b), b.Discounts))
+	for _, fee := range b.Fees {
+		split(fee.Code, fee.Name, fee.Amount)
+	}
+
+	b.Taxes = []entity.PriceLine{}
+	addPriceLine(&b.Taxes, "vat", fmt.Sprintf("%s %g%%", name, percent), tax)
+	totalPriceBreakdown(b)
+	b.Tax = tax
+	b.Net = b.Total - tax
+	return nil
+}
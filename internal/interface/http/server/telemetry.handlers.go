@@ -0,0 +1,264 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/CMPNION/Car-Rental-API.git/internal/entity"
+	authhttp "github.com/CMPNION/Car-Rental-API.git/internal/interface/http/auth"
+	"gorm.io/gorm"
+)
+
+type telemetryBatchRequest struct {
+	Readings []TelemetryReading `json:"readings"`
+}
+
+type rejectedReading struct {
+	Index  int    `json:"index"`
+	Reason string `json:"reason"`
+}
+
+// telemetryIngestHandler handles POST /api/v1/telemetry for tracker devices.
+// Devices authenticate with their own credentials, not with user JWTs.
+func (s *Server) telemetryIngestHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		RespondWithError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	device, err := authenticateDevice(s.db, r.Header.Get("Authorization"))
+	if err != nil {
+		if errors.Is(err, errInvalidDeviceToken) {
+			RespondWithError(w, http.StatusUnauthorized, "invalid device credentials")
+			return
+		}
+		RespondWithError(w, http.StatusInternalServerError, "database error")
+		return
+	}
+
+	var req telemetryBatchRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<20)).Decode(&req); err != nil {
+		RespondWithError(w, http.StatusBadRequest, "invalid JSON")
+		return
+	}
+	if len(req.Readings) == 0 {
+		RespondWithError(w, http.StatusBadRequest, "readings are required")
+		return
+	}
+	if len(req.Readings) > maxTelemetryBatch {
+		RespondWithError(w, http.StatusBadRequest, "too many readings in one batch")
+		return
+	}
+
+	now := time.Now()
+	points := make([]entity.TelemetryPoint, 0, len(req.Readings))
+	rejected := []rejectedReading{}
+	for i, reading := range req.Readings {
+		point, reason := toTelemetryPoint(device.CarID, reading, now)
+		if reason != "" {
+			rejected = append(rejected, rejectedReading{Index: i, Reason: reason})
+			continue
+		}
+		points = append(points, point)
+	}
+
+	var stored int64
+	if len(points) > 0 {
+		err = s.db.Transaction(func(tx *gorm.DB) error {
+			var err error
+			stored, err = storeTelemetry(tx, device, points)
+			return err
+		})
+		if err != nil {
+			RespondWithError(w, http.StatusInternalServerError, "could not store telemetry")
+			return
+		}
+	}
+
+	RespondWithJSON(w, http.StatusAccepted, map[string]any{
+		"accepted":   len(points),
+		"stored":     stored,
+		"duplicates": int64(len(points)) - stored,
+		"rejected":   rejected,
+	})
+}
+
+// carDeviceHandler handles POST /api/v1/admin/cars/{id}/device: it issues or
+// rotates the tracker credentials of a car. The token is only shown once.
+func (s *Server) carDeviceHandler(w http.ResponseWriter, r *http.Request, carID uint) {
+	if r.Method != http.MethodPost {
+		RespondWithError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	deviceID, token, secretHash, err := newDeviceCredentials()
+	if err != nil {
+		RespondWithError(w, http.StatusInternalServerError, "could not generate credentials")
+		return
+	}
+
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		var car entity.Car
+		if err := tx.First(&car, carID).Error; err != nil {
+			return err
+		}
+
+		var device entity.CarDevice
+		err := tx.Unscoped().Where("car_id = ?", carID).First(&device).Error
+		switch {
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			return tx.Create(&entity.CarDevice{CarID: carID, DeviceID: deviceID, SecretHash: secretHash}).Error
+		case err != nil:
+			return err
+		}
+		return tx.Unscoped().Model(&device).Updates(map[string]any{
+			"device_id":    deviceID,
+			"secret_hash":  secretHash,
+			"last_seen_at": nil,
+			"deleted_at":   nil,
+		}).Error
+	})
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			RespondWithError(w, http.StatusNotFound, "car not found")
+			return
+		}
+		RespondWithError(w, http.StatusInternalServerError, "database error")
+		return
+	}
+
+	RespondWithJSON(w, http.StatusCreated, map[string]any{
+		"car_id":    carID,
+		"device_id": deviceID,
+		"token":     token,
+		"message":   "Configure the device with Authorization: Device <token>. The token is not shown again.",
+	})
+}
+
+// carTelemetryHandler handles GET /api/v1/cars/{id}/telemetry (latest state)
+// and GET /api/v1/cars/{id}/telemetry/track (admin)
+func (s *Server) carTelemetryHandler(w http.ResponseWriter, r *http.Request) {
+	s.adminOnly(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			RespondWithError(w, http.StatusMethodNotAllowed, "method not allowed")
+			return
+		}
+
+		parts := strings.Split(strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/v1/cars/"), "/"), "/")
+		id, err := strconv.Atoi(parts[0])
+		if err != nil || id <= 0 {
+			RespondWithError(w, http.StatusBadRequest, "invalid id")
+			return
+		}
+
+		switch {
+		case len(parts) == 2:
+			s.latestTelemetry(w, uint(id))
+		case len(parts) == 3 && parts[2] == "track":
+			s.telemetryTrack(w, r, s.db.Model(&entity.TelemetryPoint{}).Where("car_id = ?", id))
+		default:
+			RespondWithError(w, http.StatusNotFound, "not found")
+		}
+	})(w, r)
+}
+
+func (s *Server) latestTelemetry(w http.ResponseWriter, carID uint) {
+	var state entity.CarTelemetryState
+	if err := s.db.First(&state, "car_id = ?", carID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			RespondWithError(w, http.StatusNotFound, "no telemetry for this car")
+			return
+		}
+		RespondWithError(w, http.StatusInternalServerError, "database error")
+		return
+	}
+
+	reading := toTelemetryReading(entity.TelemetryPoint{
+		RecordedAt:   state.RecordedAt,
+		RentalID:     state.RentalID,
+		LatE6:        state.LatE6,
+		LngE6:        state.LngE6,
+		OdometerM:    state.OdometerM,
+		FuelLevel:    state.FuelLevel,
+		BatteryLevel: state.BatteryLevel,
+		Ignition:     state.Ignition,
+	})
+	RespondWithJSON(w, http.StatusOK, map[string]any{
+		"car_id":   carID,
+		"reading":  reading,
+		"received": state.UpdatedAt,
+	})
+}
+
+// rentalTrack handles GET /api/v1/rentals/{id}/track for the renter or an admin.
+func (s *Server) rentalTrack(w http.ResponseWriter, r *http.Request, rentalID uint) {
+	userID, ok := authhttp.UserIDFromContext(r.Context())
+	if !ok {
+		RespondWithError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	var rental entity.Rental
+	if err := s.db.First(&rental, rentalID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			RespondWithError(w, http.StatusNotFound, "rental not found")
+			return
+		}
+		RespondWithError(w, http.StatusInternalServerError, "database error")
+		return
+	}
+	if getRoleFromContext(r) != entity.UserRoleAdmin && rental.UserID != userID {
+		RespondWithError(w, http.StatusForbidden, "forbidden")
+		return
+	}
+
+	s.telemetryTrack(w, r, s.db.Model(&entity.TelemetryPoint{}).Where("rental_id = ?", rentalID))
+}
+
+// telemetryTrack returns a page of readings in chronological order, optionally
+// limited to the from/to window (RFC 3339).
+func (s *Server) telemetryTrack(w http.ResponseWriter, r *http.Request, q *gorm.DB) {
+	for _, param := range []string{"from", "to"} {
+		v := r.URL.Query().Get(param)
+		if v == "" {
+			continue
+		}
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			RespondWithError(w, http.StatusBadRequest, "invalid "+param)
+			return
+		}
+		if param == "from" {
+			q = q.Where("recorded_at >= ?", t.UTC())
+		} else {
+			q = q.Where("recorded_at < ?", t.UTC())
+		}
+	}
+
+	page, err := parsePageRequest(r)
+	if err != nil {
+		RespondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	key := sortKey{Name: "recorded_at", Column: "recorded_at", IDColumn: "id", IsTime: true}
+	result, err := paginate(q, page, key, func(point entity.TelemetryPoint) (any, uint) {
+		return point.RecordedAt, point.ID
+	})
+	if err != nil {
+		respondWithPage(w, result, err)
+		return
+	}
+
+	points := result.Items.([]entity.TelemetryPoint)
+	readings := make([]TelemetryReading, 0, len(points))
+	for _, point := range points {
+		readings = append(readings, toTelemetryReading(point))
+	}
+	result.Items = readings
+	respondWithPage(w, result, nil)
+}
@@ -0,0 +1,290 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/CMPNION/Car-Rental-API.git/internal/entity"
+)
+
+// newTrackedCar creates a car and issues its tracker credentials.
+func newTrackedCar(t *testing.T, s *Server) (entity.Car, string) {
+	t.Helper()
+	car := entity.Car{Mark: "Kia", CarModel: "Rio", Category: entity.CarCategoryEconomy,
+		Status: entity.CarStatusAvailable, PricePerHour: 1000}
+	if err := s.db.Create(&car).Error; err != nil {
+		t.Fatal(err)
+	}
+	return car, issueDeviceToken(t, s, car.ID)
+}
+
+func issueDeviceToken(t *testing.T, s *Server, carID uint) string {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, fmt.Sprintf("/api/v1/admin/cars/%d/device", carID), nil)
+	s.carDeviceHandler(rec, withUser(req, 1, entity.UserRoleAdmin), carID)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("issue device: status %d: %s", rec.Code, rec.Body)
+	}
+	var resp struct {
+		Data struct {
+			Token string `json:"token"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+	return resp.Data.Token
+}
+
+type telemetryIngestResponse struct {
+	Accepted   int               `json:"accepted"`
+	Stored     int64             `json:"stored"`
+	Duplicates int64             `json:"duplicates"`
+	Rejected   []rejectedReading `json:"rejected"`
+}
+
+// sendTelemetry posts readings with the Authorization header and returns the status and response.
+func sendTelemetry(t *testing.T, s *Server, authorization string, readings []map[string]any) (int, telemetryIngestResponse) {
+	t.Helper()
+	body, err := json.Marshal(map[string]any{"readings": readings})
+	if err != nil {
+		t.Fatal(err)
+	}
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/telemetry", bytes.NewReader(body))
+	if authorization != "" {
+		req.Header.Set("Authorization", authorization)
+	}
+	s.telemetryIngestHandler(rec, req)
+
+	var resp struct {
+		Data telemetryIngestResponse `json:"data"`
+	}
+	if rec.Code == http.StatusAccepted {
+		if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return rec.Code, resp.Data
+}
+
+func reading(at time.Time) map[string]any {
+	return map[string]any{"recorded_at": at.Format(time.RFC3339), "lat": 43.2389, "lng": 76.8897, "odometer_km": 12345.6}
+}
+
+func TestTelemetryDeviceAuth(t *testing.T) {
+	s := newTestServer(t)
+	car, token := newTrackedCar(t, s)
+	readings := []map[string]any{reading(time.Now())}
+
+	tests := []struct {
+		name          string
+		authorization string
+		want          int
+	}{
+		{"no credentials", "", http.StatusUnauthorized},
+		{"user scheme", "Bearer " + token, http.StatusUnauthorized},
+		{"no secret", "Device " + token[:len("dev_")+12], http.StatusUnauthorized},
+		{"wrong secret", "Device " + token[:len(token)-1] + "x", http.StatusUnauthorized},
+		{"unknown device", "Device dev_000000000000.abc", http.StatusUnauthorized},
+		{"valid", "Device " + token, http.StatusAccepted},
+		{"scheme is case-insensitive", "device " + token, http.StatusAccepted},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if code, _ := sendTelemetry(t, s, tt.authorization, readings); code != tt.want {
+				t.Errorf("status %d, want %d", code, tt.want)
+			}
+		})
+	}
+
+	// Rotating the credentials revokes the old token.
+	rotated := issueDeviceToken(t, s, car.ID)
+	if code, _ := sendTelemetry(t, s, "Device "+token, readings); code != http.StatusUnauthorized {
+		t.Errorf("old token after rotation: status %d, want 401", code)
+	}
+	if code, _ := sendTelemetry(t, s, "Device "+rotated, readings); code != http.StatusAccepted {
+		t.Errorf("rotated token: status %d, want 202", code)
+	}
+}
+
+func TestTelemetryIngestBatch(t *testing.T) {
+	s := newTestServer(t)
+	car, token := newTrackedCar(t, s)
+	now := time.Now().UTC().Truncate(time.Second)
+
+	bad := reading(now.Add(-time.Minute))
+	bad["lat"] = 91.0
+	readings := []map[string]any{
+		reading(now.Add(-2 * time.Minute)),
+		bad,
+		reading(now.Add(time.Hour)),
+		reading(now.Add(-8 * 24 * time.Hour)),
+		reading(now.Add(-3 * time.Minute)),
+	}
+	code, resp := sendTelemetry(t, s, "Device "+token, readings)
+	if code != http.StatusAccepted {
+		t.Fatalf("status %d", code)
+	}
+	if resp.Accepted != 2 || resp.Stored != 2 || resp.Duplicates != 0 {
+		t.Errorf("accepted %d, stored %d, duplicates %d, want 2, 2, 0", resp.Accepted, resp.Stored, resp.Duplicates)
+	}
+	wantRejected := []rejectedReading{
+		{1, "coordinates out of range"},
+		{2, "recorded_at is in the future"},
+		{3, "recorded_at is older than the raw retention window"},
+	}
+	if fmt.Sprint(resp.Rejected) != fmt.Sprint(wantRejected) {
+		t.Errorf("rejected %+v, want %+v", resp.Rejected, wantRejected)
+	}
+
+	// A retried batch stores nothing twice, whatever zone the device reports in.
+	retry := reading(now.Add(-2 * time.Minute).In(time.FixedZone("UTC+5", 5*60*60)))
+	if _, resp := sendTelemetry(t, s, "Device "+token, []map[string]any{retry}); resp.Stored != 0 || resp.Duplicates != 1 {
+		t.Errorf("retry: stored %d, duplicates %d, want 0 and 1", resp.Stored, resp.Duplicates)
+	}
+
+	var state entity.CarTelemetryState
+	if err := s.db.First(&state, "car_id = ?", car.ID).Error; err != nil {
+		t.Fatal(err)
+	}
+	if !state.RecordedAt.Equal(now.Add(-2 * time.Minute)) {
+		t.Errorf("latest state at %s, want %s", state.RecordedAt, now.Add(-2*time.Minute))
+	}
+	var device entity.CarDevice
+	if err := s.db.First(&device, "car_id = ?", car.ID).Error; err != nil {
+		t.Fatal(err)
+	}
+	if device.LastSeenAt == nil {
+		t.Error("last_seen_at not recorded")
+	}
+}
+
+func TestTelemetryRentalAttribution(t *testing.T) {
+	s := newTestServer(t)
+	now := time.Now().UTC().Truncate(time.Second)
+
+	user := entity.User{FirstName: "U", LastName: "S", Email: "u@example.com", PasswordHash: "x", Role: entity.UserRoleClient}
+	if err := s.db.Create(&user).Error; err != nil {
+		t.Fatal(err)
+	}
+	newRental := func(carID uint, status string, pickedUp, end time.Time, returned *time.Time) entity.Rental {
+		r := entity.Rental{UserID: user.ID, CarID: carID, StartDate: pickedUp, EndDate: end, PickedUpAt: &pickedUp,
+			ReturnedAt: returned, TotalPrice: 1000, Status: status}
+		if err := s.db.Create(&r).Error; err != nil {
+			t.Fatal(err)
+		}
+		return r
+	}
+
+	// Returned two hours early: later readings are not the renter's.
+	returnedCar, returnedToken := newTrackedCar(t, s)
+	returnedAt := now.Add(-4 * time.Hour)
+	returned := newRental(returnedCar.ID, entity.RentalStatusCompleted, now.Add(-6*time.Hour), now.Add(-2*time.Hour), &returnedAt)
+	// Still out three hours past its end.
+	overdueCar, overdueToken := newTrackedCar(t, s)
+	overdue := newRental(overdueCar.ID, entity.RentalStatusOverdue, now.Add(-6*time.Hour), now.Add(-3*time.Hour), nil)
+
+	sendTelemetry(t, s, "Device "+returnedToken, []map[string]any{
+		reading(now.Add(-5 * time.Hour)), reading(now.Add(-3 * time.Hour)), reading(now.Add(-7 * time.Hour)),
+	})
+	sendTelemetry(t, s, "Device "+overdueToken, []map[string]any{
+		reading(now.Add(-5 * time.Hour)), reading(now.Add(-time.Hour)),
+	})
+
+	tests := []struct {
+		name   string
+		rental entity.Rental
+		want   []time.Time
+	}{
+		{"early return", returned, []time.Time{now.Add(-5 * time.Hour)}},
+		{"overdue", overdue, []time.Time{now.Add(-5 * time.Hour), now.Add(-time.Hour)}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodGet, fmt.Sprintf("/api/v1/rentals/%d/track", tt.rental.ID), nil)
+			s.rentalTrack(rec, withUser(req, user.ID, entity.UserRoleClient), tt.rental.ID)
+			if rec.Code != http.StatusOK {
+				t.Fatalf("status %d: %s", rec.Code, rec.Body)
+			}
+			var resp struct {
+				Data struct {
+					Items []TelemetryReading `json:"items"`
+				} `json:"data"`
+			}
+			if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+				t.Fatal(err)
+			}
+			var got []time.Time
+			for _, item := range resp.Data.Items {
+				got = append(got, item.RecordedAt.UTC())
+			}
+			if fmt.Sprint(got) != fmt.Sprint(tt.want) {
+				t.Errorf("track %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCompactTelemetry(t *testing.T) {
+	s := newTestServer(t)
+	now := time.Now().UTC()
+	car, _ := newTrackedCar(t, s)
+
+	bucket := now.Add(-8 * 24 * time.Hour).Truncate(telemetryBucket)
+	times := []time.Time{
+		now.Add(-91 * 24 * time.Hour),
+		bucket.Add(10 * time.Second),
+		bucket.Add(time.Minute),
+		bucket.Add(2 * time.Minute),
+		bucket.Add(telemetryBucket + time.Second),
+		now.Add(-time.Hour),
+		now.Add(-time.Hour + time.Minute),
+	}
+	for _, at := range times {
+		if err := s.db.Create(&entity.TelemetryPoint{CarID: car.ID, RecordedAt: at}).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := compactTelemetry(s.db, now); err != nil {
+		t.Fatal(err)
+	}
+
+	var points []entity.TelemetryPoint
+	if err := s.db.Order("recorded_at asc").Find(&points).Error; err != nil {
+		t.Fatal(err)
+	}
+	want := []struct {
+		at          time.Time
+		downsampled bool
+	}{
+		{times[1], true}, {times[4], true}, {times[5], false}, {times[6], false},
+	}
+	if len(points) != len(want) {
+		t.Fatalf("kept %d points, want %d", len(points), len(want))
+	}
+	for i, w := range want {
+		if !points[i].RecordedAt.Equal(w.at) || points[i].Downsampled != w.downsampled {
+			t.Errorf("point %d: %s downsampled=%v, want %s downsampled=%v",
+				i, points[i].RecordedAt, points[i].Downsampled, w.at, w.downsampled)
+		}
+	}
+
+	// Compacting again changes nothing.
+	if err := compactTelemetry(s.db, now); err != nil {
+		t.Fatal(err)
+	}
+	var count int64
+	s.db.Model(&entity.TelemetryPoint{}).Count(&count)
+	if count != int64(len(want)) {
+		t.Errorf("second pass kept %d points, want %d", count, len(want))
+	}
+}
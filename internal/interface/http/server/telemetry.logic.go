@@ -0,0 +1,279 @@
+package server
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"log"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/CMPNION/Car-Rental-API.git/internal/entity"
+	"github.com/CMPNION/Car-Rental-API.git/internal/usecase/rentalstate"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	maxTelemetryBatch = 500
+	// Readings older than this are kept at one per telemetryBucket.
+	telemetryRawRetention = 7 * 24 * time.Hour
+	telemetryBucket       = 5 * time.Minute
+	// Readings older than this are deleted.
+	telemetryRetention     = 90 * 24 * time.Hour
+	telemetryRetentionTick = time.Hour
+	// Devices may buffer readings while offline, but not report the future.
+	telemetryClockSkew = 5 * time.Minute
+)
+
+var errInvalidDeviceToken = errors.New("invalid device token")
+
+// TelemetryReading is one reading as sent by a device.
+type TelemetryReading struct {
+	RecordedAt   time.Time `json:"recorded_at"`
+	Lat          float64   `json:"lat"`
+	Lng          float64   `json:"lng"`
+	OdometerKm   float64   `json:"odometer_km"`
+	FuelLevel    *float64  `json:"fuel_level,omitempty"`
+	BatteryLevel *float64  `json:"battery_level,omitempty"`
+	Ignition     bool      `json:"ignition"`
+	RentalID     *uint     `json:"rental_id,omitempty"`
+}
+
+// newDeviceCredentials returns a device id, the secret token handed to the
+// device once, and the hash stored in the database.
+func newDeviceCredentials() (deviceID, token, secretHash string, err error) {
+	idBytes := make([]byte, 6)
+	secret := make([]byte, 32)
+	if _, err = rand.Read(idBytes); err != nil {
+		return "", "", "", err
+	}
+	if _, err = rand.Read(secret); err != nil {
+		return "", "", "", err
+	}
+	deviceID = "dev_" + hex.EncodeToString(idBytes)
+	secretHex := hex.EncodeToString(secret)
+	return deviceID, deviceID + "." + secretHex, hashDeviceSecret(secretHex), nil
+}
+
+// Secrets are 256 random bits, so a fast hash is enough to protect them at rest.
+func hashDeviceSecret(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
+
+// authenticateDevice resolves an "Authorization: Device <id>.<secret>" header to the device.
+func authenticateDevice(db *gorm.DB, header string) (entity.CarDevice, error) {
+	var device entity.CarDevice
+
+	scheme, token, ok := strings.Cut(header, " ")
+	if !ok || !strings.EqualFold(scheme, "Device") {
+		return device, errInvalidDeviceToken
+	}
+	deviceID, secret, ok := strings.Cut(strings.TrimSpace(token), ".")
+	if !ok || deviceID == "" || secret == "" {
+		return device, errInvalidDeviceToken
+	}
+
+	if err := db.Where("device_id = ?", deviceID).First(&device).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return device, errInvalidDeviceToken
+		}
+		return device, err
+	}
+	if subtle.ConstantTimeCompare([]byte(hashDeviceSecret(secret)), []byte(device.SecretHash)) != 1 {
+		return device, errInvalidDeviceToken
+	}
+	return device, nil
+}
+
+// toTelemetryPoint validates a reading and converts it to its stored form.
+func toTelemetryPoint(carID uint, reading TelemetryReading, now time.Time) (entity.TelemetryPoint, string) {
+	switch {
+	case reading.RecordedAt.IsZero():
+		return entity.TelemetryPoint{}, "recorded_at is required"
+	case reading.RecordedAt.After(now.Add(telemetryClockSkew)):
+		return entity.TelemetryPoint{}, "recorded_at is in the future"
+	case reading.RecordedAt.Before(now.Add(-telemetryRawRetention)):
+		return entity.TelemetryPoint{}, "recorded_at is older than the raw retention window"
+	case reading.Lat < -90 || reading.Lat > 90 || reading.Lng < -180 || reading.Lng > 180:
+		return entity.TelemetryPoint{}, "coordinates out of range"
+	case reading.OdometerKm < 0:
+		return entity.TelemetryPoint{}, "odometer_km must be >= 0"
+	}
+
+	fuel, ok := percentLevel(reading.FuelLevel)
+	if !ok {
+		return entity.TelemetryPoint{}, "fuel_level must be within 0..100"
+	}
+	battery, ok := percentLevel(reading.BatteryLevel)
+	if !ok {
+		return entity.TelemetryPoint{}, "battery_level must be within 0..100"
+	}
+
+	return entity.TelemetryPoint{
+		CarID:        carID,
+		RecordedAt:   reading.RecordedAt.UTC(),
+		LatE6:        int32(math.Round(reading.Lat * 1e6)),
+		LngE6:        int32(math.Round(reading.Lng * 1e6)),
+		OdometerM:    int64(math.Round(reading.OdometerKm * 1000)),
+		FuelLevel:    fuel,
+		BatteryLevel: battery,
+		Ignition:     reading.Ignition,
+	}, ""
+}
+
+func percentLevel(v *float64) (*uint8, bool) {
+	if v == nil {
+		return nil, true
+	}
+	if *v < 0 || *v > 100 {
+		return nil, false
+	}
+	level := uint8(math.Round(*v))
+	return &level, true
+}
+
+// toTelemetryReading converts a stored point back to API units.
+func toTelemetryReading(point entity.TelemetryPoint) TelemetryReading {
+	reading := TelemetryReading{
+		RecordedAt: point.RecordedAt,
+		Lat:        float64(point.LatE6) / 1e6,
+		Lng:        float64(point.LngE6) / 1e6,
+		OdometerKm: float64(point.OdometerM) / 1000,
+		Ignition:   point.Ignition,
+		RentalID:   point.RentalID,
+	}
+	if point.FuelLevel != nil {
+		v := float64(*point.FuelLevel)
+		reading.FuelLevel = &v
+	}
+	if point.BatteryLevel != nil {
+		v := float64(*point.BatteryLevel)
+		reading.BatteryLevel = &v
+	}
+	return reading
+}
+
+// storeTelemetry attributes points to the rental the car was on at the time,
+// from its pickup until it came back or, while the car is still out, with no
+// end; it inserts them (ignoring duplicates, so devices can safely retry a batch) and
+// advances the car's latest state.
+func storeTelemetry(tx *gorm.DB, device entity.CarDevice, points []entity.TelemetryPoint) (int64, error) {
+	from, to := points[0].RecordedAt, points[0].RecordedAt
+	for _, p := range points {
+		if p.RecordedAt.Before(from) {
+			from = p.RecordedAt
+		}
+		if p.RecordedAt.After(to) {
+			to = p.RecordedAt
+		}
+	}
+
+	var rentals []entity.Rental
+	if err := tx.Where("car_id = ? AND COALESCE(picked_up_at, start_date) <= ? AND (status IN ? OR (status = ? AND COALESCE(returned_at, end_date) > ?))",
+		device.CarID, to, rentalstate.Underway, entity.RentalStatusCompleted, from).
+		Find(&rentals).Error; err != nil {
+		return 0, err
+	}
+
+	latest := 0
+	for i := range points {
+		for _, rental := range rentals {
+			end := rentalUsageEnd(rental)
+			if !points[i].RecordedAt.Before(rentalUsageStart(rental)) && (end.IsZero() || points[i].RecordedAt.Before(end)) {
+				id := rental.ID
+				points[i].RentalID = &id
+				break
+			}
+		}
+		if points[i].RecordedAt.After(points[latest].RecordedAt) {
+			latest = i
+		}
+	}
+
+	res := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&points)
+	if res.Error != nil {
+		return 0, res.Error
+	}
+
+	p := points[latest]
+	state := entity.CarTelemetryState{
+		CarID:        device.CarID,
+		RecordedAt:   p.RecordedAt,
+		RentalID:     p.RentalID,
+		LatE6:        p.LatE6,
+		LngE6:        p.LngE6,
+		OdometerM:    p.OdometerM,
+		FuelLevel:    p.FuelLevel,
+		BatteryLevel: p.BatteryLevel,
+		Ignition:     p.Ignition,
+	}
+	if err := tx.Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "car_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{
+			"recorded_at", "rental_id", "lat_e6", "lng_e6", "odometer_m",
+			"fuel_level", "battery_level", "ignition", "updated_at",
+		}),
+		Where: clause.Where{Exprs: []clause.Expression{
+			clause.Expr{SQL: "excluded.recorded_at > car_telemetry_states.recorded_at"},
+		}},
+	}).Create(&state).Error; err != nil {
+		return 0, err
+	}
+
+	now := time.Now().UTC()
+	if err := tx.Model(&entity.CarDevice{}).Where("id = ?", device.ID).
+		Update("last_seen_at", now).Error; err != nil {
+		return 0, err
+	}
+
+	return res.RowsAffected, nil
+}
+
+// runTelemetryRetention periodically downsamples and expires old readings.
+func (s *Server) runTelemetryRetention() {
+	ticker := time.NewTicker(telemetryRetentionTick)
+	defer ticker.Stop()
+
+	for {
+		if err := compactTelemetry(s.db, time.Now()); err != nil {
+			log.Printf("telemetry retention failed: %v", err)
+		}
+		<-ticker.C
+	}
+}
+
+// compactTelemetry keeps the first reading of every telemetryBucket for readings
+// past the raw retention window and deletes readings past telemetryRetention.
+// Readings are stored in UTC, so the cutoffs are too.
+func compactTelemetry(db *gorm.DB, now time.Time) error {
+	now = now.UTC()
+	rawCutoff := now.Add(-telemetryRawRetention)
+	bucket := int64(telemetryBucket / time.Second)
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("recorded_at < ?", now.Add(-telemetryRetention)).
+			Delete(&entity.TelemetryPoint{}).Error; err != nil {
+			return err
+		}
+
+		keep := tx.Model(&entity.TelemetryPoint{}).
+			Select("MIN(id)").
+			Where("downsampled = ? AND recorded_at < ?", false, rawCutoff).
+			Group("car_id").
+			Group("CAST(strftime('%s', recorded_at) AS INTEGER) / " + strconv.FormatInt(bucket, 10))
+		if err := tx.Where("downsampled = ? AND recorded_at < ? AND id NOT IN (?)", false, rawCutoff, keep).
+			Delete(&entity.TelemetryPoint{}).Error; err != nil {
+			return err
+		}
+
+		return tx.Model(&entity.TelemetryPoint{}).
+			Where("downsampled = ? AND recorded_at < ?", false, rawCutoff).
+			Update("downsampled", true).Error
+	})
+}
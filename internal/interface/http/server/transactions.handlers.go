@@ -33,11 +33,14 @@ func (s *Server) transactionsHandler(w http.ResponseWriter, r *http.Request) {
 		q = q.Where("user_id = ?", id)
 	}
 
-	var transactions []entity.Transaction
-	if err := q.Order("created_at desc").Find(&transactions).Error; err != nil {
-		RespondWithError(w, http.StatusInternalServerError, "database error")
+	page, err := parsePageRequest(r)
+	if err != nil {
+		RespondWithError(w, http.StatusBadRequest, err.Error())
 		return
 	}
 
-	RespondWithJSON(w, http.StatusOK, transactions)
+	result, err := paginate(q, page, newestFirst, func(transaction entity.Transaction) (any, uint) {
+		return transaction.ID, transaction.ID
+	})
+	respondWithPage(w, result, err)
 }
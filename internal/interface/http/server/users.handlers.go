@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"errors"
 	"net/http"
+	"time"
 
 	"gorm.io/gorm"
 
@@ -11,8 +12,13 @@ import (
 	authhttp "github.com/CMPNION/Car-Rental-API.git/internal/interface/http/auth"
 )
 
+var errAmountTooSmall = errors.New("amount is too small")
+
 type balanceRequest struct {
-	Amount float64 `json:"amount"`
+	// Amount is in Currency, the user's currency when empty; the balance is
+	// credited with it converted to the base currency.
+	Amount   entity.Money `json:"amount"`
+	Currency string       `json:"currency"`
 }
 
 func (s *Server) userBalanceHandler(w http.ResponseWriter, r *http.Request) {
@@ -29,9 +35,7 @@ func (s *Server) userBalanceHandler(w http.ResponseWriter, r *http.Request) {
 			RespondWithError(w, http.StatusInternalServerError, "database error")
 			return
 		}
-		RespondWithJSON(w, http.StatusOK, map[string]any{
-			"balance": user.Balance,
-		})
+		s.respondWithBalance(w, user)
 		return
 	case http.MethodPatch:
 		// continue below
@@ -50,10 +54,27 @@ func (s *Server) userBalanceHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-		var user entity.User
+	var user entity.User
 	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.First(&user, userID).Error; err != nil {
+			return err
+		}
+		currency := user.Currency
+		if req.Currency != "" {
+			currency = req.Currency
+		}
+		currency = normalizeCurrency(currency)
+		rate, err := exchangeRate(tx, currency, time.Now())
+		if err != nil {
+			return err
+		}
+		amount := toBaseCurrency(req.Amount, rate)
+		if amount <= 0 {
+			return errAmountTooSmall
+		}
+
 		res := tx.Model(&entity.User{}).Where("id = ?", userID).
-			Update("balance", gorm.Expr("balance + ?", req.Amount))
+			Update("balance", gorm.Expr("balance + ?", amount))
 		if res.Error != nil {
 			return res.Error
 		}
@@ -61,33 +82,63 @@ func (s *Server) userBalanceHandler(w http.ResponseWriter, r *http.Request) {
 			return gorm.ErrRecordNotFound
 		}
 
-		if err := tx.First(&user, userID).Error; err != nil {
-			return err
-		}
-
 		transaction := entity.Transaction{
-			UserID: userID,
-			Type:   "topup",
-			Amount: req.Amount,
-			Status: entity.TransactionStatusSuccess,
+			UserID:         userID,
+			Type:           entity.TransactionTypeTopUp,
+			Amount:         amount,
+			Currency:       currency,
+			ExchangeRate:   rate,
+			CurrencyAmount: req.Amount,
+			Status:         entity.TransactionStatusSuccess,
 		}
 		if err := tx.Create(&transaction).Error; err != nil {
 			return err
 		}
 
-		return nil
+		// Charges the deposit did not cover are paid first.
+		if err := collectOutstandingCharges(tx, userID); err != nil {
+			return err
+		}
+		return tx.First(&user, userID).Error
 	})
 
 	if err != nil {
-		if errors.Is(err, gorm.ErrRecordNotFound) {
+		switch {
+		case errors.Is(err, gorm.ErrRecordNotFound):
 			RespondWithError(w, http.StatusNotFound, "user not found")
-			return
+		case errors.Is(err, errUnknownCurrency):
+			RespondWithError(w, http.StatusBadRequest, "unknown currency")
+		case errors.Is(err, errAmountTooSmall):
+			RespondWithError(w, http.StatusBadRequest, "amount is too small")
+		default:
+			RespondWithError(w, http.StatusInternalServerError, "database error")
 		}
-		RespondWithError(w, http.StatusInternalServerError, "database error")
 		return
 	}
 
+	s.respondWithBalance(w, user)
+}
+
+// respondWithBalance shows the balance in the base currency and in the user's
+// currency, and what the user still owes on charges.
+func (s *Server) respondWithBalance(w http.ResponseWriter, user entity.User) {
+	currency, rate, err := userCurrency(s.db, user, time.Now())
+	if err != nil {
+		RespondWithError(w, http.StatusInternalServerError, "database error")
+		return
+	}
+	owed, err := outstandingCharges(s.db, user.ID)
+	if err != nil {
+		RespondWithError(w, http.StatusInternalServerError, "database error")
+		return
+	}
 	RespondWithJSON(w, http.StatusOK, map[string]any{
-		"balance": user.Balance,
+		"balance":             user.Balance,
+		"held_balance":        user.HeldBalance,
+		"base_currency":       entity.BaseCurrency,
+		"currency":            currency,
+		"exchange_rate":       rate,
+		"balance_in_currency": fromBaseCurrency(user.Balance, rate),
+		"outstanding_charges": owed,
 	})
 }
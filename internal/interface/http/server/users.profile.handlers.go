@@ -2,8 +2,10 @@ package server
 
 import (
 	"encoding/json"
+	"errors"
 	"net/http"
 	"strings"
+	"time"
 
 	"github.com/CMPNION/Car-Rental-API.git/internal/entity"
 	authhttp "github.com/CMPNION/Car-Rental-API.git/internal/interface/http/auth"
@@ -12,6 +14,42 @@ import (
 type profileUpdateRequest struct {
 	FirstName *string `json:"first_name"`
 	LastName  *string `json:"last_name"`
+	// BirthDate is a calendar date, YYYY-MM-DD.
+	BirthDate *string `json:"birth_date"`
+	// Currency is the display and payment currency: the base currency or one with an exchange rate.
+	Currency *string `json:"currency"`
+}
+
+// userLoyaltyHandler handles GET /api/v1/users/me/loyalty: the points ledger, newest first.
+func (s *Server) userLoyaltyHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		RespondWithError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	userID, ok := authhttp.UserIDFromContext(r.Context())
+	if !ok {
+		RespondWithError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	page, err := parsePageRequest(r)
+	if err != nil {
+		RespondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err := expireLoyaltyPoints(s.db, userID, time.Now()); err != nil {
+		RespondWithError(w, http.StatusInternalServerError, "database error")
+		return
+	}
+
+	q := s.db.Model(&entity.LoyaltyEntry{}).Where("user_id = ?", userID)
+	if v := r.URL.Query().Get("type"); v != "" {
+		q = q.Where("type = ?", strings.ToLower(v))
+	}
+	result, err := paginate(q, page, newestFirst, func(entry entity.LoyaltyEntry) (any, uint) {
+		return entry.ID, entry.ID
+	})
+	respondWithPage(w, result, err)
 }
 
 func (s *Server) userProfileHandler(w http.ResponseWriter, r *http.Request) {
@@ -28,14 +66,28 @@ func (s *Server) userProfileHandler(w http.ResponseWriter, r *http.Request) {
 			RespondWithError(w, http.StatusInternalServerError, "database error")
 			return
 		}
+		loyalty, err := userLoyaltyStatus(s.db, userID, time.Now())
+		if err != nil {
+			RespondWithError(w, http.StatusInternalServerError, "database error")
+			return
+		}
+		var birthDate *string
+		if user.BirthDate != nil {
+			v := user.BirthDate.Format(time.DateOnly)
+			birthDate = &v
+		}
 		RespondWithJSON(w, http.StatusOK, map[string]any{
-			"id":         user.ID,
-			"first_name": user.FirstName,
-			"last_name":  user.LastName,
-			"email":      user.Email,
-			"role":       user.Role,
-			"rating":     user.Rating,
-			"balance":    user.Balance,
+			"id":           user.ID,
+			"first_name":   user.FirstName,
+			"last_name":    user.LastName,
+			"email":        user.Email,
+			"role":         user.Role,
+			"rating":       user.Rating,
+			"balance":      user.Balance,
+			"held_balance": user.HeldBalance,
+			"birth_date":   birthDate,
+			"currency":     normalizeCurrency(user.Currency),
+			"loyalty":      loyalty,
 		})
 		return
 	case http.MethodPatch:
@@ -68,6 +120,30 @@ func (s *Server) userProfileHandler(w http.ResponseWriter, r *http.Request) {
 		}
 		updates["last_name"] = v
 	}
+	if req.BirthDate != nil {
+		v, err := time.Parse(time.DateOnly, strings.TrimSpace(*req.BirthDate))
+		if err != nil {
+			RespondWithError(w, http.StatusBadRequest, "birth_date must be YYYY-MM-DD")
+			return
+		}
+		if v.After(time.Now()) || v.Year() < 1900 {
+			RespondWithError(w, http.StatusBadRequest, "birth_date is out of range")
+			return
+		}
+		updates["birth_date"] = v
+	}
+	if req.Currency != nil {
+		v := normalizeCurrency(*req.Currency)
+		if _, err := exchangeRate(s.db, v, time.Now()); err != nil {
+			if errors.Is(err, errUnknownCurrency) {
+				RespondWithError(w, http.StatusBadRequest, "unknown currency")
+				return
+			}
+			RespondWithError(w, http.StatusInternalServerError, "database error")
+			return
+		}
+		updates["currency"] = v
+	}
 
 	if len(updates) == 0 {
 		RespondWithError(w, http.StatusBadRequest, "no fields to update")
@@ -15,6 +15,7 @@ import (
 var (
 	ErrEmailTaken         = errors.New("email already taken")
 	ErrInvalidCredentials = errors.New("invalid credentials")
+	ErrInvalidBirthDate   = errors.New("birth_date is out of range")
 )
 
 type AuthService struct {
@@ -28,6 +29,8 @@ type RegisterRequest struct {
 	Password  string `json:"password" validate:"required,min=8"`
 	FirstName string `json:"first_name" validate:"required"`
 	LastName  string `json:"last_name" validate:"required"`
+	// BirthDate is a calendar date, YYYY-MM-DD; car categories check the driver's age against it.
+	BirthDate string `json:"birth_date" validate:"required,datetime=2006-01-02"`
 }
 
 type LoginRequest struct {
@@ -56,6 +59,14 @@ func (s *AuthService) Register(req RegisterRequest) (RegisterResponse, error) {
 		return RegisterResponse{}, err
 	}
 
+	birthDate, err := time.Parse(time.DateOnly, req.BirthDate)
+	if err != nil {
+		return RegisterResponse{}, err
+	}
+	if birthDate.After(time.Now()) || birthDate.Year() < 1900 {
+		return RegisterResponse{}, ErrInvalidBirthDate
+	}
+
 	var existing entity.User
 	if err := s.db.Where("email = ?", req.Email).First(&existing).Error; err == nil {
 		return RegisterResponse{}, ErrEmailTaken
@@ -76,6 +87,7 @@ func (s *AuthService) Register(req RegisterRequest) (RegisterResponse, error) {
 		Role:         entity.UserRoleClient,
 		Balance:      0,
 		Rating:       0,
+		BirthDate:    &birthDate,
 	}
 
 	if err := s.db.Create(&user).Error; err != nil {
@@ -0,0 +1,59 @@
+package auth
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+
+	"github.com/CMPNION/Car-Rental-API.git/internal/entity"
+)
+
+func TestRegisterBirthDate(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(filepath.Join(t.TempDir(), "auth.db")), &gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.AutoMigrate(&entity.User{}); err != nil {
+		t.Fatal(err)
+	}
+	svc := NewAuthService(db, "secret")
+
+	tests := []struct {
+		birthDate string
+		wantErr   bool
+	}{
+		{"", true},
+		{"17.05.1990", true},
+		{"1890-01-01", true},
+		{"2999-01-01", true},
+		{"1990-05-17", false},
+	}
+	for i, tt := range tests {
+		_, err := svc.Register(RegisterRequest{
+			Email:     "user" + string(rune('a'+i)) + "@example.com",
+			Password:  "password123",
+			FirstName: "A",
+			LastName:  "B",
+			BirthDate: tt.birthDate,
+		})
+		if (err != nil) != tt.wantErr {
+			t.Errorf("birth_date %q: err = %v, want error %v", tt.birthDate, err, tt.wantErr)
+		}
+	}
+
+	var user entity.User
+	if err := db.Where("email = ?", "usere@example.com").First(&user).Error; err != nil {
+		t.Fatal(err)
+	}
+	if user.BirthDate == nil || user.BirthDate.Format("2006-01-02") != "1990-05-17" {
+		t.Errorf("stored birth_date = %v, want 1990-05-17", user.BirthDate)
+	}
+	if _, err := svc.Register(RegisterRequest{Email: "old@example.com", Password: "password123",
+		FirstName: "A", LastName: "B", BirthDate: "2999-01-01"}); !errors.Is(err, ErrInvalidBirthDate) {
+		t.Errorf("future birth_date: err = %v, want ErrInvalidBirthDate", err)
+	}
+}
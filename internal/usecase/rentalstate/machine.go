@@ -0,0 +1,191 @@
+// Package rentalstate is the rental lifecycle: which events move a rental
+// from one status to another, the guards that can refuse a move and the side
+// effects that run with it. Every move is recorded as an entity.RentalTransition.
+package rentalstate
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/CMPNION/Car-Rental-API.git/internal/entity"
+)
+
+var (
+	ErrInvalidTransition = errors.New("invalid rental transition")
+	ErrRentalStarted     = errors.New("rental has already started")
+)
+
+// Events that move a rental.
+const (
+	EventCreate  = "create"
+	EventPay     = "pay"
+	EventPickup  = "pickup"
+	EventFinish  = "finish"
+	EventCancel  = "cancel"
+	EventVoid    = "void"
+	EventExpire  = "expire"
+	EventNoShow  = "no_show"
+	EventOverdue = "overdue"
+)
+
+// Transition is an allowed move: Event takes a rental in any From status to To.
+type Transition struct {
+	Event string
+	From  []string
+	To    string
+}
+
+// Transitions is the lifecycle. Payment confirms a booking; the rental only
+// becomes active when the car is picked up. Cancel is the renter's
+// cancellation before the start, of a paid rental too; void is the
+// operator's, e.g. when the car is withdrawn, and may hit a rental that is
+// already under way.
+var Transitions = []Transition{
+	{Event: EventPay, From: []string{entity.RentalStatusPending}, To: entity.RentalStatusConfirmed},
+	{Event: EventPickup, From: []string{entity.RentalStatusConfirmed}, To: entity.RentalStatusActive},
+	{Event: EventFinish, From: []string{entity.RentalStatusActive, entity.RentalStatusOverdue}, To: entity.RentalStatusCompleted},
+	{Event: EventCancel, From: []string{entity.RentalStatusPending, entity.RentalStatusConfirmed}, To: entity.RentalStatusCancelled},
+	{Event: EventVoid, From: []string{entity.RentalStatusPending, entity.RentalStatusConfirmed, entity.RentalStatusActive, entity.RentalStatusOverdue}, To: entity.RentalStatusCancelled},
+	{Event: EventExpire, From: []string{entity.RentalStatusPending}, To: entity.RentalStatusExpired},
+	{Event: EventNoShow, From: []string{entity.RentalStatusConfirmed}, To: entity.RentalStatusNoShow},
+	{Event: EventOverdue, From: []string{entity.RentalStatusActive}, To: entity.RentalStatusOverdue},
+}
+
+// Released are the statuses of rentals that ended without the car being
+// used: they no longer hold the car or count as bookings.
+var Released = []string{entity.RentalStatusCancelled, entity.RentalStatusExpired, entity.RentalStatusNoShow}
+
+// Open are the statuses of rentals that are booked or under way.
+var Open = []string{entity.RentalStatusPending, entity.RentalStatusConfirmed, entity.RentalStatusActive, entity.RentalStatusOverdue}
+
+// Underway are the statuses of rentals whose car has been picked up and not
+// returned yet.
+var Underway = []string{entity.RentalStatusActive, entity.RentalStatusOverdue}
+
+// Paid are the statuses of rentals whose price has been paid and not refunded.
+var Paid = []string{entity.RentalStatusConfirmed, entity.RentalStatusActive, entity.RentalStatusOverdue, entity.RentalStatusCompleted}
+
+// Change is a transition being applied to a rental. Rental already has the
+// new status when side effects run.
+type Change struct {
+	Rental  *entity.Rental
+	Event   string
+	From    string
+	To      string
+	ActorID *uint
+	Reason  string
+	At      time.Time
+}
+
+// Hook is a guard or a side effect. A guard's error refuses the transition;
+// a side effect's error rolls back the transaction it runs in.
+type Hook func(tx *gorm.DB, c Change) error
+
+type Machine struct {
+	transitions map[string]Transition
+	guards      map[string][]Hook
+	effects     map[string][]Hook
+}
+
+// NewMachine returns the lifecycle with its own guards; callers add the side
+// effects that need the rest of the application with OnEvent.
+func NewMachine() *Machine {
+	m := &Machine{
+		transitions: map[string]Transition{},
+		guards:      map[string][]Hook{},
+		effects:     map[string][]Hook{},
+	}
+	for _, t := range Transitions {
+		m.transitions[t.Event] = t
+	}
+	m.Guard(EventCancel, func(_ *gorm.DB, c Change) error {
+		if c.At.After(c.Rental.StartDate) {
+			return ErrRentalStarted
+		}
+		return nil
+	})
+	return m
+}
+
+// Guard adds a check that runs before the event's transition.
+func (m *Machine) Guard(event string, h Hook) {
+	m.guards[event] = append(m.guards[event], h)
+}
+
+// OnEvent adds a side effect that runs after the event's transition, in the
+// same transaction and in the order added.
+func (m *Machine) OnEvent(event string, h Hook) {
+	m.effects[event] = append(m.effects[event], h)
+}
+
+// Can reports whether the event is allowed from the status; guards are not run.
+func (m *Machine) Can(status, event string) bool {
+	t, ok := m.transitions[event]
+	return ok && slices.Contains(t.From, status)
+}
+
+// Created records the booking of a rental as its first transition.
+func (m *Machine) Created(tx *gorm.DB, rental *entity.Rental, actorID *uint) error {
+	return tx.Create(&entity.RentalTransition{
+		RentalID: rental.ID,
+		Event:    EventCreate,
+		ToStatus: rental.Status,
+		ActorID:  actorID,
+		At:       time.Now().UTC(),
+	}).Error
+}
+
+// Fire applies the event to the rental inside tx: it checks the transition
+// and its guards, moves the status, records the transition and runs the side
+// effects. The status only moves if nobody else has moved it meanwhile.
+func (m *Machine) Fire(tx *gorm.DB, rental *entity.Rental, event string, actorID *uint, reason string) error {
+	if !m.Can(rental.Status, event) {
+		return fmt.Errorf("%w: %s from %s", ErrInvalidTransition, event, rental.Status)
+	}
+	c := Change{
+		Rental:  rental,
+		Event:   event,
+		From:    rental.Status,
+		To:      m.transitions[event].To,
+		ActorID: actorID,
+		Reason:  reason,
+		At:      time.Now().UTC(),
+	}
+	for _, guard := range m.guards[event] {
+		if err := guard(tx, c); err != nil {
+			return err
+		}
+	}
+
+	res := tx.Model(&entity.Rental{}).Where("id = ? AND status = ?", rental.ID, c.From).
+		Update("status", c.To)
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return fmt.Errorf("%w: %s from %s", ErrInvalidTransition, event, c.From)
+	}
+	if err := tx.Create(&entity.RentalTransition{
+		RentalID:   rental.ID,
+		Event:      event,
+		FromStatus: c.From,
+		ToStatus:   c.To,
+		ActorID:    actorID,
+		Reason:     reason,
+		At:         c.At,
+	}).Error; err != nil {
+		return err
+	}
+	rental.Status = c.To
+
+	for _, effect := range m.effects[event] {
+		if err := effect(tx, c); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,161 @@
+package rentalstate
+
+import (
+	"errors"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+
+	"github.com/CMPNION/Car-Rental-API.git/internal/entity"
+)
+
+var statuses = []string{
+	entity.RentalStatusPending, entity.RentalStatusConfirmed, entity.RentalStatusActive,
+	entity.RentalStatusOverdue, entity.RentalStatusCompleted, entity.RentalStatusCancelled,
+	entity.RentalStatusExpired, entity.RentalStatusNoShow,
+}
+
+func TestCan(t *testing.T) {
+	allowed := map[string][]string{
+		EventPay:     {entity.RentalStatusPending},
+		EventPickup:  {entity.RentalStatusConfirmed},
+		EventFinish:  {entity.RentalStatusActive, entity.RentalStatusOverdue},
+		EventCancel:  {entity.RentalStatusPending, entity.RentalStatusConfirmed},
+		EventVoid:    {entity.RentalStatusPending, entity.RentalStatusConfirmed, entity.RentalStatusActive, entity.RentalStatusOverdue},
+		EventExpire:  {entity.RentalStatusPending},
+		EventNoShow:  {entity.RentalStatusConfirmed},
+		EventOverdue: {entity.RentalStatusActive},
+		EventCreate:  nil,
+	}
+	m := NewMachine()
+	for event, from := range allowed {
+		for _, status := range statuses {
+			if got, want := m.Can(status, event), slices.Contains(from, status); got != want {
+				t.Errorf("Can(%s, %s) = %v, want %v", status, event, got, want)
+			}
+		}
+	}
+}
+
+func newTestDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open(filepath.Join(t.TempDir(), "rentals.db")), &gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.AutoMigrate(&entity.Rental{}, &entity.RentalTransition{}); err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+func newRental(t *testing.T, db *gorm.DB, status string, start time.Time) *entity.Rental {
+	t.Helper()
+	rental := &entity.Rental{UserID: 1, CarID: 1, StartDate: start, EndDate: start.Add(4 * time.Hour),
+		TotalPrice: 4000, Status: status}
+	if err := db.Create(rental).Error; err != nil {
+		t.Fatal(err)
+	}
+	return rental
+}
+
+func TestFire(t *testing.T) {
+	db := newTestDB(t)
+	m := NewMachine()
+	var changes []Change
+	m.OnEvent(EventPay, func(_ *gorm.DB, c Change) error {
+		changes = append(changes, c)
+		return nil
+	})
+
+	actor := uint(7)
+	rental := newRental(t, db, entity.RentalStatusPending, time.Now().Add(24*time.Hour))
+	if err := m.Fire(db, rental, EventPay, &actor, "paid"); err != nil {
+		t.Fatal(err)
+	}
+	if rental.Status != entity.RentalStatusConfirmed {
+		t.Errorf("status = %s, want confirmed", rental.Status)
+	}
+	if len(changes) != 1 || changes[0].From != entity.RentalStatusPending || changes[0].To != entity.RentalStatusConfirmed {
+		t.Errorf("side effects saw %+v", changes)
+	}
+
+	var transitions []entity.RentalTransition
+	if err := db.Where("rental_id = ?", rental.ID).Find(&transitions).Error; err != nil {
+		t.Fatal(err)
+	}
+	if len(transitions) != 1 || transitions[0].Event != EventPay || transitions[0].ActorID == nil ||
+		*transitions[0].ActorID != actor || transitions[0].Reason != "paid" {
+		t.Errorf("recorded %+v", transitions)
+	}
+
+	// Paying twice is refused and runs nothing.
+	if err := m.Fire(db, rental, EventPay, nil, ""); !errors.Is(err, ErrInvalidTransition) {
+		t.Errorf("second payment: err = %v, want ErrInvalidTransition", err)
+	}
+	if len(changes) != 1 {
+		t.Errorf("side effects ran %d times, want 1", len(changes))
+	}
+}
+
+func TestFireLostRace(t *testing.T) {
+	db := newTestDB(t)
+	m := NewMachine()
+	rental := newRental(t, db, entity.RentalStatusPending, time.Now().Add(24*time.Hour))
+
+	// Someone else expired the rental after it was loaded.
+	if err := db.Model(&entity.Rental{}).Where("id = ?", rental.ID).
+		Update("status", entity.RentalStatusExpired).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Fire(db, rental, EventPay, nil, ""); !errors.Is(err, ErrInvalidTransition) {
+		t.Errorf("err = %v, want ErrInvalidTransition", err)
+	}
+	var count int64
+	db.Model(&entity.RentalTransition{}).Where("rental_id = ?", rental.ID).Count(&count)
+	if count != 0 {
+		t.Errorf("recorded %d transitions, want none", count)
+	}
+}
+
+func TestCancelGuard(t *testing.T) {
+	db := newTestDB(t)
+	m := NewMachine()
+
+	tests := []struct {
+		name    string
+		status  string
+		start   time.Time
+		wantErr error
+	}{
+		{"pending before the start", entity.RentalStatusPending, time.Now().Add(time.Hour), nil},
+		{"confirmed before the start", entity.RentalStatusConfirmed, time.Now().Add(time.Hour), nil},
+		{"confirmed after the start", entity.RentalStatusConfirmed, time.Now().Add(-time.Minute), ErrRentalStarted},
+		{"active", entity.RentalStatusActive, time.Now().Add(time.Hour), ErrInvalidTransition},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rental := newRental(t, db, tt.status, tt.start)
+			err := m.Fire(db, rental, EventCancel, nil, "")
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("err = %v, want %v", err, tt.wantErr)
+			}
+			want := entity.RentalStatusCancelled
+			if tt.wantErr != nil {
+				want = tt.status
+			}
+			var stored entity.Rental
+			if err := db.First(&stored, rental.ID).Error; err != nil {
+				t.Fatal(err)
+			}
+			if stored.Status != want {
+				t.Errorf("status = %s, want %s", stored.Status, want)
+			}
+		})
+	}
+}